@@ -46,9 +46,12 @@ const (
 	AlarmType // Alarm Type
 
 	///// Price Attributes /////
-	Currency      // Currency
-	PricePerUnit  // PricePerUnit
-	StartingRange // StartingRange
-	TermType      // TermType
-	Unit          // Unit
+	Currency            // Currency
+	LeaseContractLength // LeaseContractLength
+	OfferingClass       // OfferingClass
+	PricePerUnit        // PricePerUnit
+	PurchaseOption      // PurchaseOption
+	StartingRange       // StartingRange
+	TermType            // TermType
+	Unit                // Unit
 )
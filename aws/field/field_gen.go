@@ -7,11 +7,11 @@ import (
 	"strings"
 )
 
-const _FieldName = "SKUCapacityStatusGroupInstance TypeLocationOperating SystemPre Installed S/WProduct FamilyserviceCodeTenancyusageTypeVolume API NameVolume TypeStorage ClassAccess TypeThroughput ClassCache EngineDatabase EngineDatabase EditionDeployment OptionLicense ModelFile system typeStorage typeThroughput capacityDeployment optionAlarm TypeCurrencyPricePerUnitStartingRangeTermTypeUnit"
+const _FieldName = "SKUCapacityStatusGroupInstance TypeLocationOperating SystemPre Installed S/WProduct FamilyserviceCodeTenancyusageTypeVolume API NameVolume TypeStorage ClassAccess TypeThroughput ClassCache EngineDatabase EngineDatabase EditionDeployment OptionLicense ModelFile system typeStorage typeThroughput capacityDeployment optionAlarm TypeCurrencyLeaseContractLengthOfferingClassPricePerUnitPurchaseOptionStartingRangeTermTypeUnit"
 
-var _FieldIndex = [...]uint16{0, 3, 17, 22, 35, 43, 59, 76, 90, 101, 108, 117, 132, 143, 156, 167, 183, 195, 210, 226, 243, 256, 272, 284, 303, 320, 330, 338, 350, 363, 371, 375}
+var _FieldIndex = [...]uint16{0, 3, 17, 22, 35, 43, 59, 76, 90, 101, 108, 117, 132, 143, 156, 167, 183, 195, 210, 226, 243, 256, 272, 284, 303, 320, 330, 338, 357, 370, 382, 396, 409, 417, 421}
 
-const _FieldLowerName = "skucapacitystatusgroupinstance typelocationoperating systempre installed s/wproduct familyservicecodetenancyusagetypevolume api namevolume typestorage classaccess typethroughput classcache enginedatabase enginedatabase editiondeployment optionlicense modelfile system typestorage typethroughput capacitydeployment optionalarm typecurrencypriceperunitstartingrangetermtypeunit"
+const _FieldLowerName = "skucapacitystatusgroupinstance typelocationoperating systempre installed s/wproduct familyservicecodetenancyusagetypevolume api namevolume typestorage classaccess typethroughput classcache enginedatabase enginedatabase editiondeployment optionlicense modelfile system typestorage typethroughput capacitydeployment optionalarm typecurrencyleasecontractlengthofferingclasspriceperunitpurchaseoptionstartingrangetermtypeunit"
 
 func (i Field) String() string {
 	if i >= Field(len(_FieldIndex)-1) {
@@ -51,13 +51,16 @@ func _FieldNoOp() {
 	_ = x[FileSystemDeploymentOption-(24)]
 	_ = x[AlarmType-(25)]
 	_ = x[Currency-(26)]
-	_ = x[PricePerUnit-(27)]
-	_ = x[StartingRange-(28)]
-	_ = x[TermType-(29)]
-	_ = x[Unit-(30)]
+	_ = x[LeaseContractLength-(27)]
+	_ = x[OfferingClass-(28)]
+	_ = x[PricePerUnit-(29)]
+	_ = x[PurchaseOption-(30)]
+	_ = x[StartingRange-(31)]
+	_ = x[TermType-(32)]
+	_ = x[Unit-(33)]
 }
 
-var _FieldValues = []Field{SKU, CapacityStatus, Group, InstanceType, Location, OperatingSystem, PreInstalledSW, ProductFamily, ServiceCode, Tenancy, UsageType, VolumeAPIName, VolumeType, StorageClass, AccessType, ThroughputClass, CacheEngine, DatabaseEngine, DatabaseEdition, DatabaseDeploymentOption, LicenseModel, FileSystemType, StorageType, ThroughputCapacity, FileSystemDeploymentOption, AlarmType, Currency, PricePerUnit, StartingRange, TermType, Unit}
+var _FieldValues = []Field{SKU, CapacityStatus, Group, InstanceType, Location, OperatingSystem, PreInstalledSW, ProductFamily, ServiceCode, Tenancy, UsageType, VolumeAPIName, VolumeType, StorageClass, AccessType, ThroughputClass, CacheEngine, DatabaseEngine, DatabaseEdition, DatabaseDeploymentOption, LicenseModel, FileSystemType, StorageType, ThroughputCapacity, FileSystemDeploymentOption, AlarmType, Currency, LeaseContractLength, OfferingClass, PricePerUnit, PurchaseOption, StartingRange, TermType, Unit}
 
 var _FieldNameToValueMap = map[string]Field{
 	_FieldName[0:3]:          SKU,
@@ -114,14 +117,20 @@ var _FieldNameToValueMap = map[string]Field{
 	_FieldLowerName[320:330]: AlarmType,
 	_FieldName[330:338]:      Currency,
 	_FieldLowerName[330:338]: Currency,
-	_FieldName[338:350]:      PricePerUnit,
-	_FieldLowerName[338:350]: PricePerUnit,
-	_FieldName[350:363]:      StartingRange,
-	_FieldLowerName[350:363]: StartingRange,
-	_FieldName[363:371]:      TermType,
-	_FieldLowerName[363:371]: TermType,
-	_FieldName[371:375]:      Unit,
-	_FieldLowerName[371:375]: Unit,
+	_FieldName[338:357]:      LeaseContractLength,
+	_FieldLowerName[338:357]: LeaseContractLength,
+	_FieldName[357:370]:      OfferingClass,
+	_FieldLowerName[357:370]: OfferingClass,
+	_FieldName[370:382]:      PricePerUnit,
+	_FieldLowerName[370:382]: PricePerUnit,
+	_FieldName[382:396]:      PurchaseOption,
+	_FieldLowerName[382:396]: PurchaseOption,
+	_FieldName[396:409]:      StartingRange,
+	_FieldLowerName[396:409]: StartingRange,
+	_FieldName[409:417]:      TermType,
+	_FieldLowerName[409:417]: TermType,
+	_FieldName[417:421]:      Unit,
+	_FieldLowerName[417:421]: Unit,
 }
 
 var _FieldNames = []string{
@@ -152,10 +161,13 @@ var _FieldNames = []string{
 	_FieldName[303:320],
 	_FieldName[320:330],
 	_FieldName[330:338],
-	_FieldName[338:350],
-	_FieldName[350:363],
-	_FieldName[363:371],
-	_FieldName[371:375],
+	_FieldName[338:357],
+	_FieldName[357:370],
+	_FieldName[370:382],
+	_FieldName[382:396],
+	_FieldName[396:409],
+	_FieldName[409:417],
+	_FieldName[417:421],
 }
 
 // FieldString retrieves an enum value from the enum constants string name.
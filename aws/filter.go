@@ -42,6 +42,8 @@ func MinimalFilter(pp *price.WithProduct) bool {
 		return true // is minimal already
 	case "AWSSecretsManager":
 		return true // is minimal already
+	case SavingsPlansService:
+		return minimalFilterSavingsPlans(pp)
 	default:
 		return false
 	}
@@ -90,6 +92,11 @@ func minimalFilterRDS(pp *price.WithProduct) bool {
 	}
 }
 
+// minimalFilterSavingsPlans only ingests the rates of the EC2 Linux instances.
+func minimalFilterSavingsPlans(pp *price.WithProduct) bool {
+	return pp.Product.Attributes["DiscountedServiceCode"] == "AmazonEC2" && pp.Product.Attributes["DiscountedOperation"] == "RunInstances"
+}
+
 func minimalFilterS3Bucket(pp *price.WithProduct) bool {
 	switch pp.Product.Family {
 	case "Storage", "API Request", "Fee":
@@ -32,6 +32,10 @@ type Ingester struct {
 	httpClient HTTPClient
 	pricingURL string
 	bufferSize uint
+	cacheDir   string
+
+	offerFormat     OfferFormat
+	priceListClient PriceListClient
 
 	service string
 	region  string
@@ -57,6 +61,7 @@ func NewIngester(service, region string, options ...Option) (*Ingester, error) {
 		httpClient:      http.DefaultClient,
 		pricingURL:      defaultPricingURL,
 		bufferSize:      defaultBufferSize,
+		offerFormat:     OfferCSV,
 		service:         service,
 		region:          region,
 		progressCh:      nil,
@@ -77,8 +82,16 @@ func (ing *Ingester) Ingest(ctx context.Context, chSize int) <-chan *price.WithP
 	go func() {
 		defer close(results)
 
-		url := ing.pricingURL + "/" + ing.service + "/current/" + ing.region + "/index.csv"
-		rc, size, err := ing.download(ctx, url)
+		if ing.service == SavingsPlansService && (ing.priceListClient != nil || ing.offerFormat != OfferCSV) {
+			ing.err = fmt.Errorf("the %s offers can only be read on the CSV format", SavingsPlansService)
+			return
+		}
+		if ing.priceListClient != nil {
+			ing.err = ing.readPriceList(ctx, results)
+			return
+		}
+
+		rc, size, err := ing.openOffer(ctx)
 		if err != nil {
 			ing.err = err
 			return
@@ -99,66 +112,76 @@ func (ing *Ingester) Ingest(ctx context.Context, chSize int) <-chan *price.WithP
 			}()
 		}
 
-		csvr := csv.NewReader(rd)
-		// It's possible that AWS provides CSV files with bare quotes. Enable LazyQuotes to be able to read those files.
-		csvr.LazyQuotes = true
-
-		// The CSV contains rows with different numbers of columns. Namely, the metadata rows only have 2 columns, while
-		// the other rows will have much more. This is needed in order to avoid errors from the reader.
-		csvr.FieldsPerRecord = -1
-
-		// Read column labels from the first non-metadata row found in the CSV. An offer file always starts with a few
-		// lines key-value pairs of metadata that needs to be skipped.
-		var columns map[string]int
-		for {
-			values, err := csvr.Read()
-			if err != nil {
-				ing.err = err
-				return
-			}
-			if len(values) > 2 {
-				columns = readColumnPositions(values)
-				break
-			}
+		switch {
+		case ing.service == SavingsPlansService:
+			ing.err = ing.readSavingsPlansCSV(ctx, rd, results)
+		case ing.offerFormat == OfferJSON:
+			ing.err = ing.readJSON(ctx, rd, results)
+		default:
+			ing.err = ing.readCSV(ctx, rd, results)
 		}
+	}()
 
-		// Read through each row in the CSV file and send a price.WithProduct on the results channel.
-		for {
-			select {
-			case <-ctx.Done():
-				ing.err = ctx.Err()
-				return
-			default:
-			}
+	return results
+}
 
-			row, err := csvr.Read()
-			if err != nil {
-				if err != io.EOF {
-					ing.err = err
-				}
-				return
-			}
+// readCSV reads the offer file on the CSV format and sends a price.WithProduct on
+// the results channel for each one of its rows
+func (ing *Ingester) readCSV(ctx context.Context, r io.Reader, results chan<- *price.WithProduct) error {
+	csvr := csv.NewReader(r)
+	// It's possible that AWS provides CSV files with bare quotes. Enable LazyQuotes to be able to read those files.
+	csvr.LazyQuotes = true
+
+	// The CSV contains rows with different numbers of columns. Namely, the metadata rows only have 2 columns, while
+	// the other rows will have much more. This is needed in order to avoid errors from the reader.
+	csvr.FieldsPerRecord = -1
+
+	// Read column labels from the first non-metadata row found in the CSV. An offer file always starts with a few
+	// lines key-value pairs of metadata that needs to be skipped.
+	var columns map[string]int
+	for {
+		values, err := csvr.Read()
+		if err != nil {
+			return err
+		}
+		if len(values) > 2 {
+			columns = readColumnPositions(values)
+			break
+		}
+	}
 
-			data := make(map[field.Field]string)
-			for col, index := range columns {
-				if f, err := field.FieldString(col); err == nil {
-					data[f] = row[index]
-				}
-			}
+	// Read through each row in the CSV file and send a price.WithProduct on the results channel.
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
 
-			pp, err := newPriceWithProduct(data)
-			if err != nil {
-				ing.err = err
-				return
+		row, err := csvr.Read()
+		if err != nil {
+			if err != io.EOF {
+				return err
 			}
+			return nil
+		}
 
-			if ing.ingestionFilter(pp) {
-				results <- pp
+		data := make(map[field.Field]string)
+		for col, index := range columns {
+			if f, err := field.FieldString(col); err == nil {
+				data[f] = row[index]
 			}
 		}
-	}()
 
-	return results
+		pp, err := newPriceWithProduct(data)
+		if err != nil {
+			return err
+		}
+
+		if ing.ingestionFilter(pp) {
+			results <- pp
+		}
+	}
 }
 
 // Err returns any error that might have happened during the ingestion.
@@ -166,6 +189,11 @@ func (ing *Ingester) Err() error {
 	return ing.err
 }
 
+// Identity returns the provider, service and region of the ingestion.
+func (ing *Ingester) Identity() (string, string, string) {
+	return ProviderName, ing.service, ing.region
+}
+
 // download is a helper that performs an HTTP GET request and returns the body of the response. The returned
 // io.ReadCloser must be manually closed after use.
 func (ing *Ingester) download(ctx context.Context, url string) (io.ReadCloser, int64, error) {
@@ -232,6 +260,11 @@ var columnProductToIngest = map[field.Field]string{
 var columnPriceToIngest = map[field.Field]string{
 	field.StartingRange: "StartingRange",
 	field.TermType:      "TermType",
+
+	// Reserved terms
+	field.LeaseContractLength: "LeaseContractLength",
+	field.OfferingClass:       "OfferingClass",
+	field.PurchaseOption:      "PurchaseOption",
 }
 
 func newPriceWithProduct(values map[field.Field]string) (*price.WithProduct, error) {
@@ -111,6 +111,41 @@ func TestIngester_Ingest(t *testing.T) {
 		require.NoError(t, ing.Err())
 	})
 
+	t.Run("ReservedTerms", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client := mock.NewHTTPClient(ctrl)
+		ing, err := NewIngester("AmazonEC2", "eu-west-3", WithHTTPClient(client))
+		require.NoError(t, err)
+
+		content := makeCSV([][]string{
+			{"SKU", "Product Family", "serviceCode", "TermType", "Location", "Unit", "Currency", "PricePerUnit", "Instance Type", "LeaseContractLength", "PurchaseOption", "OfferingClass"},
+			{"prod1", "Compute Instance", "AmazonEC2", "Reserved", "EU (Paris)", "Hrs", "USD", "0.5", "m5.xlarge", "1yr", "Partial Upfront", "standard"},
+			{"prod1", "Compute Instance", "AmazonEC2", "Reserved", "EU (Paris)", "Quantity", "USD", "2000", "m5.xlarge", "1yr", "Partial Upfront", "standard"},
+		})
+		res := &http.Response{Body: ioutil.NopCloser(strings.NewReader(content))}
+
+		client.EXPECT().Do(gomock.Any()).Return(res, nil)
+
+		pps := make([]*price.WithProduct, 0)
+		for pp := range ing.Ingest(context.Background(), 1) {
+			pps = append(pps, pp)
+		}
+		require.NoError(t, ing.Err())
+		require.Len(t, pps, 2)
+
+		attrs := map[string]string{
+			"TermType":            "Reserved",
+			"LeaseContractLength": "1yr",
+			"PurchaseOption":      "Partial Upfront",
+			"OfferingClass":       "standard",
+		}
+		assert.Equal(t, attrs, pps[0].Price.Attributes)
+		assert.Equal(t, attrs, pps[1].Price.Attributes)
+		assert.Equal(t, "Quantity", pps[1].Price.Unit)
+	})
+
 	t.Run("LazyQuotes", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -0,0 +1,245 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// regionIndex is the region index of the offers of a service, it has the URL of
+// the current version of the offer file of each one of the regions
+type regionIndex struct {
+	Regions map[string]struct {
+		RegionCode        string `json:"regionCode"`
+		CurrentVersionURL string `json:"currentVersionUrl"`
+	} `json:"regions"`
+}
+
+// savingsPlansRegionIndex is the region index of the Savings Plans offers, which has a list of regions
+type savingsPlansRegionIndex struct {
+	Regions []struct {
+		RegionCode string `json:"regionCode"`
+		VersionURL string `json:"versionUrl"`
+	} `json:"regions"`
+}
+
+// openOffer returns the offer file of the service on the region, read from the disk if the pricingURL is a local
+// path, from the cacheDir if it already has the current version of it or downloaded otherwise.
+// The returned io.ReadCloser must be manually closed after use.
+func (ing *Ingester) openOffer(ctx context.Context) (io.ReadCloser, int64, error) {
+	if dir, ok := localPath(ing.pricingURL); ok {
+		return openFile(filepath.Join(dir, ing.service, "current", ing.region, "index."+string(ing.offerFormat)))
+	}
+	if ing.cacheDir == "" {
+		return ing.download(ctx, ing.offersURL()+"/"+ing.service+"/current/"+ing.region+"/index."+string(ing.offerFormat))
+	}
+
+	version, err := ing.currentVersion(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dir := filepath.Join(ing.cacheDir, ing.service, ing.region)
+	file := filepath.Join(dir, version+"."+string(ing.offerFormat))
+	if _, err := os.Stat(file); err == nil {
+		return openFile(file)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, 0, fmt.Errorf("failed to create the cache directory: %w", err)
+	}
+	if err := ing.downloadFile(ctx, ing.offersURL()+"/"+ing.service+"/"+version+"/"+ing.region+"/index."+string(ing.offerFormat), file); err != nil {
+		return nil, 0, err
+	}
+
+	// The previous versions of the offer are no longer needed
+	olds, _ := filepath.Glob(filepath.Join(dir, "*."+string(ing.offerFormat)))
+	for _, old := range olds {
+		if old != file {
+			os.Remove(old)
+		}
+	}
+
+	return openFile(file)
+}
+
+// currentVersion returns the version of the current offer file of the region from the region index of the
+// service, which is cached and only downloaded again if its ETag changed
+func (ing *Ingester) currentVersion(ctx context.Context) (string, error) {
+	dir := filepath.Join(ing.cacheDir, ing.service)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create the cache directory: %w", err)
+	}
+
+	file := filepath.Join(dir, "region_index.json")
+	etag, _ := os.ReadFile(file + ".etag")
+	if _, err := os.Stat(file); err != nil {
+		etag = nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ing.offersURL()+"/"+ing.service+"/current/region_index.json", nil)
+	if err != nil {
+		return "", err
+	}
+	if len(etag) != 0 {
+		req.Header.Set("If-None-Match", string(etag))
+	}
+	resp, err := ing.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+	case http.StatusOK:
+		if err := writeFile(file, resp.Body); err != nil {
+			return "", fmt.Errorf("failed to cache the region index: %w", err)
+		}
+		if err := os.WriteFile(file+".etag", []byte(resp.Header.Get("ETag")), 0o644); err != nil {
+			return "", fmt.Errorf("failed to cache the region index: %w", err)
+		}
+	default:
+		return "", fmt.Errorf("failed to get the region index of %s: %s", ing.service, resp.Status)
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	var versionURL string
+	if ing.service == SavingsPlansService {
+		var ri savingsPlansRegionIndex
+		if err := json.Unmarshal(b, &ri); err != nil {
+			return "", fmt.Errorf("failed to read the region index of %s: %w", ing.service, err)
+		}
+		for _, r := range ri.Regions {
+			if r.RegionCode == ing.region {
+				versionURL = r.VersionURL
+			}
+		}
+	} else {
+		var ri regionIndex
+		if err := json.Unmarshal(b, &ri); err != nil {
+			return "", fmt.Errorf("failed to read the region index of %s: %w", ing.service, err)
+		}
+		versionURL = ri.Regions[ing.region].CurrentVersionURL
+	}
+	if versionURL == "" {
+		return "", fmt.Errorf("region %q not found on the region index of %s", ing.region, ing.service)
+	}
+
+	// The URL is like /offers/v1.0/aws/AmazonEC2/20240620175707/us-east-1/index.json
+	version := path.Base(path.Dir(path.Dir(versionURL)))
+	if version == "." || version == "/" {
+		return "", fmt.Errorf("invalid current version URL %q of region %q of %s", versionURL, ing.region, ing.service)
+	}
+	return version, nil
+}
+
+// downloadFile downloads the url to the file. The download is written to a partial file first, so if it's interrupted
+// the next one resumes from where it stopped if the ETag of the url did not change.
+func (ing *Ingester) downloadFile(ctx context.Context, url, file string) error {
+	part := file + ".part"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+	etag, _ := os.ReadFile(part + ".etag")
+	if fi, err := os.Stat(part); err == nil && len(etag) != 0 && fi.Size() > 0 {
+		offset = fi.Size()
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", string(etag))
+	}
+
+	resp, err := ing.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		flags |= os.O_TRUNC
+		if err := os.WriteFile(part+".etag", []byte(resp.Header.Get("ETag")), 0o644); err != nil {
+			return fmt.Errorf("failed to cache the offer file: %w", err)
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is not valid anymore so the next download starts again
+		os.Remove(part)
+		return fmt.Errorf("failed to resume the download of %s: %s", url, resp.Status)
+	default:
+		return fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	f, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to cache the offer file: %w", err)
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to cache the offer file: %w", err)
+	}
+
+	os.Remove(part + ".etag")
+	return os.Rename(part, file)
+}
+
+// localPath returns the path of the pricingURL if it's a local one, a file:// URL or a path
+func localPath(pricingURL string) (string, bool) {
+	u, err := url.Parse(pricingURL)
+	if err != nil || (u.Scheme != "" && u.Scheme != "file") {
+		return "", false
+	}
+	if u.Scheme == "file" {
+		return filepath.FromSlash(u.Path), true
+	}
+	return pricingURL, true
+}
+
+func openFile(name string) (io.ReadCloser, int64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, fi.Size(), nil
+}
+
+// writeFile writes the r to the file atomically, as the ingesters of the
+// other regions of the service may be writing it at the same time
+func writeFile(name string, r io.Reader) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, name)
+}
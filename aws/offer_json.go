@@ -0,0 +1,291 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/cycloidio/terracost/aws/region"
+	"github.com/cycloidio/terracost/price"
+	"github.com/cycloidio/terracost/product"
+)
+
+// offerProduct is a product of the JSON offer files and of the Price List Query API
+type offerProduct struct {
+	SKU           string            `json:"sku"`
+	ProductFamily string            `json:"productFamily"`
+	Attributes    map[string]string `json:"attributes"`
+}
+
+// offerTerm is a term (OnDemand or Reserved) of a product with its prices
+type offerTerm struct {
+	OfferTermCode   string                         `json:"offerTermCode"`
+	PriceDimensions map[string]offerPriceDimension `json:"priceDimensions"`
+	TermAttributes  map[string]string              `json:"termAttributes"`
+}
+
+type offerPriceDimension struct {
+	Unit         string            `json:"unit"`
+	BeginRange   string            `json:"beginRange"`
+	PricePerUnit map[string]string `json:"pricePerUnit"`
+}
+
+// priceListItem is an item of the PriceList of the Price List Query API, the terms
+// are by type and then by offer term code
+type priceListItem struct {
+	Product offerProduct                    `json:"product"`
+	Terms   map[string]map[string]offerTerm `json:"terms"`
+}
+
+// jsonProductAttributes is a mapping from the attributes of the JSON products to the product.Product attribute
+// names used for the columns of the CSV offer files, the rest of the attributes are stored with their JSON name
+var jsonProductAttributes = map[string]string{
+	"capacitystatus":  "CapacityStatus",
+	"group":           "Group",
+	"instanceType":    "InstanceType",
+	"operatingSystem": "OperatingSystem",
+	"preInstalledSw":  "PreInstalledSW",
+	"tenancy":         "Tenancy",
+	"usagetype":       "UsageType",
+	"volumeApiName":   "VolumeAPIName",
+	"volumeType":      "VolumeType",
+
+	// EFS attributes
+	"storageClass":    "StorageClass",
+	"accessType":      "AccessType",
+	"throughputClass": "ThroughputClass",
+
+	// ElastiCache
+	"cacheEngine": "CacheEngine",
+
+	// RDS attributes
+	"databaseEngine":   "DatabaseEngine",
+	"databaseEdition":  "DatabaseEdition",
+	"deploymentOption": "DeploymentOption",
+	"licenseModel":     "LicenseModel",
+
+	// FSx attributes
+	"fileSystemType":     "FileSystemType",
+	"storageType":        "StorageType",
+	"throughputCapacity": "ThroughputCapacity",
+
+	// Cloudwatch alarms
+	"alarmType": "AlarmType",
+}
+
+// reservedTermAttributes are the attributes of the Reserved terms stored on their prices
+var reservedTermAttributes = []string{"LeaseContractLength", "OfferingClass", "PurchaseOption"}
+
+// readJSON reads the offer file on the JSON format and sends a price.WithProduct on the results channel for each
+// one of the prices of its terms. The file is decoded as a stream as the offers can be of several GB, only the
+// products are kept in memory until their terms, that come after them, are read.
+func (ing *Ingester) readJSON(ctx context.Context, r io.Reader, results chan<- *price.WithProduct) error {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	products := make(map[string]offerProduct)
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "products":
+			if err := expectDelim(dec, '{'); err != nil {
+				return err
+			}
+			for dec.More() {
+				if _, err := dec.Token(); err != nil {
+					return err
+				}
+				var p offerProduct
+				if err := dec.Decode(&p); err != nil {
+					return fmt.Errorf("failed to decode the product: %w", err)
+				}
+				products[p.SKU] = p
+			}
+			if err := expectDelim(dec, '}'); err != nil {
+				return err
+			}
+		case "terms":
+			if err := expectDelim(dec, '{'); err != nil {
+				return err
+			}
+			for dec.More() {
+				termType, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				if err := expectDelim(dec, '{'); err != nil {
+					return err
+				}
+				for dec.More() {
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					default:
+					}
+
+					sku, err := dec.Token()
+					if err != nil {
+						return err
+					}
+					var terms map[string]offerTerm
+					if err := dec.Decode(&terms); err != nil {
+						return fmt.Errorf("failed to decode the terms of %v: %w", sku, err)
+					}
+
+					p, ok := products[fmt.Sprint(sku)]
+					if !ok {
+						continue
+					}
+					if err := ing.sendTerms(p, fmt.Sprint(termType), terms, results); err != nil {
+						return err
+					}
+				}
+				if err := expectDelim(dec, '}'); err != nil {
+					return err
+				}
+			}
+			if err := expectDelim(dec, '}'); err != nil {
+				return err
+			}
+		default:
+			// The metadata of the offer (formatVersion, version, publicationDate...) is skipped
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readPriceList reads the pages of the price list of the Price List Query API and sends a price.WithProduct on the
+// results channel for each one of the prices of the terms of its products
+func (ing *Ingester) readPriceList(ctx context.Context, results chan<- *price.WithProduct) error {
+	var token string
+	for {
+		list, next, err := ing.priceListClient.GetProducts(ctx, ing.service, ing.region, token)
+		if err != nil {
+			return fmt.Errorf("failed to get the products of %s on %q: %w", ing.service, ing.region, err)
+		}
+
+		for _, doc := range list {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+
+			var item priceListItem
+			if err := json.Unmarshal([]byte(doc), &item); err != nil {
+				return fmt.Errorf("failed to decode the price list: %w", err)
+			}
+			for termType, terms := range item.Terms {
+				if err := ing.sendTerms(item.Product, termType, terms, results); err != nil {
+					return err
+				}
+			}
+		}
+
+		if next == "" {
+			return nil
+		}
+		token = next
+	}
+}
+
+// sendTerms sends a price.WithProduct, that passes the IngestionFilter, on the results
+// channel for each one of the prices of the terms of type termType of the product
+func (ing *Ingester) sendTerms(p offerProduct, termType string, terms map[string]offerTerm, results chan<- *price.WithProduct) error {
+	prod := newJSONProduct(p, ing.service)
+	for _, term := range terms {
+		for _, pd := range term.PriceDimensions {
+			for cur, v := range pd.PricePerUnit {
+				priceVal, err := decimal.NewFromString(v)
+				if err != nil {
+					return fmt.Errorf("failed to parse PricePerUnit: %w", err)
+				}
+
+				priceAttrs := map[string]string{"TermType": termType}
+				if pd.BeginRange != "" {
+					priceAttrs["StartingRange"] = pd.BeginRange
+				}
+				for _, k := range reservedTermAttributes {
+					if v := term.TermAttributes[k]; v != "" {
+						priceAttrs[k] = v
+					}
+				}
+
+				pp := &price.WithProduct{
+					Price: price.Price{
+						Unit:       pd.Unit,
+						Value:      priceVal,
+						Currency:   cur,
+						Attributes: priceAttrs,
+					},
+					Product: prod,
+				}
+				if ing.ingestionFilter(pp) {
+					results <- pp
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// newJSONProduct returns the product.Product of the p with all its attributes, the service is used if the
+// attributes do not have it
+func newJSONProduct(p offerProduct, service string) *product.Product {
+	prod := &product.Product{
+		Provider:   ProviderName,
+		SKU:        p.SKU,
+		Service:    service,
+		Family:     p.ProductFamily,
+		Attributes: make(map[string]string),
+	}
+
+	for k, v := range p.Attributes {
+		if v == "" {
+			continue
+		}
+		switch k {
+		case "servicecode":
+			prod.Service = v
+		case "location":
+			prod.Location = region.NewFromName(v).String()
+		default:
+			if attr, ok := jsonProductAttributes[k]; ok {
+				k = attr
+			}
+			prod.Attributes[k] = v
+		}
+	}
+
+	// On the CSV offer files the deployment option of FSx is a different column than the RDS one
+	if dopt, ok := prod.Attributes["DeploymentOption"]; ok && prod.Service == "AmazonFSx" {
+		delete(prod.Attributes, "DeploymentOption")
+		prod.Attributes["Deployment_option"] = dopt
+	}
+	return prod
+}
+
+// expectDelim reads the next token of the dec and fails if it's not the delim
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("invalid JSON offer: expected %q but found %v", delim, t)
+	}
+	return nil
+}
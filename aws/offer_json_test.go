@@ -0,0 +1,197 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cycloidio/terracost/price"
+	"github.com/cycloidio/terracost/product"
+)
+
+const offerJSON = `{
+  "formatVersion": "v1.0",
+  "offerCode": "AmazonEC2",
+  "version": "20240101000000",
+  "products": {
+    "prod1": {
+      "sku": "prod1",
+      "productFamily": "Compute Instance",
+      "attributes": {
+        "servicecode": "AmazonEC2",
+        "location": "EU (Paris)",
+        "instanceType": "m5.xlarge",
+        "operatingSystem": "Linux",
+        "tenancy": "Shared",
+        "vcpu": "4",
+        "memory": "16 GiB"
+      }
+    },
+    "prod2": {
+      "sku": "prod2",
+      "productFamily": "Storage",
+      "attributes": {
+        "servicecode": "AmazonEC2",
+        "location": "EU (Paris)",
+        "volumeApiName": "gp2"
+      }
+    }
+  },
+  "terms": {
+    "OnDemand": {
+      "prod1": {
+        "prod1.JRTCKXETXF": {
+          "offerTermCode": "JRTCKXETXF",
+          "sku": "prod1",
+          "priceDimensions": {
+            "prod1.JRTCKXETXF.6YS6EN2CT7": {"unit": "Hrs", "beginRange": "0", "endRange": "Inf", "pricePerUnit": {"USD": "1.2340000000"}}
+          },
+          "termAttributes": {}
+        }
+      },
+      "prod2": {
+        "prod2.JRTCKXETXF": {
+          "offerTermCode": "JRTCKXETXF",
+          "sku": "prod2",
+          "priceDimensions": {
+            "prod2.JRTCKXETXF.6YS6EN2CT7": {"unit": "GB-Mo", "pricePerUnit": {"USD": "0.4560000000"}}
+          },
+          "termAttributes": {}
+        }
+      }
+    }
+  }
+}`
+
+func TestIngester_Ingest_JSON(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/AmazonEC2/current/eu-west-3/index.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(offerJSON))
+	}))
+	defer s.Close()
+
+	ing, err := NewIngester("AmazonEC2", "eu-west-3", WithPricingURL(s.URL), WithOfferFormat(OfferJSON))
+	require.NoError(t, err)
+
+	pps := make([]*price.WithProduct, 0)
+	for pp := range ing.Ingest(context.Background(), 1) {
+		pps = append(pps, pp)
+	}
+	require.NoError(t, ing.Err())
+
+	expected := []*price.WithProduct{
+		{
+			Product: &product.Product{
+				Provider: ProviderName,
+				SKU:      "prod1",
+				Service:  "AmazonEC2",
+				Family:   "Compute Instance",
+				Location: "eu-west-3",
+				Attributes: map[string]string{
+					"InstanceType":    "m5.xlarge",
+					"OperatingSystem": "Linux",
+					"Tenancy":         "Shared",
+					"vcpu":            "4",
+					"memory":          "16 GiB",
+				},
+			},
+			Price: price.Price{
+				Unit:       "Hrs",
+				Currency:   "USD",
+				Value:      decimal.RequireFromString("1.234"),
+				Attributes: map[string]string{"TermType": "OnDemand", "StartingRange": "0"},
+			},
+		},
+		{
+			Product: &product.Product{
+				Provider:   ProviderName,
+				SKU:        "prod2",
+				Service:    "AmazonEC2",
+				Family:     "Storage",
+				Location:   "eu-west-3",
+				Attributes: map[string]string{"VolumeAPIName": "gp2"},
+			},
+			Price: price.Price{
+				Unit:       "GB-Mo",
+				Currency:   "USD",
+				Value:      decimal.RequireFromString("0.456"),
+				Attributes: map[string]string{"TermType": "OnDemand"},
+			},
+		},
+	}
+	require.Len(t, pps, len(expected))
+	for i, pp := range pps {
+		assert.Equal(t, expected[i].Product, pp.Product)
+		assert.Equal(t, expected[i].Unit, pp.Unit)
+		assert.Equal(t, expected[i].Currency, pp.Currency)
+		assert.True(t, expected[i].Value.Equal(pp.Value))
+		assert.Equal(t, expected[i].Attributes, pp.Attributes)
+	}
+}
+
+// priceListClient is a stand-in of the Price List Query API with a page for each one of the pages
+type priceListClient struct {
+	pages [][]string
+	err   error
+}
+
+func (c *priceListClient) GetProducts(_ context.Context, service, region, nextToken string) ([]string, string, error) {
+	if c.err != nil {
+		return nil, "", c.err
+	}
+	i := 0
+	if nextToken != "" {
+		i = int(nextToken[0] - '0')
+	}
+	var next string
+	if i+1 < len(c.pages) {
+		next = string(rune('0' + i + 1))
+	}
+	return c.pages[i], next, nil
+}
+
+func TestIngester_Ingest_PriceList(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		client := &priceListClient{pages: [][]string{
+			{
+				`{"product":{"sku":"prod1","productFamily":"Storage","attributes":{"servicecode":"AmazonFSx","location":"EU (Paris)","deploymentOption":"Multi-AZ","fileSystemType":"Windows"}},"terms":{"OnDemand":{"prod1.JRTCKXETXF":{"offerTermCode":"JRTCKXETXF","priceDimensions":{"prod1.JRTCKXETXF.6YS6EN2CT7":{"unit":"GB-Mo","pricePerUnit":{"USD":"0.23"}}}}}}}`,
+			},
+			{
+				`{"product":{"sku":"prod2","productFamily":"Storage","attributes":{"servicecode":"AmazonFSx","location":"EU (Paris)","deploymentOption":"Single-AZ","fileSystemType":"Windows"}},"terms":{"OnDemand":{"prod2.JRTCKXETXF":{"offerTermCode":"JRTCKXETXF","priceDimensions":{"prod2.JRTCKXETXF.6YS6EN2CT7":{"unit":"GB-Mo","pricePerUnit":{"USD":"0.13"}}}}}}}`,
+			},
+		}}
+
+		ing, err := NewIngester("AmazonFSx", "eu-west-3", WithPriceListClient(client))
+		require.NoError(t, err)
+
+		pps := make([]*price.WithProduct, 0)
+		for pp := range ing.Ingest(context.Background(), 1) {
+			pps = append(pps, pp)
+		}
+		require.NoError(t, ing.Err())
+		require.Len(t, pps, 2)
+
+		sort.Slice(pps, func(i, j int) bool { return pps[i].Product.SKU < pps[j].Product.SKU })
+		assert.Equal(t, "eu-west-3", pps[0].Product.Location)
+		assert.Equal(t, map[string]string{"Deployment_option": "Multi-AZ", "FileSystemType": "Windows"}, pps[0].Product.Attributes)
+		assert.Equal(t, map[string]string{"Deployment_option": "Single-AZ", "FileSystemType": "Windows"}, pps[1].Product.Attributes)
+		assert.True(t, decimal.RequireFromString("0.13").Equal(pps[1].Value))
+	})
+	t.Run("Error", func(t *testing.T) {
+		ing, err := NewIngester("AmazonFSx", "eu-west-3", WithPriceListClient(&priceListClient{err: errors.New("throttled")}))
+		require.NoError(t, err)
+		for range ing.Ingest(context.Background(), 1) {
+		}
+		assert.EqualError(t, ing.Err(), `failed to get the products of AmazonFSx on "eu-west-3": throttled`)
+	})
+}
@@ -0,0 +1,170 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cycloidio/terracost/price"
+)
+
+var offerCSV = makeCSV([][]string{
+	{"SKU", "Product Family", "serviceCode", "TermType", "Location", "Unit", "Currency", "PricePerUnit", "Instance Type"},
+	{"prod1", "Compute Instance", "AmazonEC2", "OnDemand", "EU (Paris)", "Hrs", "USD", "1.234", "m5.xlarge"},
+	{"prod2", "Compute Instance", "AmazonEC2", "OnDemand", "EU (Paris)", "Hrs", "USD", "0.456", "t3.micro"},
+})
+
+// offerServer is a stand-in of the AWS pricing of the AmazonEC2 on eu-west-3
+type offerServer struct {
+	*httptest.Server
+
+	mux      sync.Mutex
+	version  string
+	requests []*http.Request
+}
+
+func newOfferServer(t *testing.T) *offerServer {
+	s := &offerServer{version: "20240101000000"}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mux.Lock()
+		s.requests = append(s.requests, r)
+		version := s.version
+		s.mux.Unlock()
+
+		switch r.URL.Path {
+		case "/AmazonEC2/current/region_index.json":
+			w.Header().Set("ETag", fmt.Sprintf("%q", version))
+			index := fmt.Sprintf(`{"regions":{"eu-west-3":{"regionCode":"eu-west-3","currentVersionUrl":"/offers/v1.0/aws/AmazonEC2/%s/eu-west-3/index.json"}}}`, version)
+			http.ServeContent(w, r, "region_index.json", time.Time{}, strings.NewReader(index))
+		case "/AmazonEC2/" + version + "/eu-west-3/index.csv":
+			w.Header().Set("ETag", fmt.Sprintf("%q", version))
+			http.ServeContent(w, r, "index.csv", time.Time{}, strings.NewReader(offerCSV))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// paths returns the paths requested since the last call
+func (s *offerServer) paths() []string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	paths := make([]string, 0, len(s.requests))
+	for _, r := range s.requests {
+		paths = append(paths, r.URL.Path)
+	}
+	s.requests = nil
+	return paths
+}
+
+func ingestSKUs(t *testing.T, ing *Ingester) []string {
+	skus := make([]string, 0)
+	for pp := range ing.Ingest(context.Background(), 1) {
+		skus = append(skus, pp.Product.SKU)
+	}
+	require.NoError(t, ing.Err())
+	return skus
+}
+
+func TestIngester_Ingest_Cache(t *testing.T) {
+	t.Run("Cache", func(t *testing.T) {
+		s := newOfferServer(t)
+		dir := t.TempDir()
+
+		ing, err := NewIngester("AmazonEC2", "eu-west-3", WithPricingURL(s.URL), WithCacheDir(dir))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"prod1", "prod2"}, ingestSKUs(t, ing))
+		assert.Equal(t, []string{"/AmazonEC2/current/region_index.json", "/AmazonEC2/20240101000000/eu-west-3/index.csv"}, s.paths())
+		assert.FileExists(t, filepath.Join(dir, "AmazonEC2", "eu-west-3", "20240101000000.csv"))
+
+		// The region index did not change so the cached offer file is used
+		ing, err = NewIngester("AmazonEC2", "eu-west-3", WithPricingURL(s.URL), WithCacheDir(dir))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"prod1", "prod2"}, ingestSKUs(t, ing))
+		assert.Equal(t, []string{"/AmazonEC2/current/region_index.json"}, s.paths())
+
+		// A new version is downloaded and the previous one removed
+		s.mux.Lock()
+		s.version = "20240201000000"
+		s.mux.Unlock()
+		ing, err = NewIngester("AmazonEC2", "eu-west-3", WithPricingURL(s.URL), WithCacheDir(dir))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"prod1", "prod2"}, ingestSKUs(t, ing))
+		assert.Equal(t, []string{"/AmazonEC2/current/region_index.json", "/AmazonEC2/20240201000000/eu-west-3/index.csv"}, s.paths())
+		assert.FileExists(t, filepath.Join(dir, "AmazonEC2", "eu-west-3", "20240201000000.csv"))
+		assert.NoFileExists(t, filepath.Join(dir, "AmazonEC2", "eu-west-3", "20240101000000.csv"))
+	})
+	t.Run("Resume", func(t *testing.T) {
+		s := newOfferServer(t)
+		dir := t.TempDir()
+
+		part := filepath.Join(dir, "AmazonEC2", "eu-west-3", "20240101000000.csv.part")
+		require.NoError(t, os.MkdirAll(filepath.Dir(part), 0o755))
+		require.NoError(t, os.WriteFile(part, []byte(offerCSV[:50]), 0o644))
+		require.NoError(t, os.WriteFile(part+".etag", []byte(`"20240101000000"`), 0o644))
+
+		var rng string
+		client := httpClientFunc(func(r *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(r.URL.Path, ".csv") {
+				rng = r.Header.Get("Range")
+			}
+			return http.DefaultClient.Do(r)
+		})
+
+		ing, err := NewIngester("AmazonEC2", "eu-west-3", WithPricingURL(s.URL), WithCacheDir(dir), WithHTTPClient(client))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"prod1", "prod2"}, ingestSKUs(t, ing))
+		assert.Equal(t, "bytes=50-", rng)
+		assert.NoFileExists(t, part)
+
+		b, err := os.ReadFile(filepath.Join(dir, "AmazonEC2", "eu-west-3", "20240101000000.csv"))
+		require.NoError(t, err)
+		assert.Equal(t, offerCSV, string(b))
+	})
+	t.Run("UnknownRegion", func(t *testing.T) {
+		s := newOfferServer(t)
+
+		ing, err := NewIngester("AmazonEC2", "us-east-1", WithPricingURL(s.URL), WithCacheDir(t.TempDir()))
+		require.NoError(t, err)
+		for range ing.Ingest(context.Background(), 1) {
+		}
+		assert.EqualError(t, ing.Err(), `region "us-east-1" not found on the region index of AmazonEC2`)
+	})
+}
+
+func TestIngester_Ingest_LocalPath(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "AmazonEC2", "current", "eu-west-3", "index.csv")
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+	require.NoError(t, os.WriteFile(file, []byte(offerCSV), 0o644))
+
+	for _, pricingURL := range []string{dir, "file://" + filepath.ToSlash(dir)} {
+		ing, err := NewIngester("AmazonEC2", "eu-west-3", WithPricingURL(pricingURL))
+		require.NoError(t, err)
+
+		pps := make([]*price.WithProduct, 0)
+		for pp := range ing.Ingest(context.Background(), 1) {
+			pps = append(pps, pp)
+		}
+		require.NoError(t, ing.Err())
+		require.Len(t, pps, 2)
+		assert.Equal(t, "eu-west-3", pps[0].Product.Location)
+	}
+}
+
+type httpClientFunc func(*http.Request) (*http.Response, error)
+
+func (fn httpClientFunc) Do(r *http.Request) (*http.Response, error) { return fn(r) }
@@ -1,6 +1,7 @@
 package aws
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -14,10 +15,28 @@ type HTTPClient interface {
 	Do(*http.Request) (*http.Response, error)
 }
 
+// PriceListClient is an interface of a client of the AWS Price List Query API
+type PriceListClient interface {
+	// GetProducts returns a page of the price list of the service on the region (as the JSON documents
+	// of the PriceList of the API) and the token of the next one, empty if it's the last one
+	GetProducts(ctx context.Context, service, region, nextToken string) ([]string, string, error)
+}
+
+// OfferFormat is the format of the AWS offer files
+type OfferFormat string
+
+// List of the formats of the offer files
+const (
+	OfferCSV  OfferFormat = "csv"
+	OfferJSON OfferFormat = "json"
+)
+
 // Option is used to configure the Ingester.
 type Option func(ing *Ingester)
 
 // WithPricingURL sets the base AWS pricing URL, "https://pricing.us-east-1.amazonaws.com/offers/v1.0/aws" by default.
+// It can also be a local path, or a file:// URL, with the same layout ({service}/current/{region}/index.csv) to
+// ingest offer files already downloaded.
 func WithPricingURL(url string) Option {
 	return func(ing *Ingester) {
 		ing.pricingURL = url
@@ -38,6 +57,29 @@ func WithBufferSize(size uint) Option {
 	}
 }
 
+// WithCacheDir caches the offer files on the dir, so they are only downloaded again when the region index of
+// the service has a new version of them. The interrupted downloads are resumed by the next ingestion.
+func WithCacheDir(dir string) Option {
+	return func(ing *Ingester) {
+		ing.cacheDir = dir
+	}
+}
+
+// WithOfferFormat sets the format of the offer files read, OfferCSV by default.
+func WithOfferFormat(format OfferFormat) Option {
+	return func(ing *Ingester) {
+		ing.offerFormat = format
+	}
+}
+
+// WithPriceListClient reads the pricing from the AWS Price List Query API with the client
+// instead of the offer files.
+func WithPriceListClient(client PriceListClient) Option {
+	return func(ing *Ingester) {
+		ing.priceListClient = client
+	}
+}
+
 // WithProgress sets a channel for receiving progress updates. By default progress is not sent.
 func WithProgress(progressCh chan<- progress.Progress, interval time.Duration) Option {
 	return func(ing *Ingester) {
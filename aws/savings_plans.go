@@ -0,0 +1,120 @@
+package aws
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/cycloidio/terracost/aws/region"
+	"github.com/cycloidio/terracost/price"
+	"github.com/cycloidio/terracost/product"
+)
+
+const (
+	// SavingsPlansService is the service of the offer files of the Compute and EC2 Instance Savings Plans, their
+	// products are the rates of the plans for each one of the usages they discount
+	SavingsPlansService = "AWSComputeSavingsPlan"
+
+	defaultSavingsPlansURL = "https://pricing.us-east-1.amazonaws.com/savingsPlan/v1.0/aws"
+)
+
+// columnSavingsPlanToIngest is a mapping from the columns of the Savings Plans offer files to the product.Product
+// attribute names under which the values will be stored.
+var columnSavingsPlanToIngest = map[string]string{
+	"DiscountedSKU":         "DiscountedSKU",
+	"DiscountedServiceCode": "DiscountedServiceCode",
+	"DiscountedUsageType":   "DiscountedUsageType",
+	"DiscountedOperation":   "DiscountedOperation",
+	"Instance Family":       "InstanceFamily",
+	"PurchaseOption":        "PurchaseOption",
+}
+
+// offersURL returns the base URL of the offer files of the service
+func (ing *Ingester) offersURL() string {
+	if ing.service == SavingsPlansService && ing.pricingURL == defaultPricingURL {
+		return defaultSavingsPlansURL
+	}
+	return ing.pricingURL
+}
+
+// readSavingsPlansCSV reads the Savings Plans offer file on the CSV format and sends a price.WithProduct on the
+// results channel for each one of its rates. The LeaseContractLength is stored like the one of the Reserved
+// terms (1yr, 3yr) so both can be chosen in the same way.
+func (ing *Ingester) readSavingsPlansCSV(ctx context.Context, r io.Reader, results chan<- *price.WithProduct) error {
+	csvr := csv.NewReader(r)
+	csvr.LazyQuotes = true
+	csvr.FieldsPerRecord = -1
+
+	var columns map[string]int
+	for {
+		values, err := csvr.Read()
+		if err != nil {
+			return err
+		}
+		if len(values) > 2 {
+			columns = readColumnPositions(values)
+			break
+		}
+	}
+
+	value := func(row []string, col string) string {
+		if i, ok := columns[col]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		row, err := csvr.Read()
+		if err != nil {
+			if err != io.EOF {
+				return err
+			}
+			return nil
+		}
+
+		rate, err := decimal.NewFromString(value(row, "DiscountedRate"))
+		if err != nil {
+			return fmt.Errorf("failed to parse DiscountedRate: %w", err)
+		}
+
+		attributes := make(map[string]string)
+		for col, attr := range columnSavingsPlanToIngest {
+			if v := value(row, col); v != "" {
+				attributes[attr] = v
+			}
+		}
+		if l := value(row, "LeaseContractLength"); l != "" {
+			attributes["LeaseContractLength"] = l + "yr"
+		}
+
+		pp := &price.WithProduct{
+			Price: price.Price{
+				Unit:       value(row, "Unit"),
+				Value:      rate,
+				Currency:   value(row, "Currency"),
+				Attributes: map[string]string{"TermType": "SavingsPlan"},
+			},
+			Product: &product.Product{
+				Provider:   ProviderName,
+				SKU:        value(row, "RateCode"),
+				Service:    ing.service,
+				Family:     value(row, "Product Family"),
+				Location:   region.NewFromName(value(row, "Location")).String(),
+				Attributes: attributes,
+			},
+		}
+		if ing.ingestionFilter(pp) {
+			results <- pp
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package aws
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cycloidio/terracost/price"
+	"github.com/cycloidio/terracost/product"
+)
+
+var savingsPlansCSV = makeCSV([][]string{
+	{"FormatVersion", "v1.0"},
+	{"SKU", "RateCode", "Unit", "EffectiveDate", "DiscountedRate", "Currency", "DiscountedSKU", "DiscountedServiceCode", "DiscountedUsageType", "DiscountedOperation", "PurchaseOption", "LeaseContractLength", "LeaseContractLengthUnit", "Instance Family", "Location", "Product Family"},
+	{"sp1", "sp1.disc1", "Hrs", "2023-01-01", "0.123", "USD", "disc1", "AmazonEC2", "EU-BoxUsage:m5.xlarge", "RunInstances", "No Upfront", "1", "year", "", "EU (Paris)", "ComputeSavingsPlans"},
+	{"sp1", "sp1.disc2", "Hrs", "2023-01-01", "0.012", "USD", "disc2", "AmazonEC2", "EU-BoxUsage:m5.xlarge", "RunInstances:0002", "No Upfront", "1", "year", "", "EU (Paris)", "ComputeSavingsPlans"},
+	{"sp2", "sp2.disc3", "Hrs", "2023-01-01", "0.021", "USD", "disc3", "AWSLambda", "EU-Lambda-GB-Second", "Invoke", "All Upfront", "3", "year", "", "EU (Paris)", "ComputeSavingsPlans"},
+})
+
+func TestIngester_Ingest_SavingsPlans(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, SavingsPlansService, "current", "eu-west-3", "index.csv")
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+	require.NoError(t, os.WriteFile(file, []byte(savingsPlansCSV), 0o644))
+
+	t.Run("Success", func(t *testing.T) {
+		ing, err := NewIngester(SavingsPlansService, "eu-west-3", WithPricingURL(dir))
+		require.NoError(t, err)
+
+		pps := make([]*price.WithProduct, 0)
+		for pp := range ing.Ingest(context.Background(), 1) {
+			pps = append(pps, pp)
+		}
+		require.NoError(t, ing.Err())
+		require.Len(t, pps, 3)
+
+		assert.Equal(t, &price.WithProduct{
+			Price: price.Price{
+				Unit:       "Hrs",
+				Value:      decimal.RequireFromString("0.123"),
+				Currency:   "USD",
+				Attributes: map[string]string{"TermType": "SavingsPlan"},
+			},
+			Product: &product.Product{
+				Provider: ProviderName,
+				SKU:      "sp1.disc1",
+				Service:  SavingsPlansService,
+				Family:   "ComputeSavingsPlans",
+				Location: "eu-west-3",
+				Attributes: map[string]string{
+					"DiscountedSKU":         "disc1",
+					"DiscountedServiceCode": "AmazonEC2",
+					"DiscountedUsageType":   "EU-BoxUsage:m5.xlarge",
+					"DiscountedOperation":   "RunInstances",
+					"PurchaseOption":        "No Upfront",
+					"LeaseContractLength":   "1yr",
+				},
+			},
+		}, pps[0])
+		assert.Equal(t, "3yr", pps[2].Product.Attributes["LeaseContractLength"])
+	})
+
+	t.Run("MinimalFilter", func(t *testing.T) {
+		ing, err := NewIngester(SavingsPlansService, "eu-west-3", WithPricingURL(dir), WithIngestionFilter(MinimalFilter))
+		require.NoError(t, err)
+
+		pps := make([]*price.WithProduct, 0)
+		for pp := range ing.Ingest(context.Background(), 1) {
+			pps = append(pps, pp)
+		}
+		require.NoError(t, ing.Err())
+		require.Len(t, pps, 1)
+		assert.Equal(t, "sp1.disc1", pps[0].Product.SKU)
+	})
+
+	t.Run("JSONFormat", func(t *testing.T) {
+		ing, err := NewIngester(SavingsPlansService, "eu-west-3", WithPricingURL(dir), WithOfferFormat(OfferJSON))
+		require.NoError(t, err)
+
+		for range ing.Ingest(context.Background(), 1) {
+		}
+		assert.Error(t, ing.Err())
+	})
+}
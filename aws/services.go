@@ -19,6 +19,8 @@ var supportedServices = map[string]struct{}{
 	"awskms":            {},
 	"AWSQueueService":   {},
 	"AWSSecretsManager": {},
+
+	SavingsPlansService: {},
 }
 
 // IsServiceSupported returns true if the AWS service is valid and supported by Terracost (e.g. for ingestion.)
@@ -39,6 +39,8 @@ type autoscalingGroupValues struct {
 
 	MinSize         int64 `mapstructure:"min_size"`
 	DesiredCapacity int64 `mapstructure:"desired_capacity"`
+
+	Usage commitmentUsage `mapstructure:"tc_usage"`
 }
 
 // decodeAutoscalingGroupValues decodes and returns instanceValues from a Terraform values map.
@@ -72,6 +74,7 @@ func (p *Provider) newAutoscalingGroup(rss map[string]terraform.Resource, vals a
 		operatingSystem: "Linux",
 		capacityStatus:  "Used",
 		preInstalledSW:  "NA",
+		commitment:      p.commitment(vals.Usage),
 	}
 
 	var availabilityZone string
@@ -0,0 +1,230 @@
+package terraform
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/cycloidio/terracost/price"
+	"github.com/cycloidio/terracost/product"
+	"github.com/cycloidio/terracost/query"
+	"github.com/cycloidio/terracost/util"
+)
+
+// CommitmentType is the type of the pricing commitment of a resource
+type CommitmentType string
+
+// List of the types of commitments
+const (
+	// OnDemand has no commitment, it's the default
+	OnDemand               CommitmentType = ""
+	ReservedInstance       CommitmentType = "reserved"
+	ComputeSavingsPlan     CommitmentType = "compute"
+	EC2InstanceSavingsPlan CommitmentType = "ec2_instance"
+)
+
+// PaymentOption is how a commitment is paid
+type PaymentOption string
+
+// List of the payment options of the commitments
+const (
+	NoUpfront      PaymentOption = "no_upfront"
+	PartialUpfront PaymentOption = "partial_upfront"
+	AllUpfront     PaymentOption = "all_upfront"
+)
+
+// Commitment is the pricing commitment of the instances, databases and cache nodes. The Savings Plans
+// only apply to the EC2 instances, the rest of the resources are priced on-demand with them.
+type Commitment struct {
+	Type CommitmentType
+
+	// Term is the length of the commitment in years, 1 or 3
+	Term int
+
+	PaymentOption PaymentOption
+
+	// OfferingClass of the EC2 Reserved Instances, "standard" or "convertible"
+	OfferingClass string
+}
+
+// commitmentUsage is the usage of the resources that sets their Commitment, the terms
+// are like "1_year" and the types of Reserved Instances are their OfferingClass
+type commitmentUsage struct {
+	ReservedInstanceType          string `mapstructure:"reserved_instance_type"`
+	ReservedInstanceTerm          string `mapstructure:"reserved_instance_term"`
+	ReservedInstancePaymentOption string `mapstructure:"reserved_instance_payment_option"`
+
+	SavingsPlanType          string `mapstructure:"savings_plan_type"`
+	SavingsPlanTerm          string `mapstructure:"savings_plan_term"`
+	SavingsPlanPaymentOption string `mapstructure:"savings_plan_payment_option"`
+}
+
+// commitment returns the Commitment of the usage u, or the one of the Provider if it has none
+func (p *Provider) commitment(u commitmentUsage) Commitment {
+	if u.ReservedInstanceType != "" || u.ReservedInstanceTerm != "" || u.ReservedInstancePaymentOption != "" {
+		return Commitment{
+			Type:          ReservedInstance,
+			Term:          parseTerm(u.ReservedInstanceTerm),
+			PaymentOption: PaymentOption(u.ReservedInstancePaymentOption),
+			OfferingClass: u.ReservedInstanceType,
+		}.withDefaults()
+	}
+	if u.SavingsPlanType != "" {
+		return Commitment{
+			Type:          CommitmentType(u.SavingsPlanType),
+			Term:          parseTerm(u.SavingsPlanTerm),
+			PaymentOption: PaymentOption(u.SavingsPlanPaymentOption),
+		}.withDefaults()
+	}
+	return p.defaultCommitment.withDefaults()
+}
+
+// parseTerm returns the years of the term t, like "3_year", 0 if it's not valid
+func parseTerm(t string) int {
+	switch t {
+	case "1_year":
+		return 1
+	case "3_year":
+		return 3
+	default:
+		return 0
+	}
+}
+
+// withDefaults returns the c with its unset values set to the defaults, a 1 year term without upfront
+// payment of the standard Reserved Instances
+func (c Commitment) withDefaults() Commitment {
+	if c.Type == OnDemand {
+		return c
+	}
+	if c.Term != 3 {
+		c.Term = 1
+	}
+	switch c.PaymentOption {
+	case NoUpfront, PartialUpfront, AllUpfront:
+	default:
+		c.PaymentOption = NoUpfront
+	}
+	if c.Type == ReservedInstance && c.OfferingClass != "convertible" {
+		c.OfferingClass = "standard"
+	}
+	return c
+}
+
+func (c Commitment) isSavingsPlan() bool {
+	return c.Type == ComputeSavingsPlan || c.Type == EC2InstanceSavingsPlan
+}
+
+// leaseContractLength is the LeaseContractLength of the prices of the commitment
+func (c Commitment) leaseContractLength() string {
+	return fmt.Sprintf("%dyr", c.Term)
+}
+
+// purchaseOption is the PurchaseOption of the prices of the commitment
+func (c Commitment) purchaseOption() string {
+	switch c.PaymentOption {
+	case PartialUpfront:
+		return "Partial Upfront"
+	case AllUpfront:
+		return "All Upfront"
+	default:
+		return "No Upfront"
+	}
+}
+
+// label is the detail of the components priced with the commitment
+func (c Commitment) label() string {
+	switch {
+	case c.Type == ReservedInstance:
+		return fmt.Sprintf("reserved %s %s", c.leaseContractLength(), c.purchaseOption())
+	case c.isSavingsPlan():
+		return fmt.Sprintf("%s savings plan %s %s", c.Type, c.leaseContractLength(), c.purchaseOption())
+	default:
+		return "on-demand"
+	}
+}
+
+// details returns the details with the "on-demand" one replaced by the label of the commitment
+func (c Commitment) details(details []string) []string {
+	res := make([]string, 0, len(details)+1)
+	found := false
+	for _, d := range details {
+		if d == "on-demand" {
+			d = c.label()
+			found = true
+		}
+		res = append(res, d)
+	}
+	if !found && c.Type != OnDemand {
+		res = append(res, c.label())
+	}
+	return res
+}
+
+// reservedComponents returns the components of the on-demand hourly comp priced with the Reserved commitment: the
+// hourly price, unless it's paid all upfront, and the upfront fee amortized monthly over the term, unless there's
+// none. The OfferingClass is only on the prices of the EC2 instances.
+func (c Commitment) reservedComponents(comp query.Component, offeringClass bool) []query.Component {
+	if c.Type != ReservedInstance {
+		return []query.Component{comp}
+	}
+
+	attrs := []*price.AttributeFilter{
+		{Key: "TermType", Value: util.StringPtr("Reserved")},
+		{Key: "LeaseContractLength", Value: util.StringPtr(c.leaseContractLength())},
+		{Key: "PurchaseOption", Value: util.StringPtr(c.purchaseOption())},
+	}
+	if offeringClass {
+		attrs = append(attrs, &price.AttributeFilter{Key: "OfferingClass", Value: util.StringPtr(c.OfferingClass)})
+	}
+
+	comp.Details = c.details(comp.Details)
+	comps := make([]query.Component, 0, 2)
+	if c.PaymentOption != AllUpfront {
+		hourly := comp
+		hourly.PriceFilter = &price.Filter{Unit: util.StringPtr("Hrs"), AttributeFilters: attrs}
+		comps = append(comps, hourly)
+	}
+	if c.PaymentOption != NoUpfront {
+		upfront := comp
+		upfront.Name = comp.Name + " (upfront, amortized)"
+		upfront.HourlyQuantity = decimal.Zero
+		upfront.MonthlyQuantity = comp.HourlyQuantity.Div(decimal.NewFromInt(int64(12 * c.Term)))
+		upfront.PriceFilter = &price.Filter{Unit: util.StringPtr("Quantity"), AttributeFilters: attrs}
+		comps = append(comps, upfront)
+	}
+	return comps
+}
+
+// savingsPlanComponent returns the comp of the EC2 usage usageType priced with the Savings Plan commitment,
+// their rates already include the upfront payment amortized
+func (c Commitment) savingsPlanComponent(comp query.Component, usageType string) query.Component {
+	family := "ComputeSavingsPlans"
+	if c.Type == EC2InstanceSavingsPlan {
+		family = "EC2InstanceSavingsPlans"
+	}
+
+	comp.Details = c.details(comp.Details)
+	comp.ProductFilter = &product.Filter{
+		Provider: comp.ProductFilter.Provider,
+		Service:  util.StringPtr("AWSComputeSavingsPlan"),
+		Family:   util.StringPtr(family),
+		Location: comp.ProductFilter.Location,
+		AttributeFilters: []*product.AttributeFilter{
+			{Key: "DiscountedServiceCode", Value: util.StringPtr("AmazonEC2")},
+			{Key: "DiscountedOperation", Value: util.StringPtr("RunInstances")},
+			// The usage types have the prefix of the region but on us-east-1
+			{Key: "DiscountedUsageType", ValueRegex: util.StringPtr(fmt.Sprintf("^([A-Z0-9]+-)?%s$", regexp.QuoteMeta(usageType)))},
+			{Key: "LeaseContractLength", Value: util.StringPtr(c.leaseContractLength())},
+			{Key: "PurchaseOption", Value: util.StringPtr(c.purchaseOption())},
+		},
+	}
+	comp.PriceFilter = &price.Filter{
+		Unit: util.StringPtr("Hrs"),
+		AttributeFilters: []*price.AttributeFilter{
+			{Key: "TermType", Value: util.StringPtr("SavingsPlan")},
+		},
+	}
+	return comp
+}
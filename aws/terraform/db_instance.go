@@ -40,6 +40,9 @@ type DBInstance struct {
 
 	// storageIOPS is only valid for Provisioned IOPS types of storage and denotes the amount of IOPS allocated.
 	storageIOPS decimal.Decimal
+
+	// commitment is the pricing commitment of the instance, only the Reserved Instances apply
+	commitment Commitment
 }
 
 type dbInstanceValues struct {
@@ -51,6 +54,8 @@ type dbInstanceValues struct {
 	AllocatedStorage float64 `mapstructure:"allocated_storage"`
 	StorageType      string  `mapstructure:"storage_type"`
 	IOPS             float64 `mapstructure:"iops"`
+
+	Usage commitmentUsage `mapstructure:"tc_usage"`
 }
 
 type dbType struct {
@@ -116,6 +121,7 @@ func (p *Provider) newDBInstance(vals dbInstanceValues) *DBInstance {
 		allocatedStorage: decimal.NewFromFloat(vals.AllocatedStorage),
 		storageType:      vals.StorageType,
 		storageIOPS:      decimal.NewFromFloat(vals.IOPS),
+		commitment:       p.commitment(vals.Usage),
 	}
 
 	if reg := region.NewFromZone(vals.AvailabilityZone); reg.Valid() {
@@ -127,7 +133,8 @@ func (p *Provider) newDBInstance(vals dbInstanceValues) *DBInstance {
 
 // Components returns the price component queries that make up this Instance.
 func (inst *DBInstance) Components() []query.Component {
-	components := []query.Component{inst.databaseInstanceComponent(), inst.storageComponent()}
+	components := inst.commitment.reservedComponents(inst.databaseInstanceComponent(), false)
+	components = append(components, inst.storageComponent())
 
 	if strings.HasPrefix(inst.storageType, "io") {
 		components = append(components, inst.iopsComponent())
@@ -152,6 +152,56 @@ func TestDBInstance_Components(t *testing.T) {
 		assert.Equal(t, expected, actual)
 	})
 
+	t.Run("ReservedAllUpfront", func(t *testing.T) {
+		tfres := terraform.Resource{
+			Address:      "aws_db_instance.test",
+			Type:         "aws_db_instance",
+			Name:         "test",
+			ProviderName: "aws",
+			Values: map[string]interface{}{
+				"instance_class":    "db.t2.xlarge",
+				"allocated_storage": float64(42),
+				"engine":            "postgres",
+				"tc_usage": map[string]interface{}{
+					"reserved_instance_term":           "1_year",
+					"reserved_instance_payment_option": "all_upfront",
+				},
+			},
+		}
+		rss := map[string]terraform.Resource{}
+
+		expected := query.Component{
+			Name:            "Database instance (upfront, amortized)",
+			HourlyQuantity:  decimal.Zero,
+			MonthlyQuantity: decimal.NewFromInt(1).Div(decimal.NewFromInt(12)),
+			Details:         []string{"Single-AZ", "db.t2.xlarge", "reserved 1yr All Upfront"},
+			ProductFilter: &product.Filter{
+				Provider: util.StringPtr("aws"),
+				Service:  util.StringPtr("AmazonRDS"),
+				Family:   util.StringPtr("Database Instance"),
+				Location: util.StringPtr("eu-west-1"),
+				AttributeFilters: []*product.AttributeFilter{
+					{Key: "InstanceType", Value: util.StringPtr("db.t2.xlarge")},
+					{Key: "DeploymentOption", Value: util.StringPtr("Single-AZ")},
+					{Key: "DatabaseEngine", Value: util.StringPtr("PostgreSQL")},
+				},
+			},
+			PriceFilter: &price.Filter{
+				Unit: util.StringPtr("Quantity"),
+				AttributeFilters: []*price.AttributeFilter{
+					{Key: "TermType", Value: util.StringPtr("Reserved")},
+					{Key: "LeaseContractLength", Value: util.StringPtr("1yr")},
+					{Key: "PurchaseOption", Value: util.StringPtr("All Upfront")},
+				},
+			},
+		}
+
+		actual := p.ResourceComponents(rss, tfres)
+		require.Len(t, actual, 2)
+		assert.Equal(t, expected, actual[0])
+		assert.Equal(t, "Database storage", actual[1].Name)
+	})
+
 	t.Run("WithLicenseModelMultiAZ", func(t *testing.T) {
 		tfres := terraform.Resource{
 			Address:      "aws_db_instance.test",
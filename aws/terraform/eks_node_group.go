@@ -25,6 +25,8 @@ type eksNodeGroupValues struct {
 		Name    string `mapstructure:"name"`
 		Version string `mapstructure:"version"`
 	} `mapstructure:"launch_template"`
+
+	Usage commitmentUsage `mapstructure:"tc_usage"`
 }
 
 // decodeEKSNodeGroupValues decodes and returns instanceValues from a Terraform values map.
@@ -58,6 +60,7 @@ func (p *Provider) newEKSNodeGroup(rss map[string]terraform.Resource, vals eksNo
 		operatingSystem: "Linux",
 		capacityStatus:  "Used",
 		preInstalledSW:  "NA",
+		commitment:      p.commitment(vals.Usage),
 	}
 
 	var defaultEKSInstanceType = "t3.medium"
@@ -30,6 +30,9 @@ type ElastiCache struct {
 	replicationGroupID string
 
 	snapshotRetentionLimit decimal.Decimal
+
+	// commitment is the pricing commitment of the nodes, only the Reserved Nodes apply
+	commitment Commitment
 }
 
 type elastiCacheValues struct {
@@ -39,6 +42,8 @@ type elastiCacheValues struct {
 	ReplicationGroupID     string `mapstructure:"replication_group_id"`
 	NumCacheNodes          int64  `mapstructure:"num_cache_nodes"`
 	SnapshotRetentionLimit int64  `mapstructure:"snapshot_retention_limit"`
+
+	Usage commitmentUsage `mapstructure:"tc_usage"`
 }
 
 var cacheTypeMap = map[string]string{
@@ -76,6 +81,7 @@ func (p *Provider) newElastiCache(vals elastiCacheValues) *ElastiCache {
 		numCacheNodes:          decimal.NewFromInt(vals.NumCacheNodes),
 		replicationGroupID:     vals.ReplicationGroupID,
 		snapshotRetentionLimit: decimal.NewFromInt(vals.SnapshotRetentionLimit),
+		commitment:             p.commitment(vals.Usage),
 	}
 
 	if reg := region.NewFromZone(vals.AvailabilityZone); reg.Valid() {
@@ -92,7 +98,7 @@ func (inst *ElastiCache) Components() []query.Component {
 		return []query.Component{}
 	}
 
-	components := []query.Component{inst.elastiCacheInstanceComponent()}
+	components := inst.commitment.reservedComponents(inst.elastiCacheInstanceComponent(), false)
 
 	if inst.snapshotRetentionLimit.GreaterThan(decimal.NewFromInt(0)) && strings.HasPrefix(inst.cacheEngine, "Redis") {
 		components = append(components, inst.backupStorageComponent())
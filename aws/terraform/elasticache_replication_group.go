@@ -26,6 +26,9 @@ type ElastiCacheReplication struct {
 	snapshotRetentionLimit decimal.Decimal
 
 	globalReplicationGroupID string
+
+	// commitment is the pricing commitment of the nodes, only the Reserved Nodes apply
+	commitment Commitment
 }
 
 type elastiCacheReplicationValues struct {
@@ -42,6 +45,8 @@ type elastiCacheReplicationValues struct {
 	NumberCacheClusters      int64  `mapstructure:"num_cache_clusters"`
 	SnapshotRetentionLimit   int64  `mapstructure:"snapshot_retention_limit"`
 	GlobalReplicationGroupID string `mapstructure:"global_replication_group_id"`
+
+	Usage commitmentUsage `mapstructure:"tc_usage"`
 }
 
 func decodeElastiCacheReplicationValues(tfVals map[string]interface{}) (elastiCacheReplicationValues, error) {
@@ -90,6 +95,7 @@ func (p *Provider) newElastiCacheReplication(vals elastiCacheReplicationValues)
 		numCacheNodes:            numCacheNodes,
 		snapshotRetentionLimit:   decimal.NewFromInt(vals.SnapshotRetentionLimit),
 		globalReplicationGroupID: vals.GlobalReplicationGroupID,
+		commitment:               p.commitment(vals.Usage),
 	}
 
 	if len(vals.AvailabilityZones) > 0 {
@@ -108,7 +114,7 @@ func (inst *ElastiCacheReplication) Components() []query.Component {
 		return []query.Component{}
 	}
 
-	components := []query.Component{inst.elastiCacheReplicationInstanceComponent()}
+	components := inst.commitment.reservedComponents(inst.elastiCacheReplicationInstanceComponent(), false)
 
 	if inst.snapshotRetentionLimit.GreaterThan(decimal.NewFromInt(0)) && strings.HasPrefix(inst.cacheEngine, "Redis") {
 		components = append(components, inst.backupStorageComponent())
@@ -51,6 +51,9 @@ type Instance struct {
 	instanceCount decimal.Decimal
 
 	rootVolume *Volume
+
+	// commitment is the pricing commitment of the compute
+	commitment Commitment
 }
 
 // instanceValues represents the structure of Terraform values for aws_instance resource.
@@ -70,6 +73,8 @@ type instanceValues struct {
 		VolumeSize float64 `mapstructure:"volume_size"`
 		IOPS       float64 `mapstructure:"iops"`
 	} `mapstructure:"root_block_device"`
+
+	Usage commitmentUsage `mapstructure:"tc_usage"`
 }
 
 // decodeInstanceValues decodes and returns instanceValues from a Terraform values map.
@@ -106,6 +111,7 @@ func (p *Provider) newInstance(vals instanceValues) *Instance {
 		instanceCount:   decimal.NewFromInt(1),
 
 		instanceType: vals.InstanceType,
+		commitment:   p.commitment(vals.Usage),
 	}
 
 	if reg := region.NewFromZone(vals.AvailabilityZone); reg.Valid() {
@@ -145,7 +151,7 @@ func (p *Provider) newInstance(vals instanceValues) *Instance {
 
 // Components returns the price component queries that make up this Instance.
 func (inst *Instance) Components() []query.Component {
-	components := []query.Component{inst.computeComponent()}
+	components := inst.computeComponents()
 
 	if inst.rootVolume != nil {
 		for _, comp := range inst.rootVolume.Components() {
@@ -250,6 +256,19 @@ func (inst *Instance) ebsOptimizedCostComponent() query.Component {
 	}
 }
 
+// computeComponents returns the compute components priced with the commitment of the instance
+func (inst *Instance) computeComponents() []query.Component {
+	comp := inst.computeComponent()
+	if inst.commitment.isSavingsPlan() {
+		usageType := "BoxUsage:" + inst.instanceType
+		if inst.tenancy == "Dedicated" {
+			usageType = "DedicatedUsage:" + inst.instanceType
+		}
+		return []query.Component{inst.commitment.savingsPlanComponent(comp, usageType)}
+	}
+	return inst.commitment.reservedComponents(comp, true)
+}
+
 func (inst *Instance) computeComponent() query.Component {
 	return query.Component{
 		Name:           "Compute",
@@ -78,6 +78,125 @@ func TestInstance_Components(t *testing.T) {
 		assert.Equal(t, expected, actual)
 	})
 
+	t.Run("ReservedUsage", func(t *testing.T) {
+		tfres := terraform.Resource{
+			Address:      "aws_instance.test",
+			Type:         "aws_instance",
+			Name:         "test",
+			ProviderName: "aws",
+			Values: map[string]interface{}{
+				"instance_type": "m5.xlarge",
+				"tc_usage": map[string]interface{}{
+					"reserved_instance_type":           "convertible",
+					"reserved_instance_term":           "3_year",
+					"reserved_instance_payment_option": "partial_upfront",
+				},
+			},
+		}
+		productFilter := &product.Filter{
+			Provider: util.StringPtr("aws"),
+			Service:  util.StringPtr("AmazonEC2"),
+			Family:   util.StringPtr("Compute Instance"),
+			Location: util.StringPtr("eu-west-1"),
+			AttributeFilters: []*product.AttributeFilter{
+				{Key: "CapacityStatus", Value: util.StringPtr("Used")},
+				{Key: "InstanceType", Value: util.StringPtr("m5.xlarge")},
+				{Key: "Tenancy", Value: util.StringPtr("Shared")},
+				{Key: "OperatingSystem", Value: util.StringPtr("Linux")},
+				{Key: "PreInstalledSW", Value: util.StringPtr("NA")},
+			},
+		}
+		priceAttrs := []*price.AttributeFilter{
+			{Key: "TermType", Value: util.StringPtr("Reserved")},
+			{Key: "LeaseContractLength", Value: util.StringPtr("3yr")},
+			{Key: "PurchaseOption", Value: util.StringPtr("Partial Upfront")},
+			{Key: "OfferingClass", Value: util.StringPtr("convertible")},
+		}
+
+		expected := []query.Component{
+			{
+				Name:           "Compute",
+				HourlyQuantity: decimal.NewFromInt(1),
+				Details:        []string{"Linux", "reserved 3yr Partial Upfront", "m5.xlarge"},
+				ProductFilter:  productFilter,
+				PriceFilter: &price.Filter{
+					Unit:             util.StringPtr("Hrs"),
+					AttributeFilters: priceAttrs,
+				},
+			},
+			{
+				Name:            "Compute (upfront, amortized)",
+				HourlyQuantity:  decimal.Zero,
+				MonthlyQuantity: decimal.NewFromInt(1).Div(decimal.NewFromInt(36)),
+				Details:         []string{"Linux", "reserved 3yr Partial Upfront", "m5.xlarge"},
+				ProductFilter:   productFilter,
+				PriceFilter: &price.Filter{
+					Unit:             util.StringPtr("Quantity"),
+					AttributeFilters: priceAttrs,
+				},
+			},
+		}
+
+		actual := p.ResourceComponents(map[string]terraform.Resource{}, tfres)
+		require.Len(t, actual, 3)
+		assert.Equal(t, expected, actual[:2])
+	})
+
+	t.Run("SavingsPlanCommitment", func(t *testing.T) {
+		p, err := awstf.NewProvider("aws", "eu-west-1", awstf.WithCommitment(awstf.Commitment{
+			Type:          awstf.ComputeSavingsPlan,
+			Term:          1,
+			PaymentOption: awstf.AllUpfront,
+		}))
+		require.NoError(t, err)
+
+		tfres := terraform.Resource{
+			Address:      "aws_instance.test",
+			Type:         "aws_instance",
+			Name:         "test",
+			ProviderName: "aws",
+			Values: map[string]interface{}{
+				"instance_type": "m5.xlarge",
+			},
+		}
+
+		expected := query.Component{
+			Name:           "Compute",
+			HourlyQuantity: decimal.NewFromInt(1),
+			Details:        []string{"Linux", "compute savings plan 1yr All Upfront", "m5.xlarge"},
+			ProductFilter: &product.Filter{
+				Provider: util.StringPtr("aws"),
+				Service:  util.StringPtr("AWSComputeSavingsPlan"),
+				Family:   util.StringPtr("ComputeSavingsPlans"),
+				Location: util.StringPtr("eu-west-1"),
+				AttributeFilters: []*product.AttributeFilter{
+					{Key: "DiscountedServiceCode", Value: util.StringPtr("AmazonEC2")},
+					{Key: "DiscountedOperation", Value: util.StringPtr("RunInstances")},
+					{Key: "DiscountedUsageType", ValueRegex: util.StringPtr(`^([A-Z0-9]+-)?BoxUsage:m5\.xlarge$`)},
+					{Key: "LeaseContractLength", Value: util.StringPtr("1yr")},
+					{Key: "PurchaseOption", Value: util.StringPtr("All Upfront")},
+				},
+			},
+			PriceFilter: &price.Filter{
+				Unit: util.StringPtr("Hrs"),
+				AttributeFilters: []*price.AttributeFilter{
+					{Key: "TermType", Value: util.StringPtr("SavingsPlan")},
+				},
+			},
+		}
+
+		actual := p.ResourceComponents(map[string]terraform.Resource{}, tfres)
+		require.Len(t, actual, 2)
+		assert.Equal(t, expected, actual[0])
+
+		// The usage takes precedence over the commitment of the provider
+		tfres.Values["tc_usage"] = map[string]interface{}{"reserved_instance_term": "1_year"}
+		actual = p.ResourceComponents(map[string]terraform.Resource{}, tfres)
+		require.Len(t, actual, 2)
+		assert.Equal(t, []string{"Linux", "reserved 1yr No Upfront", "m5.xlarge"}, actual[0].Details)
+		assert.Equal(t, "Reserved", *actual[0].PriceFilter.AttributeFilters[0].Value)
+	})
+
 	t.Run("WithAllValues", func(t *testing.T) {
 		tfres := terraform.Resource{
 			Address:      "aws_instance.test",
@@ -13,14 +13,31 @@ import (
 type Provider struct {
 	key    string
 	region region.Code
+
+	defaultCommitment Commitment
+}
+
+// Option sets an option of the Provider
+type Option func(p *Provider)
+
+// WithCommitment prices the instances, databases and cache nodes with the commitment
+// unless their usage sets another one, they are priced on-demand by default.
+func WithCommitment(c Commitment) Option {
+	return func(p *Provider) {
+		p.defaultCommitment = c
+	}
 }
 
 // NewProvider returns a new Provider with the provided default region and a query key.
-func NewProvider(key string, regionCode region.Code) (*Provider, error) {
+func NewProvider(key string, regionCode region.Code, opts ...Option) (*Provider, error) {
 	if !regionCode.Valid() {
 		return nil, fmt.Errorf("invalid AWS region: %q", regionCode)
 	}
-	return &Provider{key: key, region: regionCode}, nil
+	p := &Provider{key: key, region: regionCode}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
 }
 
 // Name returns the Provider's common name.
@@ -0,0 +1,102 @@
+package terraform
+
+import (
+	"github.com/cycloidio/terracost/usage"
+)
+
+// reservedSchema is the usage of the resources that can be priced with Reserved Instances
+var reservedSchema = usage.Schema{
+	{Key: "reserved_instance_type", Type: usage.String, Description: "Offering class of the Reserved Instances: standard or convertible, only for EC2"},
+	{Key: "reserved_instance_term", Type: usage.String, Description: "Term of the Reserved Instances: 1_year or 3_year"},
+	{Key: "reserved_instance_payment_option", Type: usage.String, Description: "Payment of the Reserved Instances: no_upfront, partial_upfront or all_upfront"},
+}
+
+// commitmentSchema is the usage of the EC2 instances, that can also be priced with Savings Plans
+var commitmentSchema = append(usage.Schema{
+	{Key: "savings_plan_type", Type: usage.String, Description: "Type of the Savings Plan: compute or ec2_instance"},
+	{Key: "savings_plan_term", Type: usage.String, Description: "Term of the Savings Plan: 1_year or 3_year"},
+	{Key: "savings_plan_payment_option", Type: usage.String, Description: "Payment of the Savings Plan: no_upfront, partial_upfront or all_upfront"},
+}, reservedSchema...)
+
+// eksNodeGroupSchema is the usage of the EKS node groups, which are
+// EC2 instances that can also be priced with commitments
+var eksNodeGroupSchema = append(usage.Schema{
+	{Key: "instances", Type: usage.Number, Unit: "instances", Description: "Number of instances of the node group"},
+	{Key: "operating_system", Type: usage.String, Description: "Operating system of the instances: linux or windows"},
+	{Key: "monthly_cpu_credit_hrs", Type: usage.Number, Unit: "hours", Description: "Monthly vCPU hours of CPU credits used by the burstable instances"},
+	{Key: "vcpu_count", Type: usage.Number, Unit: "vCPU", Description: "Number of vCPUs of each instance"},
+}, commitmentSchema...)
+
+// usageSchemas are the usage attributes accepted by each resource type
+var usageSchemas = map[string]usage.Schema{
+	"aws_autoscaling_group":             commitmentSchema,
+	"aws_db_instance":                   reservedSchema,
+	"aws_eks_node_group":                eksNodeGroupSchema,
+	"aws_elasticache_cluster":           reservedSchema,
+	"aws_elasticache_replication_group": reservedSchema,
+	"aws_instance":                      commitmentSchema,
+	"aws_cloudwatch_log_group": {
+		{Key: "storage_gb", Type: usage.Number, Unit: "GB", Description: "Total size of the stored logs"},
+		{Key: "monthly_data_ingested_gb", Type: usage.Number, Unit: "GB", Description: "Monthly size of the ingested logs"},
+		{Key: "monthly_data_scanned_insights_gb", Type: usage.Number, Unit: "GB", Description: "Monthly size of the logs scanned by Logs Insights queries"},
+	},
+	"aws_efs_file_system": {
+		{Key: "storage_gb", Type: usage.Number, Unit: "GB", Description: "Total size of the Standard storage"},
+		{Key: "infrequent_access_storage_gb", Type: usage.Number, Unit: "GB", Description: "Total size of the Infrequent Access storage"},
+		{Key: "monthly_infrequent_access_read_gb", Type: usage.Number, Unit: "GB", Description: "Monthly size of the Infrequent Access reads"},
+		{Key: "monthly_infrequent_access_write_gb", Type: usage.Number, Unit: "GB", Description: "Monthly size of the Infrequent Access writes"},
+	},
+	"aws_fsx_lustre_file_system": {
+		{Key: "backup_storage_gb", Type: usage.Number, Unit: "GB", Description: "Total size of the backups"},
+	},
+	"aws_fsx_ontap_file_system": {
+		{Key: "backup_storage_gb", Type: usage.Number, Unit: "GB", Description: "Total size of the backups"},
+	},
+	"aws_fsx_openzfs_file_system": {
+		{Key: "backup_storage_gb", Type: usage.Number, Unit: "GB", Description: "Total size of the backups"},
+	},
+	"aws_fsx_windows_file_system": {
+		{Key: "backup_storage_gb", Type: usage.Number, Unit: "GB", Description: "Total size of the backups"},
+	},
+	"aws_nat_gateway": {
+		{Key: "monthly_data_processed_gb", Type: usage.Number, Unit: "GB", Description: "Monthly size of the data processed"},
+	},
+	"aws_rds_cluster": {
+		{Key: "capacity_units_per_hr", Type: usage.Number, Unit: "ACU", Description: "Aurora Capacity Units used per hour by Serverless"},
+		{Key: "storage_gb", Type: usage.Number, Unit: "GB", Description: "Total size of the storage"},
+		{Key: "write_requests_per_sec", Type: usage.Number, Unit: "requests/s", Description: "Write I/O requests per second"},
+		{Key: "read_requests_per_sec", Type: usage.Number, Unit: "requests/s", Description: "Read I/O requests per second"},
+		{Key: "backup_snapshot_size_gb", Type: usage.Number, Unit: "GB", Description: "Size of the backup snapshots"},
+		{Key: "average_statements_per_hr", Type: usage.Number, Unit: "statements/h", Description: "Average of statements per hour for Backtrack"},
+		{Key: "change_records_per_statement", Type: usage.Number, Unit: "records", Description: "Change records per statement for Backtrack"},
+		{Key: "backtrack_window_hrs", Type: usage.Number, Unit: "hours", Description: "Backtrack window"},
+		{Key: "snapshot_export_size_gb", Type: usage.Number, Unit: "GB", Description: "Size of the exported snapshots"},
+	},
+	"aws_rds_cluster_instance": {
+		{Key: "monthly_additional_performance_insights_requests", Type: usage.Number, Unit: "requests", Description: "Monthly Performance Insights API requests over the free tier"},
+		{Key: "capacity_units_per_hr", Type: usage.Number, Unit: "ACU", Description: "Aurora Capacity Units used per hour by Serverless"},
+	},
+	"aws_s3_bucket": {
+		{Key: "storage_gb", Type: usage.Number, Unit: "GB", Description: "Total size of the Standard storage"},
+		{Key: "monthly_outbound_data_gb", Type: usage.Number, Unit: "GB", Description: "Monthly size of the data transferred out to the internet"},
+	},
+	"aws_s3_bucket_analytics_configuration": {
+		{Key: "monthly_monitored_objects", Type: usage.Number, Unit: "objects", Description: "Monthly objects monitored by the Storage Class Analysis"},
+	},
+	"aws_s3_bucket_inventory": {
+		{Key: "monthly_listed_objects", Type: usage.Number, Unit: "objects", Description: "Monthly objects listed by the inventory"},
+	},
+	"aws_secretsmanager_secret": {
+		{Key: "monthly_requests", Type: usage.Number, Unit: "requests", Description: "Monthly API requests"},
+	},
+	"aws_sqs_queue": {
+		{Key: "monthly_requests", Type: usage.Number, Unit: "requests", Description: "Monthly API requests"},
+		{Key: "request_size_kb", Type: usage.Number, Unit: "KB", Description: "Size of each request"},
+	},
+}
+
+func init() {
+	for rt, s := range usageSchemas {
+		usage.RegisterSchema(rt, s)
+	}
+}
@@ -2,6 +2,7 @@ package aws
 
 import (
 	"fmt"
+	"log/slog"
 
 	"github.com/cycloidio/terracost/aws/region"
 	awstf "github.com/cycloidio/terracost/aws/terraform"
@@ -18,9 +19,12 @@ const (
 )
 
 // TerraformProviderInitializer is a terraform.ProviderInitializer that initializes the default AWS provider.
-var TerraformProviderInitializer = terraform.ProviderInitializer{
-	MatchNames: []string{ProviderName, RegistryName},
-	Provider: func(values map[string]interface{}) (terraform.Provider, error) {
+var TerraformProviderInitializer = NewTerraformProviderInitializer()
+
+// NewTerraformProviderInitializer returns a terraform.ProviderInitializer that initializes the AWS provider
+// with the opts, like the awstf.WithCommitment used to price the resources.
+func NewTerraformProviderInitializer(opts ...awstf.Option) terraform.ProviderInitializer {
+	newProvider := func(logger *slog.Logger, values map[string]interface{}) (terraform.Provider, error) {
 		var regCode region.Code
 
 		r, ok := values["region"]
@@ -29,20 +33,28 @@ var TerraformProviderInitializer = terraform.ProviderInitializer{
 			// and it's not tracked on the Plan or HCL so we'll assume the
 			// region to be the DefaultRegion
 			regCode = DefaultRegion
-			log.Logger.Info(fmt.Sprintf("AWS terraform provider region not set, defaulting to %s", DefaultRegion))
-			return awstf.NewProvider(ProviderName, regCode)
+			logger.Info(fmt.Sprintf("AWS terraform provider region not set, defaulting to %s", DefaultRegion))
+			return awstf.NewProvider(ProviderName, regCode, opts...)
 		}
 
 		switch value := r.(type) {
 		case string:
-			if regCode == "" {
-				log.Logger.Info(fmt.Sprintf("AWS terraform provider region not set, defaulting to %s", DefaultRegion))
-				return awstf.NewProvider(ProviderName, DefaultRegion)
+			if value == "" {
+				logger.Info(fmt.Sprintf("AWS terraform provider region not set, defaulting to %s", DefaultRegion))
+				return awstf.NewProvider(ProviderName, DefaultRegion, opts...)
 			}
 
-			return awstf.NewProvider(ProviderName, region.Code(value))
+			return awstf.NewProvider(ProviderName, region.Code(value), opts...)
 		default:
 			return nil, fmt.Errorf("invalid region type (expected string): %T", r)
 		}
-	},
+	}
+
+	return terraform.ProviderInitializer{
+		MatchNames: []string{ProviderName, RegistryName},
+		Provider: func(values map[string]interface{}) (terraform.Provider, error) {
+			return newProvider(log.Logger, values)
+		},
+		ProviderWithLogger: newProvider,
+	}
 }
@@ -171,3 +171,8 @@ func (ing *Ingester) buildPricesURL() string {
 func (ing *Ingester) Err() error {
 	return ing.err
 }
+
+// Identity returns the provider, service and region of the ingestion.
+func (ing *Ingester) Identity() (string, string, string) {
+	return ProviderName, ing.service, ing.region
+}
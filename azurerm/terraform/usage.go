@@ -0,0 +1,56 @@
+package terraform
+
+import (
+	"github.com/cycloidio/terracost/usage"
+)
+
+// osDiskUsageSchema is the usage of the OS disk of the virtual machines
+var osDiskUsageSchema = usage.Attribute{
+	Key:         "os_disk",
+	Type:        usage.Object,
+	Description: "Usage of the OS disk",
+	Attributes: usage.Schema{
+		{Key: "monthly_disk_operations", Type: usage.Number, Unit: "operations", Description: "Monthly disk operations (writes, reads and deletes)"},
+	},
+}
+
+// usageSchemas are the usage attributes accepted by each resource type
+var usageSchemas = map[string]usage.Schema{
+	"azurerm_bastion_host": {
+		{Key: "monthly_outbound_data_gb", Type: usage.Number, Unit: "GB", Description: "Monthly size of the outbound data"},
+	},
+	"azurerm_linux_virtual_machine": {osDiskUsageSchema},
+	"azurerm_managed_disk": {
+		{Key: "monthly_disk_operations", Type: usage.Number, Unit: "operations", Description: "Monthly disk operations (writes, reads and deletes)"},
+	},
+	"azurerm_nat_gateway": {
+		{Key: "monthly_data_processed_gb", Type: usage.Number, Unit: "GB", Description: "Monthly size of the data processed"},
+	},
+	"azurerm_postgresql_flexible_server": {
+		{Key: "additional_backup_storage_gb", Type: usage.Number, Unit: "GB", Description: "Size of the backup storage over the free one"},
+		{Key: "monthly_hours", Type: usage.Number, Unit: "hours", Description: "Monthly hours the server is running"},
+	},
+	"azurerm_private_endpoint": {
+		{Key: "monthly_hours", Type: usage.Number, Unit: "hours", Description: "Monthly hours the endpoint is provisioned"},
+	},
+	"azurerm_public_ip": {
+		{Key: "monthly_hours", Type: usage.Number, Unit: "hours", Description: "Monthly hours the IP is provisioned"},
+	},
+	"azurerm_storage_share": {
+		{Key: "monthly_write_transactions", Type: usage.Number, Unit: "transactions", Description: "Monthly write transactions"},
+		{Key: "monthly_list_transactions", Type: usage.Number, Unit: "transactions", Description: "Monthly list transactions"},
+		{Key: "monthly_read_transactions", Type: usage.Number, Unit: "transactions", Description: "Monthly read transactions"},
+		{Key: "monthly_other_transactions", Type: usage.Number, Unit: "transactions", Description: "Monthly other transactions"},
+	},
+	"azurerm_virtual_machine": {osDiskUsageSchema},
+	"azurerm_virtual_network_gateway": {
+		{Key: "monthly_data_transfer_gb", Type: usage.Number, Unit: "GB", Description: "Monthly size of the data transferred"},
+	},
+	"azurerm_windows_virtual_machine": {osDiskUsageSchema},
+}
+
+func init() {
+	for rt, s := range usageSchemas {
+		usage.RegisterSchema(rt, s)
+	}
+}
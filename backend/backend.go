@@ -1,6 +1,9 @@
 package backend
 
 import (
+	"context"
+
+	"github.com/cycloidio/terracost/ingestion"
 	"github.com/cycloidio/terracost/price"
 	"github.com/cycloidio/terracost/product"
 )
@@ -13,3 +16,16 @@ type Backend interface {
 	Products() product.Repository
 	Prices() price.Repository
 }
+
+// IngestionRecorder is implemented by the Backend that records the ingestions of the pricing.
+type IngestionRecorder interface {
+	// Ingestions returns the ingestion.Repository in which the ingestions are recorded, nil if they are not
+	Ingestions() ingestion.Repository
+}
+
+// Transactioner is implemented by the Backend that can group the changes on a transaction.
+type Transactioner interface {
+	// Transaction calls fn with a Backend whose changes are only seen by the rest once fn returns
+	// without error, they are all discarded otherwise.
+	Transaction(ctx context.Context, fn func(be Backend) error) error
+}
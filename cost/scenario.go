@@ -0,0 +1,103 @@
+package cost
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Scenario is the estimation of the resources with a named usage, with one Plan for each
+// estimated module (only one for the Terraform plans).
+type Scenario struct {
+	Name  string
+	Plans []*Plan
+}
+
+// PlannedCost returns the total planned cost of all the Plans of the Scenario.
+func (s Scenario) PlannedCost() (Cost, error) {
+	total := Zero
+	for _, p := range s.Plans {
+		c, err := p.PlannedCost()
+		if err != nil {
+			return Zero, err
+		}
+		total, err = total.Add(c)
+		if err != nil {
+			return Zero, err
+		}
+	}
+	return total, nil
+}
+
+// ComponentRange is the range of the planned cost of a usage based component between the scenarios.
+type ComponentRange struct {
+	// Plan is the Name of the Plan the resource is on
+	Plan    string
+	Address string
+	Label   string
+
+	Min, Max Cost
+
+	// Costs are the costs of the component by scenario name
+	Costs map[string]Cost
+}
+
+// ComponentRanges returns the ranges of the planned cost of the usage based components between the
+// scenarios, sorted by Plan, Address and Label. The components with errors are ignored.
+func ComponentRanges(scenarios []Scenario) ([]ComponentRange, error) {
+	ranges := make(map[string]*ComponentRange)
+	for _, s := range scenarios {
+		for _, p := range s.Plans {
+			if p.Planned == nil {
+				continue
+			}
+			for addr, res := range p.Planned.Resources {
+				for label, comp := range res.Components {
+					if !comp.Usage || comp.Error != nil {
+						continue
+					}
+
+					c := comp.Cost()
+					k := fmt.Sprintf("%s\x00%s\x00%s", p.Name, addr, label)
+					cr, ok := ranges[k]
+					if !ok {
+						ranges[k] = &ComponentRange{
+							Plan:    p.Name,
+							Address: addr,
+							Label:   label,
+							Min:     c,
+							Max:     c,
+							Costs:   map[string]Cost{s.Name: c},
+						}
+						continue
+					}
+
+					if c.Currency != cr.Min.Currency && !c.IsZero() && !cr.Min.IsZero() {
+						return nil, fmt.Errorf("currency mismatch on %s %q: expected %s, got %s", addr, label, cr.Min.Currency, c.Currency)
+					}
+					if c.LessThan(cr.Min.Decimal) {
+						cr.Min = c
+					}
+					if c.GreaterThan(cr.Max.Decimal) {
+						cr.Max = c
+					}
+					cr.Costs[s.Name] = c
+				}
+			}
+		}
+	}
+
+	crs := make([]ComponentRange, 0, len(ranges))
+	for _, cr := range ranges {
+		crs = append(crs, *cr)
+	}
+	sort.Slice(crs, func(i, j int) bool {
+		if crs[i].Plan != crs[j].Plan {
+			return crs[i].Plan < crs[j].Plan
+		}
+		if crs[i].Address != crs[j].Address {
+			return crs[i].Address < crs[j].Address
+		}
+		return crs[i].Label < crs[j].Label
+	})
+	return crs, nil
+}
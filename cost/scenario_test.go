@@ -0,0 +1,68 @@
+package cost_test
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cycloidio/terracost/cost"
+)
+
+func TestComponentRanges(t *testing.T) {
+	newScenario := func(name string, storage int64) cost.Scenario {
+		return cost.Scenario{
+			Name: name,
+			Plans: []*cost.Plan{cost.NewPlan("stack", nil, &cost.State{
+				Resources: map[string]cost.Resource{
+					"aws_s3_bucket.test": {
+						Components: map[string]cost.Component{
+							"Storage": {
+								Quantity: decimal.NewFromInt(storage),
+								Rate:     cost.NewMonthly(decimal.NewFromFloat(0.1), "USD"),
+								Usage:    true,
+							},
+							"Requests": {
+								Usage: true,
+								Error: cost.ErrPriceNotFound,
+							},
+						},
+					},
+					"aws_instance.test": {
+						Components: map[string]cost.Component{
+							"Compute": {
+								Quantity: decimal.NewFromInt(1),
+								Rate:     cost.NewHourly(decimal.NewFromFloat(0.1), "USD"),
+							},
+						},
+					},
+				},
+			})},
+		}
+	}
+	scenarios := []cost.Scenario{
+		newScenario("expected", 100),
+		newScenario("low", 10),
+		newScenario("high", 1000),
+	}
+
+	ranges, err := cost.ComponentRanges(scenarios)
+	require.NoError(t, err)
+	require.Len(t, ranges, 1)
+
+	cr := ranges[0]
+	assert.Equal(t, "stack", cr.Plan)
+	assert.Equal(t, "aws_s3_bucket.test", cr.Address)
+	assert.Equal(t, "Storage", cr.Label)
+	assert.Equal(t, "1", cr.Min.String())
+	assert.Equal(t, "100", cr.Max.String())
+	assert.Equal(t, "10", cr.Costs["expected"].String())
+
+	low, err := scenarios[1].PlannedCost()
+	require.NoError(t, err)
+	high, err := scenarios[2].PlannedCost()
+	require.NoError(t, err)
+	assert.Equal(t, "74", low.String())
+	assert.Equal(t, "173", high.String())
+}
@@ -3,8 +3,11 @@ package cost
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/cycloidio/terracost/backend"
+	"github.com/cycloidio/terracost/event"
+	"github.com/cycloidio/terracost/price"
 	"github.com/cycloidio/terracost/query"
 	"github.com/cycloidio/terracost/terraform"
 )
@@ -22,8 +25,26 @@ var (
 	ErrPriceNotFound   = fmt.Errorf("price not found")
 )
 
+// StateOption sets an option of NewState
+type StateOption func(*stateOptions)
+
+type stateOptions struct {
+	asOf *time.Time
+}
+
+// AsOf prices the components with the prices that were effective at the time t
+// instead of the current ones, unless their price.Filter has its own AsOf
+func AsOf(t time.Time) StateOption {
+	return func(o *stateOptions) { o.asOf = &t }
+}
+
 // NewState returns a new State from a query.Resource slice by using the Backend to fetch the pricing data.
-func NewState(ctx context.Context, backend backend.Backend, queries []query.Resource) (*State, error) {
+func NewState(ctx context.Context, backend backend.Backend, queries []query.Resource, opts ...StateOption) (*State, error) {
+	o := &stateOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	state := &State{Resources: make(map[string]Resource)}
 
 	if len(queries) == 0 {
@@ -34,46 +55,61 @@ func NewState(ctx context.Context, backend backend.Backend, queries []query.Reso
 		state.ensureResource(res.Address, res.Provider, res.Type, len(res.Components) == 0)
 
 		for _, comp := range res.Components {
-			prods, err := backend.Products().Filter(ctx, comp.ProductFilter)
-			if err != nil {
-				state.addComponent(res.Address, comp.Name, Component{Error: err})
-				continue
-			}
-			if len(prods) < 1 {
-				state.addComponent(res.Address, comp.Name, Component{Error: ErrProductNotFound})
-				continue
-			}
-			prices, err := backend.Prices().Filter(ctx, prods[0].ID, comp.PriceFilter)
+			component, err := newComponent(ctx, backend, comp, o)
 			if err != nil {
+				event.Emit(ctx, event.Event{Type: event.ComponentFailed, Address: res.Address, Component: comp.Name, Err: err})
 				state.addComponent(res.Address, comp.Name, Component{Error: err})
 				continue
 			}
-			if len(prices) < 1 {
-				state.addComponent(res.Address, comp.Name, Component{Error: ErrPriceNotFound})
-				continue
-			}
+			event.Emit(ctx, event.Event{Type: event.ComponentPriced, Address: res.Address, Component: comp.Name})
+			state.addComponent(res.Address, comp.Name, component)
+		}
+	}
 
-			quantity := comp.MonthlyQuantity
-			rate := NewMonthly(prices[0].Value, prices[0].Currency)
+	return state, nil
+}
 
-			if quantity.IsZero() {
-				quantity = comp.HourlyQuantity
-				rate = NewHourly(prices[0].Value, prices[0].Currency)
-			}
+// newComponent returns the Component of the comp with the first price of its product found on the backend
+func newComponent(ctx context.Context, backend backend.Backend, comp query.Component, o *stateOptions) (Component, error) {
+	prods, err := backend.Products().Filter(ctx, comp.ProductFilter)
+	if err != nil {
+		return Component{}, err
+	}
+	if len(prods) < 1 {
+		return Component{}, ErrProductNotFound
+	}
+	pf := comp.PriceFilter
+	if o.asOf != nil && (pf == nil || pf.AsOf == nil) {
+		f := price.Filter{}
+		if pf != nil {
+			f = *pf
+		}
+		f.AsOf = o.asOf
+		pf = &f
+	}
+	prices, err := backend.Prices().Filter(ctx, prods[0].ID, pf)
+	if err != nil {
+		return Component{}, err
+	}
+	if len(prices) < 1 {
+		return Component{}, ErrPriceNotFound
+	}
 
-			component := Component{
-				Quantity: quantity,
-				Unit:     comp.Unit,
-				Rate:     rate,
-				Details:  comp.Details,
-				Usage:    comp.Usage,
-			}
+	quantity := comp.MonthlyQuantity
+	rate := NewMonthly(prices[0].Value, prices[0].Currency)
 
-			state.addComponent(res.Address, comp.Name, component)
-		}
+	if quantity.IsZero() {
+		quantity = comp.HourlyQuantity
+		rate = NewHourly(prices[0].Value, prices[0].Currency)
 	}
 
-	return state, nil
+	return Component{
+		Quantity: quantity,
+		Unit:     comp.Unit,
+		Rate:     rate,
+		Details:  comp.Details,
+		Usage:    comp.Usage,
+	}, nil
 }
 
 // Cost returns the sum of the costs of every Resource included in this State.
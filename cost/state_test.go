@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/shopspring/decimal"
@@ -118,6 +119,28 @@ func TestNewState(t *testing.T) {
 		require.NoError(t, err)
 		assert.Error(t, state.Resources["aws_instance.test1"].Components["Compute"].Error)
 	})
+
+	t.Run("AsOf", func(t *testing.T) {
+		ctx := context.Background()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		productRepo := mock.NewProductRepository(ctrl)
+		priceRepo := mock.NewPriceRepository(ctrl)
+		backend := mock.NewBackend(ctrl)
+		backend.EXPECT().Products().AnyTimes().Return(productRepo)
+		backend.EXPECT().Prices().AnyTimes().Return(priceRepo)
+
+		asOf := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		prod1 := &product.Product{ID: product.ID(1)}
+		productRepo.EXPECT().Filter(ctx, queries[0].Components[0].ProductFilter).Return([]*product.Product{prod1}, nil)
+		prc1 := &price.Price{Value: decimal.NewFromFloat(1), Unit: "Hrs", Currency: "USD"}
+		priceRepo.EXPECT().Filter(ctx, prod1.ID, &price.Filter{AsOf: &asOf}).Return([]*price.Price{prc1}, nil)
+
+		state, err := cost.NewState(ctx, backend, queries, cost.AsOf(asOf))
+		require.NoError(t, err)
+		assert.NoError(t, state.Resources["aws_instance.test1"].Components["Compute"].Error)
+	})
 }
 
 func TestState_Cost(t *testing.T) {
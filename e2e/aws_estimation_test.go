@@ -413,7 +413,8 @@ func TestAWSEstimation(t *testing.T) {
 			}
 		})
 		t.Run("TerragruntContextCancelled", func(t *testing.T) {
-			ctx, _ = context.WithTimeoutCause(ctx, time.Millisecond, fmt.Errorf("potato"))
+			ctx, cancel := context.WithTimeoutCause(ctx, time.Millisecond, fmt.Errorf("potato"))
+			defer cancel()
 			_, err := costestimation.EstimateHCL(ctx, backend, nil, "../testdata/aws/terragrunt/", "../testdata/aws/terragrunt/non-prod/us-east-1/qa/webserver-cluster/", noForceTerragrunt, noParallelismTerragrunt, usage.Default, noDebug)
 			require.EqualError(t, err, "potato")
 
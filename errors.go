@@ -0,0 +1,53 @@
+package terracost
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnpricedComponents is returned on strict mode when some components could not be priced
+var ErrUnpricedComponents = errors.New("components could not be priced")
+
+// ModuleError is the error of a module that failed to be estimated
+type ModuleError struct {
+	// Path is the directory of the module on the stack
+	Path string
+	Err  error
+}
+
+func (e *ModuleError) Error() string {
+	return fmt.Sprintf("module %q: %s", e.Path, e.Err)
+}
+
+func (e *ModuleError) Unwrap() error { return e.Err }
+
+// ModuleErrors are the errors of the modules that failed to be estimated when
+// WithBestEffort is set, the rest of the modules are estimated
+type ModuleErrors []*ModuleError
+
+func (es ModuleErrors) Error() string {
+	msgs := make([]string, 0, len(es))
+	for _, e := range es {
+		msgs = append(msgs, e.Error())
+	}
+	return fmt.Sprintf("failed to estimate %d module(s): %s", len(es), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of each module so they can be checked with errors.Is and errors.As
+func (es ModuleErrors) Unwrap() []error {
+	errs := make([]error, 0, len(es))
+	for _, e := range es {
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+// Paths returns the paths of the modules that failed
+func (es ModuleErrors) Paths() []string {
+	paths := make([]string, 0, len(es))
+	for _, e := range es {
+		paths = append(paths, e.Path)
+	}
+	return paths
+}
@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/sirupsen/logrus"
@@ -18,8 +19,11 @@ import (
 
 	"github.com/cycloidio/terracost/backend"
 	"github.com/cycloidio/terracost/cost"
-	"github.com/cycloidio/terracost/log"
+	"github.com/cycloidio/terracost/event"
+	"github.com/cycloidio/terracost/price"
+	"github.com/cycloidio/terracost/query"
 	"github.com/cycloidio/terracost/terraform"
+	"github.com/cycloidio/terracost/terragrunt"
 	"github.com/cycloidio/terracost/usage"
 	"github.com/cycloidio/terracost/util"
 	"github.com/gruntwork-io/terragrunt/cli"
@@ -31,47 +35,192 @@ import (
 
 // EstimateTerraformPlan is a helper function that reads a Terraform plan using the provided io.Reader,
 // generates the prior and planned cost.State, and then creates a cost.Plan from them that is returned.
-// It uses the Backend to retrieve the pricing data.
+// It uses the Backend to retrieve the pricing data. It's like EstimatePlan with WithUsage and WithProviders.
 func EstimateTerraformPlan(ctx context.Context, be backend.Backend, plan io.Reader, u usage.Usage, providerInitializers ...terraform.ProviderInitializer) (*cost.Plan, error) {
-	if len(providerInitializers) == 0 {
-		providerInitializers = getDefaultProviders()
-	}
+	return EstimatePlan(ctx, be, plan, WithUsage(u), WithProviders(providerInitializers...))
+}
 
-	tfplan := terraform.NewPlan(providerInitializers...)
-	if err := tfplan.Read(plan); err != nil {
-		return nil, err
-	}
-	tfplan.SetUsage(u)
+// EstimateTerraformPlanScenarios is like EstimateTerraformPlan but it estimates the plan with each one of the
+// usage scenarios, returning a cost.Scenario for each one of them in the same order. The plan is only read once.
+func EstimateTerraformPlanScenarios(ctx context.Context, be backend.Backend, plan io.Reader, scenarios []usage.Scenario, providerInitializers ...terraform.ProviderInitializer) ([]cost.Scenario, error) {
+	return EstimatePlanScenarios(ctx, be, plan, scenarios, WithProviders(providerInitializers...))
+}
 
-	priorQueries, err := tfplan.ExtractPriorQueries()
+// EstimatePlan reads a Terraform plan from the provided io.Reader and returns a cost.Plan with
+// the prior and planned cost.State of it, using the Backend to retrieve the pricing data
+func EstimatePlan(ctx context.Context, be backend.Backend, plan io.Reader, opts ...EstimateOption) (*cost.Plan, error) {
+	o := newEstimateOptions(opts...)
+	costs, err := estimatePlan(ctx, be, plan, []usage.Scenario{{Usage: o.Usage}}, o)
 	if err != nil {
 		return nil, err
 	}
+	return costs[0].Plans[0], nil
+}
+
+// EstimatePlanScenarios is like EstimatePlan but it estimates the plan with each one of the usage
+// scenarios, returning a cost.Scenario for each one of them in the same order
+func EstimatePlanScenarios(ctx context.Context, be backend.Backend, plan io.Reader, scenarios []usage.Scenario, opts ...EstimateOption) ([]cost.Scenario, error) {
+	return estimatePlan(ctx, be, plan, scenarios, newEstimateOptions(opts...))
+}
 
-	// If it's the first time we run the plan, then we might not have
-	// prior queries so we ignore it and move forward
-	prior, err := cost.NewState(ctx, be, priorQueries)
-	if err != nil && err != terraform.ErrNoQueries {
+// EstimateState reads a Terraform state on the JSON format of 'terraform show -json' from the provided
+// io.Reader and returns a cost.Plan with its cost as the planned cost.State. As the state has no
+// configuration, the providers are configured with WithProviderConfig.
+func EstimateState(ctx context.Context, be backend.Backend, state io.Reader, opts ...EstimateOption) (*cost.Plan, error) {
+	o := newEstimateOptions(opts...)
+	tfplan, err := terraform.NewPlanFromState(state, o.ProviderConfigs, o.ProviderInitializers...)
+	if err != nil {
 		return nil, err
 	}
-
-	plannedQueries, err := tfplan.ExtractPlannedQueries()
+	costs, err := estimateTerraformPlan(ctx, be, tfplan, []usage.Scenario{{Usage: o.Usage}}, o)
 	if err != nil {
 		return nil, err
 	}
+	return costs[0].Plans[0], nil
+}
 
-	planned, err := cost.NewState(ctx, be, plannedQueries)
-	if err != nil {
+// estimatePlan reads the plan and estimates it with each one of the scenarios
+func estimatePlan(ctx context.Context, be backend.Backend, plan io.Reader, scenarios []usage.Scenario, o *EstimateOptions) ([]cost.Scenario, error) {
+	tfplan := terraform.NewPlan(o.ProviderInitializers...)
+	if err := tfplan.Read(plan); err != nil {
 		return nil, err
 	}
+	return estimateTerraformPlan(ctx, be, tfplan, scenarios, o)
+}
+
+// estimateTerraformPlan estimates the tfplan with each one of the scenarios
+func estimateTerraformPlan(ctx context.Context, be backend.Backend, tfplan *terraform.Plan, scenarios []usage.Scenario, o *EstimateOptions) ([]cost.Scenario, error) {
+	ctx = o.context(ctx)
+	tfplan.SetLogger(o.Logger)
 
 	modules := make([]string, 0, 0)
 	for k := range tfplan.Configuration.RootModule.ModuleCalls {
 		modules = append(modules, k)
 	}
 	sort.Strings(modules)
+	name := strings.Join(modules, ", ")
+
+	costs := newCostScenarios(scenarios)
+	for i, s := range scenarios {
+		tfplan.SetUsage(s.Usage)
+
+		priorQueries, err := tfplan.ExtractPriorQueries()
+		if err != nil {
+			return nil, err
+		}
+
+		// If it's the first time we run the plan, then we might not have
+		// prior queries so we ignore it and move forward
+		prior, err := o.newState(ctx, be, name, priorQueries)
+		if err != nil && err != terraform.ErrNoQueries {
+			return nil, err
+		}
+
+		plannedQueries, err := tfplan.ExtractPlannedQueries()
+		if err != nil {
+			return nil, err
+		}
+
+		planned, err := o.newState(ctx, be, name, plannedQueries)
+		if err != nil {
+			return nil, err
+		}
+
+		costs[i].Plans = append(costs[i].Plans, cost.NewPlan(name, prior, planned))
+	}
+
+	return costs, nil
+}
+
+// newState returns the cost.State of the queries of the module with the prices of the Currency,
+// if Strict it fails when any of the components could not be priced
+func (o *EstimateOptions) newState(ctx context.Context, be backend.Backend, module string, queries []query.Resource) (*cost.State, error) {
+	for _, q := range queries {
+		event.Emit(ctx, event.Event{Type: event.ResourceExtracted, Module: module, Address: q.Address})
+	}
+
+	if o.collectQueries != nil {
+		if len(queries) == 0 {
+			return nil, terraform.ErrNoQueries
+		}
+		o.collectQueries(queries)
+		return &cost.State{Resources: make(map[string]cost.Resource)}, nil
+	}
+
+	if o.MaxPricingAge > 0 {
+		if err := o.checkFreshness(ctx, be, module, queries); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.Currency != "" {
+		for i := range queries {
+			for j, c := range queries[i].Components {
+				pf := price.Filter{}
+				if c.PriceFilter != nil {
+					pf = *c.PriceFilter
+				}
+				pf.Currency = &o.Currency
+				queries[i].Components[j].PriceFilter = &pf
+			}
+		}
+	}
+
+	var sopts []cost.StateOption
+	if !o.AsOf.IsZero() {
+		sopts = append(sopts, cost.AsOf(o.AsOf))
+	}
+
+	state, err := cost.NewState(ctx, be, queries, sopts...)
+	if err != nil {
+		return nil, err
+	}
 
-	return cost.NewPlan(strings.Join(modules, ", "), prior, planned), nil
+	if o.Strict {
+		errs := make([]string, 0)
+		for addr, r := range state.Resources {
+			for label, c := range r.Components {
+				if c.Error != nil {
+					errs = append(errs, fmt.Sprintf("%s %q: %s", addr, label, c.Error))
+				}
+			}
+		}
+		if len(errs) != 0 {
+			sort.Strings(errs)
+			return nil, fmt.Errorf("%w: %s", ErrUnpricedComponents, strings.Join(errs, ", "))
+		}
+	}
+	return state, nil
+}
+
+// checkFreshness warns about the pricing used by the queries of the module that is older than the MaxPricingAge,
+// each one of the providers, services and regions is only checked once
+func (o *EstimateOptions) checkFreshness(ctx context.Context, be backend.Backend, module string, queries []query.Resource) error {
+	scopes := queryScopes(queries)
+	for s := range scopes {
+		if _, checked := o.freshnessChecked.LoadOrStore(s, struct{}{}); checked {
+			delete(scopes, s)
+		}
+	}
+
+	errs, err := checkFreshness(ctx, be, scopes, o.MaxPricingAge)
+	if err != nil {
+		return err
+	}
+	for _, err := range errs {
+		o.Logger.WarnContext(ctx, "Outdated pricing", "module", module, "error", err)
+		event.Emit(ctx, event.Event{Type: event.PricingOutdated, Module: module, Err: err})
+	}
+	return nil
+}
+
+// newCostScenarios returns the empty cost.Scenario for each one of the usage scenarios
+func newCostScenarios(scenarios []usage.Scenario) []cost.Scenario {
+	costs := make([]cost.Scenario, 0, len(scenarios))
+	for _, s := range scenarios {
+		costs = append(costs, cost.Scenario{Name: s.Name, Plans: make([]*cost.Plan, 0)})
+	}
+	return costs
 }
 
 // EstimateHCL is a helper function that recursively reads Terraform modules from a directory at the
@@ -81,10 +230,57 @@ func EstimateTerraformPlan(ctx context.Context, be backend.Backend, plan io.Read
 // If Force Terragrunt(ftg) is set then we'll just run Terragrunt
 // If Parallelisim Terragrunt is set(!=0) it'll set it when running TG
 // If debug is set to true it'll add more complex logging
+// It's like EstimateStack with the equivalent options, which also allows to set the terraform.HCLOptions.
 func EstimateHCL(ctx context.Context, be backend.Backend, afs afero.Fs, stackPath, modulePath string, ftg bool, ptg int, u usage.Usage, debug bool, providerInitializers ...terraform.ProviderInitializer) ([]*cost.Plan, error) {
-	if len(providerInitializers) == 0 {
-		providerInitializers = getDefaultProviders()
+	return EstimateStack(ctx, be, stackPath, hclOptions(afs, modulePath, ftg, ptg, debug, providerInitializers), WithUsage(u))
+}
+
+// EstimateHCLScenarios is like EstimateHCL but it estimates the modules with each one of the usage scenarios,
+// returning a cost.Scenario for each one of them in the same order. The stack is only walked and run with
+// Terragrunt once for all the scenarios.
+func EstimateHCLScenarios(ctx context.Context, be backend.Backend, afs afero.Fs, stackPath, modulePath string, ftg bool, ptg int, scenarios []usage.Scenario, debug bool, providerInitializers ...terraform.ProviderInitializer) ([]cost.Scenario, error) {
+	return EstimateStackScenarios(ctx, be, stackPath, scenarios, hclOptions(afs, modulePath, ftg, ptg, debug, providerInitializers))
+}
+
+// hclOptions returns the EstimateOption with the parameters of EstimateHCL
+func hclOptions(afs afero.Fs, modulePath string, ftg bool, ptg int, debug bool, providerInitializers []terraform.ProviderInitializer) EstimateOption {
+	return func(o *EstimateOptions) {
+		o.Fs = afs
+		o.ModulePath = modulePath
+		o.ForceTerragrunt = ftg
+		o.TerragruntParallelism = ptg
+		o.Debug = debug
+		o.ProviderInitializers = providerInitializers
+	}
+}
+
+// EstimateStack recursively reads the Terraform modules, or the Terragrunt units, of the stackPath and returns a
+// cost.Plan with the planned cost.State of each one of them, using the Backend to retrieve the pricing data. With
+// WithBestEffort the plans of the modules that could be estimated are returned with a ModuleErrors of the rest.
+func EstimateStack(ctx context.Context, be backend.Backend, stackPath string, opts ...EstimateOption) ([]*cost.Plan, error) {
+	o := newEstimateOptions(opts...)
+	costs, err := estimateHCL(ctx, be, stackPath, []usage.Scenario{{Usage: o.Usage}}, o)
+	if err != nil {
+		var merrs ModuleErrors
+		if errors.As(err, &merrs) {
+			return costs[0].Plans, err
+		}
+		return nil, err
 	}
+	return costs[0].Plans, nil
+}
+
+// EstimateStackScenarios is like EstimateStack but it estimates the modules with each one of the usage
+// scenarios, returning a cost.Scenario for each one of them in the same order
+func EstimateStackScenarios(ctx context.Context, be backend.Backend, stackPath string, scenarios []usage.Scenario, opts ...EstimateOption) ([]cost.Scenario, error) {
+	return estimateHCL(ctx, be, stackPath, scenarios, newEstimateOptions(opts...))
+}
+
+// estimateHCL estimates the modules of the stackPath with each one of the scenarios
+func estimateHCL(ctx context.Context, be backend.Backend, stackPath string, scenarios []usage.Scenario, o *EstimateOptions) ([]cost.Scenario, error) {
+	ctx = o.context(ctx)
+
+	modulePath := o.ModulePath
 	var (
 		relModulePath string
 		err           error
@@ -98,14 +294,11 @@ func EstimateHCL(ctx context.Context, be backend.Backend, afs afero.Fs, stackPat
 		modulePath = stackPath
 	}
 
-	log.Logger.DebugContext(ctx, "Paths evaluated", "stackPath", stackPath, "modulePath", modulePath, "relModulePath", relModulePath)
-
-	if afs == nil {
-		afs = afero.NewOsFs()
-	}
+	o.Logger.DebugContext(ctx, "Paths evaluated", "stackPath", stackPath, "modulePath", modulePath, "relModulePath", relModulePath)
 
-	if !ftg {
-		log.Logger.DebugContext(ctx, "No TerraGrunt was forced")
+	afs := o.Fs
+	if !o.ForceTerragrunt {
+		o.Logger.DebugContext(ctx, "No TerraGrunt was forced")
 		var hasTG bool
 		// We first check if the main main modulePath has a Terragrunt file to know what we have to run
 		err = afero.Walk(afs, modulePath, func(p string, info fs.FileInfo, err error) error {
@@ -128,20 +321,31 @@ func EstimateHCL(ctx context.Context, be backend.Backend, afs afero.Fs, stackPat
 
 		// If no Terragrunt file is found then we execute the normal code
 		if !hasTG {
-			log.Logger.DebugContext(ctx, "No TerraGrunt found executing ExtractQueriesFromHCL", "modulePath", modulePath)
-			plannedQueries, modAddr, err := terraform.ExtractQueriesFromHCL(afs, providerInitializers, modulePath, u, nil)
+			o.Logger.DebugContext(ctx, "No TerraGrunt found executing ExtractQueriesFromHCL", "modulePath", modulePath)
+			event.Emit(ctx, event.Event{Type: event.ModuleDiscovered, Module: modulePath})
+			// The module is read once and then estimated with the usage of each scenario
+			mod, modAddr, err := terraform.ReadHCLModule(afs, o.ProviderInitializers, modulePath, nil, o.HCL)
 			if err != nil {
 				return nil, fmt.Errorf("failed to ExtractQueriesFromHCL on module %q executed on 'stackPath' %q and 'modulePath' %q with error: %w", modAddr, stackPath, modulePath, err)
 			}
-			planned, err := cost.NewState(ctx, be, plannedQueries)
-			if err != nil {
-				return nil, fmt.Errorf("failed to initialize a state: %w", err)
+			costs := newCostScenarios(scenarios)
+			for i, s := range scenarios {
+				planned, err := o.newState(ctx, be, modAddr, mod.Queries(s.Usage))
+				if err != nil {
+					return nil, fmt.Errorf("failed to initialize a state: %w", err)
+				}
+
+				costs[i].Plans = append(costs[i].Plans, cost.NewPlan(modAddr, nil, planned))
 			}
 
-			return []*cost.Plan{cost.NewPlan(modAddr, nil, planned)}, nil
+			return costs, nil
 		}
 	}
 
+	if o.NativeTerragrunt {
+		return estimateTerragruntUnits(ctx, be, modulePath, scenarios, o)
+	}
+
 	// We create a tmp dir to move the files from fs to it so we can
 	// run Terragrunt on it. Terragrunt only runs on OS
 	tmpdir, err := os.MkdirTemp("", "terracost-terragrunt")
@@ -150,14 +354,14 @@ func EstimateHCL(ctx context.Context, be backend.Backend, afs afero.Fs, stackPat
 	}
 	defer os.RemoveAll(tmpdir)
 
-	log.Logger.DebugContext(ctx, "Moving files to from Afero to FS", "stackPath", stackPath, "tmpdir", tmpdir)
+	o.Logger.DebugContext(ctx, "Moving files to from Afero to FS", "stackPath", stackPath, "tmpdir", tmpdir)
 	// We move the files from afs stackPath to the just created tmpdir
 	err = util.FromAferoToOS(afs, stackPath, tmpdir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to move content from Afero(%q) to OS: %w", stackPath, err)
 	}
 
-	log.Logger.DebugContext(ctx, "Getting TerraGrunt options", "path", relModulePath)
+	o.Logger.DebugContext(ctx, "Getting TerraGrunt options", "path", relModulePath)
 	tgo, err := options.NewTerragruntOptions(filepath.Join(tmpdir, relModulePath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create terragrunt options for %s: %w", tmpdir, err)
@@ -166,8 +370,8 @@ func EstimateHCL(ctx context.Context, be backend.Backend, afs afero.Fs, stackPat
 
 	// If we have a specific Parallelism we set it, if not
 	// we'll use the default one
-	if ptg != 0 {
-		tgo.Parallelism = ptg
+	if o.TerragruntParallelism != 0 {
+		tgo.Parallelism = o.TerragruntParallelism
 	}
 
 	// DryRun is an specific option we added to the fork of Terragrunt we have.
@@ -183,7 +387,7 @@ func EstimateHCL(ctx context.Context, be backend.Backend, afs afero.Fs, stackPat
 	// We set Writer and ErrWriter to io.Discard so we do not get
 	// any logs on the screen when running test of the tool itself
 	var buff = &bytes.Buffer{}
-	if debug {
+	if o.Debug {
 		tgo.LogLevel = logrus.DebugLevel
 
 		tgo.Env = map[string]string{
@@ -198,7 +402,7 @@ func EstimateHCL(ctx context.Context, be backend.Backend, afs afero.Fs, stackPat
 	// We need to initialize the tmpdir as a git repository because if the Terragrunt
 	// config has any of the functions like 'get_repo_root' it would fail if it's not
 	// a git repository
-	log.Logger.DebugContext(ctx, "Running Git Init", "path", tmpdir)
+	o.Logger.DebugContext(ctx, "Running Git Init", "path", tmpdir)
 	_, err = git.PlainInit(tmpdir, false)
 	if err != nil && !errors.Is(git.ErrRepositoryAlreadyExists, err) {
 		return nil, fmt.Errorf("failed to initialize git repo %q: %w", tmpdir, err)
@@ -214,7 +418,7 @@ func EstimateHCL(ctx context.Context, be backend.Backend, afs afero.Fs, stackPat
 	defer close(tgRun)
 
 	// Runs Terragrunt which basically generates some submodules
-	log.Logger.DebugContext(ctx, "Running TerraGrunt")
+	o.Logger.DebugContext(ctx, "Running TerraGrunt")
 	go func() {
 		localErr := stack.Run(tgo)
 		tgRun <- localErr
@@ -229,70 +433,241 @@ func EstimateHCL(ctx context.Context, be backend.Backend, afs afero.Fs, stackPat
 		}
 	}
 
-	log.Logger.DebugContext(ctx, "Modules found", "count", len(stack.Modules))
+	o.Logger.DebugContext(ctx, "Modules found", "count", len(stack.Modules))
 
-	costs := make([]*cost.Plan, 0)
-	for _, m := range stack.Modules {
-		log.Logger.DebugContext(ctx, "Working on module", "path", m.TerragruntOptions.WorkingDir)
-		// We ReadTerragruntConfig so we can have the 'tgc.Inputs' which has the values+variables
-		// that we need to set to the module. Normally those inputs are passed via ENV variables
-		// when Terragrunt is running
-		// We also have access to the 'Skip' because if true we do need to do any actions
-		tgc, _ := config.ReadTerragruntConfig(m.TerragruntOptions)
-		if tgc.Skip {
-			modAddr := filepath.Base(m.TerragruntOptions.WorkingDir)
+	plans, errs, err := estimateEach(ctx, o, len(stack.Modules), func(ctx context.Context, i int) ([]*cost.Plan, error) {
+		return estimateTerragruntModule(ctx, be, stack.Modules[i], scenarios, o)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+		return nil, fmt.Errorf("failed to estimate module on 'stackPath' %q and 'modulePath' %q with error: %w", stackPath, modulePath, err)
+	}
 
-			costs = append(costs, cost.NewPlan(modAddr, nil, nil))
+	costs := newCostScenarios(scenarios)
+	var merrs ModuleErrors
+	for mi, m := range stack.Modules {
+		if err := errs[mi]; err != nil {
+			// The path is the one of the module on the stackPath instead of the tmpdir
+			mpath := m.Path
+			if rel, err := filepath.Rel(tmpdir, m.Path); err == nil {
+				mpath = filepath.Join(stackPath, rel)
+			}
+			o.Logger.DebugContext(ctx, "Failed to estimate module", "path", mpath, "error", err)
+			merrs = append(merrs, &ModuleError{Path: mpath, Err: err})
 			continue
 		}
+		for i, p := range plans[mi] {
+			costs[i].Plans = append(costs[i].Plans, p)
+		}
+	}
+	if len(merrs) != 0 {
+		return costs, merrs
+	}
+	return costs, nil
+}
 
-		sourceURL, err := config.GetTerraformSourceUrl(m.TerragruntOptions, &m.Config)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get terraform source url: %w", err)
+// estimateEach calls fn for each one of the n modules with the Concurrency of the o, returning their plans
+// and errors in the same order. Unless BestEffort is set, it stops on the first error and returns it.
+func estimateEach(ctx context.Context, o *EstimateOptions, n int, fn func(ctx context.Context, i int) ([]*cost.Plan, error)) ([][]*cost.Plan, []error, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	plans := make([][]*cost.Plan, n)
+	errs := make([]error, n)
+	sem := make(chan struct{}, o.Concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
 		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			plans[i], errs[i] = fn(ctx, i)
+			if errs[i] != nil && !o.BestEffort {
+				cancel(errs[i])
+			}
+		}(i)
+	}
+	wg.Wait()
 
-		// We need to get the terraformSource as it has the '.WorkingDir' which has the right path of the module just downloaded on the 'stack.Run'
-		// this path is not predictable so we need to get it from this 'terraformSource'
-		terraformSource, err := tfsource.NewTerraformSource(sourceURL, m.TerragruntOptions.DownloadDir, m.TerragruntOptions.WorkingDir, m.TerragruntOptions.Logger)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get terraform source: %w", err)
+	if ctx.Err() != nil {
+		return nil, nil, context.Cause(ctx)
+	}
+	return plans, errs, nil
+}
+
+// estimateTerragruntModule estimates the module m that Terragrunt generated with each one of the
+// scenarios, returning one cost.Plan for each one of them in the same order
+func estimateTerragruntModule(ctx context.Context, be backend.Backend, m *configstack.TerraformModule, scenarios []usage.Scenario, o *EstimateOptions) ([]*cost.Plan, error) {
+	o.Logger.DebugContext(ctx, "Working on module", "path", m.TerragruntOptions.WorkingDir)
+	event.Emit(ctx, event.Event{Type: event.ModuleDiscovered, Module: m.TerragruntOptions.WorkingDir})
+	// We ReadTerragruntConfig so we can have the 'tgc.Inputs' which has the values+variables
+	// that we need to set to the module. Normally those inputs are passed via ENV variables
+	// when Terragrunt is running
+	// We also have access to the 'Skip' because if true we do need to do any actions
+	tgc, _ := config.ReadTerragruntConfig(m.TerragruntOptions)
+	name := filepath.Base(m.TerragruntOptions.WorkingDir)
+	if tgc.Skip {
+		return skippedPlans(name, scenarios), nil
+	}
+
+	sourceURL, err := config.GetTerraformSourceUrl(m.TerragruntOptions, &m.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get terraform source url: %w", err)
+	}
+
+	// We need to get the terraformSource as it has the '.WorkingDir' which has the right path of the module just downloaded on the 'stack.Run'
+	// this path is not predictable so we need to get it from this 'terraformSource'
+	terraformSource, err := tfsource.NewTerraformSource(sourceURL, m.TerragruntOptions.DownloadDir, m.TerragruntOptions.WorkingDir, m.TerragruntOptions.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get terraform source: %w", err)
+	}
+	nfs := afero.NewMemMapFs()
+
+	// We move the downloaded and generated code+module from the 'terraformSource.WorkingDir' (which is on the OS) to the 'nfs' which
+	// is a Memory implementation
+	err = util.FromOSToAfero(nfs, terraformSource.WorkingDir, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to move content from OS(%q) to Afero: %w", terraformSource.WorkingDir, err)
+	}
+
+	// The variable definition files are on the afs so we copy
+	// them to the nfs in which the module is read
+	err = copyFiles(o.Fs, nfs, o.HCL.VarFiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy the variable definition files: %w", err)
+	}
+
+	o.Logger.DebugContext(ctx, "ExtractQueriesFromHCL", "Inputs", tgc.Inputs)
+	return estimateModule(ctx, be, nfs, "", name, tgc.Inputs, scenarios, o)
+}
+
+// estimateModule estimates the module on the modPath of the fs with the inputs with each one of the scenarios,
+// returning one cost.Plan for each one of them in the same order. The name is used when the module has no module calls.
+func estimateModule(ctx context.Context, be backend.Backend, fs afero.Fs, modPath, name string, inputs map[string]interface{}, scenarios []usage.Scenario, o *EstimateOptions) ([]*cost.Plan, error) {
+	mod, modAddr, err := terraform.ReadHCLModule(fs, o.ProviderInitializers, modPath, inputs, o.HCL)
+	// If no module is defined we can always use the name of
+	// the directory in which the module was found
+	if modAddr == "" {
+		modAddr = name
+	}
+	if err != nil {
+		if err == terraform.ErrNoKnownProvider {
+			// If we do not know the provider it means we have to skip it,
+			// the best way is to just return nil instead of the error so we
+			// can continue estimating the rest
+			return skippedPlans(modAddr, scenarios), nil
 		}
-		nfs := afero.NewMemMapFs()
+		return nil, fmt.Errorf("failed to ExtractQueriesFromHCL on module %q: %w", modAddr, err)
+	}
 
-		// We move the downloaded and generated code+module from the 'terraformSource.WorkingDir' (which is on the OS) to the 'nfs' which
-		// is a Memory implementation
-		err = util.FromOSToAfero(nfs, terraformSource.WorkingDir, "")
+	// The module is read once and then estimated with the usage of each scenario
+	plans := make([]*cost.Plan, 0, len(scenarios))
+	for _, s := range scenarios {
+		planned, err := o.newState(ctx, be, modAddr, mod.Queries(s.Usage))
 		if err != nil {
-			return nil, fmt.Errorf("failed to move content from OS(%q) to Afero: %w", terraformSource.WorkingDir, err)
+			return nil, err
 		}
 
-		log.Logger.DebugContext(ctx, "ExtractQueriesFromHCL", "Inputs", tgc.Inputs)
-		plannedQueries, modAddr, err := terraform.ExtractQueriesFromHCL(nfs, providerInitializers, "", u, tgc.Inputs)
+		plans = append(plans, cost.NewPlan(modAddr, nil, planned))
+	}
+	return plans, nil
+}
+
+// skippedPlans returns the empty cost.Plan of a skipped module for each one of the scenarios
+func skippedPlans(name string, scenarios []usage.Scenario) []*cost.Plan {
+	plans := make([]*cost.Plan, 0, len(scenarios))
+	for range scenarios {
+		plans = append(plans, cost.NewPlan(name, nil, nil))
+	}
+	return plans
+}
+
+// estimateTerragruntUnits estimates the Terragrunt units on the modulePath parsing
+// their configurations with the terragrunt package instead of running Terragrunt
+func estimateTerragruntUnits(ctx context.Context, be backend.Backend, modulePath string, scenarios []usage.Scenario, o *EstimateOptions) ([]cost.Scenario, error) {
+	units, err := terragrunt.FindUnits(ctx, o.Fs, modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the Terragrunt units of %q: %w", modulePath, err)
+	}
+
+	o.Logger.DebugContext(ctx, "Units found", "count", len(units))
+
+	plans, errs, err := estimateEach(ctx, o, len(units), func(ctx context.Context, i int) ([]*cost.Plan, error) {
+		return estimateTerragruntUnit(ctx, be, units[i], scenarios, o)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	costs := newCostScenarios(scenarios)
+	var merrs ModuleErrors
+	for ui, u := range units {
+		if err := errs[ui]; err != nil {
+			o.Logger.DebugContext(ctx, "Failed to estimate unit", "path", u.Path, "error", err)
+			merrs = append(merrs, &ModuleError{Path: u.Path, Err: err})
+			continue
+		}
+		for i, p := range plans[ui] {
+			costs[i].Plans = append(costs[i].Plans, p)
+		}
+	}
+	if len(merrs) != 0 {
+		return costs, merrs
+	}
+	return costs, nil
+}
+
+// estimateTerragruntUnit estimates the unit u with each one of the scenarios,
+// returning one cost.Plan for each one of them in the same order
+func estimateTerragruntUnit(ctx context.Context, be backend.Backend, u *terragrunt.Unit, scenarios []usage.Scenario, o *EstimateOptions) ([]*cost.Plan, error) {
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+
+	o.Logger.DebugContext(ctx, "Working on unit", "path", u.Path)
+	event.Emit(ctx, event.Event{Type: event.ModuleDiscovered, Module: u.Path})
+	name := filepath.Base(u.Path)
+	if u.Skip {
+		return skippedPlans(name, scenarios), nil
+	}
+
+	mfs, mpath, err := u.Module(o.Fs, o.HCL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare the module of %q: %w", u.Path, err)
+	}
+
+	o.Logger.DebugContext(ctx, "ExtractQueriesFromHCL", "Inputs", u.Inputs)
+	plans, err := estimateModule(ctx, be, mfs, mpath, name, u.Inputs, scenarios, o)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate unit %q: %w", u.Path, err)
+	}
+	return plans, nil
+}
+
+// copyFiles copies the files on paths from the src to the dst, on the same path
+func copyFiles(src, dst afero.Fs, paths []string) error {
+	for _, p := range paths {
+		b, err := afero.ReadFile(src, p)
 		if err != nil {
-			if err == terraform.ErrNoKnownProvider {
-				// If we do not know the provider it means we have to skip it,
-				// the best way is to just return nil instead of the error so we
-				// can continue estimating the rest
-				if modAddr == "" {
-					modAddr = filepath.Base(m.TerragruntOptions.WorkingDir)
-				}
-				costs = append(costs, cost.NewPlan(modAddr, nil, nil))
-				continue
-			}
-			return nil, fmt.Errorf("failed to ExtractQueriesFromHCL on module %q executed on 'stackPath' %q and 'modulePath' %q with error: %w", modAddr, stackPath, modulePath, err)
+			return fmt.Errorf("failed to read %q: %w", p, err)
 		}
-		planned, err := cost.NewState(ctx, be, plannedQueries)
+		err = dst.MkdirAll(filepath.Dir(p), 0700)
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("could not create path %q: %w", filepath.Dir(p), err)
 		}
-
-		// If no module is defined we can always use the name of the WorkingDir in which
-		// TG found the modules
-		if modAddr == "" {
-			modAddr = filepath.Base(m.TerragruntOptions.WorkingDir)
+		err = afero.WriteFile(dst, p, b, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to write %q: %w", p, err)
 		}
-
-		costs = append(costs, cost.NewPlan(modAddr, nil, planned))
 	}
-	return costs, nil
+	return nil
 }
@@ -0,0 +1,470 @@
+package terracost_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cycloidio/terracost"
+	"github.com/cycloidio/terracost/aws"
+	"github.com/cycloidio/terracost/cost"
+	"github.com/cycloidio/terracost/event"
+	"github.com/cycloidio/terracost/log"
+	"github.com/cycloidio/terracost/mock"
+	"github.com/cycloidio/terracost/price"
+	"github.com/cycloidio/terracost/product"
+	"github.com/cycloidio/terracost/terraform"
+	"github.com/cycloidio/terracost/usage"
+)
+
+func TestEstimateHCLScenarios(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	productRepo := mock.NewProductRepository(ctrl)
+	priceRepo := mock.NewPriceRepository(ctrl)
+	backend := mock.NewBackend(ctrl)
+
+	backend.EXPECT().Products().AnyTimes().Return(productRepo)
+	backend.EXPECT().Prices().AnyTimes().Return(priceRepo)
+	productRepo.EXPECT().Filter(gomock.Any(), gomock.Any()).AnyTimes().Return([]*product.Product{{ID: 1}}, nil)
+	priceRepo.EXPECT().Filter(gomock.Any(), product.ID(1), gomock.Any()).AnyTimes().Return([]*price.Price{
+		{Value: decimal.RequireFromString("0.1"), Currency: "USD"},
+	}, nil)
+
+	scenarios := []usage.Scenario{
+		{
+			Name: "low",
+			Usage: usage.Usage{ResourceDefaultTypeUsage: map[string]interface{}{
+				"aws_s3_bucket": map[string]interface{}{"storage_gb": 10, "monthly_outbound_data_gb": 1},
+			}},
+		},
+		{
+			Name: "high",
+			Usage: usage.Usage{ResourceDefaultTypeUsage: map[string]interface{}{
+				"aws_s3_bucket": map[string]interface{}{"storage_gb": 1000, "monthly_outbound_data_gb": 100},
+			}},
+		},
+	}
+
+	css, err := terracost.EstimateHCLScenarios(context.Background(), backend, nil, "testdata/aws/stack-expansion", "", false, 0, scenarios, false)
+	require.NoError(t, err)
+	require.Len(t, css, 2)
+	assert.Equal(t, "low", css[0].Name)
+	assert.Equal(t, "high", css[1].Name)
+	require.Len(t, css[0].Plans, 1)
+	require.Len(t, css[1].Plans, 1)
+
+	low, err := css[0].PlannedCost()
+	require.NoError(t, err)
+	high, err := css[1].PlannedCost()
+	require.NoError(t, err)
+	assert.True(t, high.GreaterThan(low.Decimal), "expected %s > %s", high, low)
+
+	ranges, err := cost.ComponentRanges(css)
+	require.NoError(t, err)
+	require.NotEmpty(t, ranges)
+	for _, cr := range ranges {
+		// Only the buckets have usage based components
+		assert.True(t, strings.HasPrefix(cr.Address, "aws_s3_bucket.bucket"), cr.Address)
+		assert.Len(t, cr.Costs, 2)
+		assert.True(t, cr.Costs["low"].Equal(cr.Min.Decimal), "%s %s", cr.Address, cr.Label)
+		assert.True(t, cr.Costs["high"].Equal(cr.Max.Decimal), "%s %s", cr.Address, cr.Label)
+	}
+}
+
+func TestEstimateStackScenarios_ReadOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	productRepo := mock.NewProductRepository(ctrl)
+	priceRepo := mock.NewPriceRepository(ctrl)
+	backend := mock.NewBackend(ctrl)
+
+	backend.EXPECT().Products().AnyTimes().Return(productRepo)
+	backend.EXPECT().Prices().AnyTimes().Return(priceRepo)
+	productRepo.EXPECT().Filter(gomock.Any(), gomock.Any()).AnyTimes().Return([]*product.Product{{ID: 1}}, nil)
+	priceRepo.EXPECT().Filter(gomock.Any(), product.ID(1), gomock.Any()).AnyTimes().Return([]*price.Price{
+		{Value: decimal.RequireFromString("0.1"), Currency: "USD"},
+	}, nil)
+
+	scenarios := []usage.Scenario{{Name: "low", Usage: usage.Default}, {Name: "mid", Usage: usage.Default}, {Name: "high", Usage: usage.Default}}
+
+	// The providers of a module are initialized each time it's read
+	var (
+		mux   sync.Mutex
+		reads int
+	)
+	pi := aws.NewTerraformProviderInitializer()
+	initProvider := pi.ProviderWithLogger
+	pi.ProviderWithLogger = func(logger *slog.Logger, values map[string]interface{}) (terraform.Provider, error) {
+		mux.Lock()
+		reads++
+		mux.Unlock()
+		return initProvider(logger, values)
+	}
+
+	t.Run("Module", func(t *testing.T) {
+		reads = 0
+		css, err := terracost.EstimateStackScenarios(context.Background(), backend, "testdata/aws/stack-expansion", scenarios, terracost.WithProviders(pi))
+		require.NoError(t, err)
+		require.Len(t, css, 3)
+		assert.Equal(t, 1, reads)
+	})
+	t.Run("NativeTerragrunt", func(t *testing.T) {
+		reads = 0
+		css, err := terracost.EstimateStackScenarios(context.Background(), backend, "testdata/aws/terragrunt-native", scenarios, terracost.WithProviders(pi), terracost.WithNativeTerragrunt())
+		require.NoError(t, err)
+		require.Len(t, css, 3)
+		require.Len(t, css[0].Plans, 6)
+		// Each unit is read once but the skipped one
+		assert.Equal(t, 5, reads)
+	})
+}
+
+func TestEstimateStack_LogHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	productRepo := mock.NewProductRepository(ctrl)
+	priceRepo := mock.NewPriceRepository(ctrl)
+	backend := mock.NewBackend(ctrl)
+
+	backend.EXPECT().Products().AnyTimes().Return(productRepo)
+	backend.EXPECT().Prices().AnyTimes().Return(priceRepo)
+	productRepo.EXPECT().Filter(gomock.Any(), gomock.Any()).AnyTimes().Return(nil, nil)
+
+	afs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(afs, "/stack/main.tf", []byte(`
+provider "aws" {}
+
+locals {
+  invalid = unknown_function(1)
+}
+
+resource "aws_instance" "web" {
+  ami           = "ami-123456"
+  instance_type = "t3.micro"
+}
+`), 0644))
+
+	// Nothing has to be logged with the package logger
+	var global bytes.Buffer
+	defer func(l *slog.Logger) { log.Logger = l }(log.Logger)
+	log.Logger = slog.New(slog.NewTextHandler(&global, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	var buff bytes.Buffer
+	_, err := terracost.EstimateStack(context.Background(), backend, "/stack",
+		terracost.WithFs(afs),
+		terracost.WithLogHandler(slog.NewTextHandler(&buff, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	)
+	require.NoError(t, err)
+
+	assert.Contains(t, buff.String(), "level=ERROR msg=\"hcl: Error on abstracting value for 'local'\"")
+	assert.Contains(t, buff.String(), "AWS terraform provider region not set")
+	assert.Empty(t, global.String())
+}
+
+func TestEstimateStack_NativeTerragrunt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	productRepo := mock.NewProductRepository(ctrl)
+	priceRepo := mock.NewPriceRepository(ctrl)
+	backend := mock.NewBackend(ctrl)
+
+	instanceTypes := make(map[string]string)
+	backend.EXPECT().Products().AnyTimes().Return(productRepo)
+	backend.EXPECT().Prices().AnyTimes().Return(priceRepo)
+	productRepo.EXPECT().Filter(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(func(_ context.Context, f *product.Filter) ([]*product.Product, error) {
+		for _, af := range f.AttributeFilters {
+			if af.Key == "InstanceType" && af.Value != nil {
+				instanceTypes[*af.Value] = *f.Location
+			}
+		}
+		return []*product.Product{{ID: 1}}, nil
+	})
+	priceRepo.EXPECT().Filter(gomock.Any(), product.ID(1), gomock.Any()).AnyTimes().Return([]*price.Price{
+		{Value: decimal.RequireFromString("0.1"), Currency: "USD"},
+	}, nil)
+
+	// The read only fs makes sure nothing is written
+	afs := afero.NewReadOnlyFs(afero.NewOsFs())
+	plans, err := terracost.EstimateStack(context.Background(), backend, "testdata/aws/terragrunt-native", terracost.WithFs(afs), terracost.WithNativeTerragrunt())
+	require.NoError(t, err)
+	require.Len(t, plans, 6)
+
+	names := make([]string, 0, len(plans))
+	for _, p := range plans {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"db", "local", "queue", "skipped", "web", "worker"}, names)
+	assert.Nil(t, plans[3].Planned)
+	for _, i := range []int{0, 1, 2, 4, 5} {
+		assert.NotNil(t, plans[i].Planned, plans[i].Name)
+	}
+
+	// The db uses the mock_outputs of the web, the worker the outputs on
+	// the state of the queue and the region comes from the generated provider
+	assert.Equal(t, map[string]string{
+		"c5.large": "eu-west-1",
+		"m5.large": "eu-west-1",
+		"t3.small": "eu-west-1",
+		"t3.large": "eu-west-1",
+	}, instanceTypes)
+}
+
+func TestEstimateStack_BestEffort(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	productRepo := mock.NewProductRepository(ctrl)
+	priceRepo := mock.NewPriceRepository(ctrl)
+	backend := mock.NewBackend(ctrl)
+
+	backend.EXPECT().Products().AnyTimes().Return(productRepo)
+	backend.EXPECT().Prices().AnyTimes().Return(priceRepo)
+	productRepo.EXPECT().Filter(gomock.Any(), gomock.Any()).AnyTimes().Return([]*product.Product{{ID: 1}}, nil)
+	priceRepo.EXPECT().Filter(gomock.Any(), product.ID(1), gomock.Any()).AnyTimes().Return([]*price.Price{
+		{Value: decimal.RequireFromString("0.1"), Currency: "USD"},
+	}, nil)
+
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/stack/terragrunt.hcl":         ``,
+		"/stack/modules/vm/main.tf":     "provider \"aws\" {}\nresource \"aws_instance\" \"vm\" { instance_type = \"t3.micro\" }",
+		"/stack/app/terragrunt.hcl":     `terraform { source = "../modules/vm" }`,
+		"/stack/broken/terragrunt.hcl":  `terraform { source = "../modules/missing" }`,
+		"/stack/invalid/terragrunt.hcl": `terraform { source = "../modules/vm" }`,
+		"/stack/invalid/invalid.tf":     `resource "aws_instance" {`,
+	}
+	for p, c := range files {
+		require.NoError(t, afero.WriteFile(fs, p, []byte(c), 0644))
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		plans, err := terracost.EstimateStack(context.Background(), backend, "/stack", terracost.WithFs(fs), terracost.WithNativeTerragrunt())
+		require.Error(t, err)
+		assert.Nil(t, plans)
+
+		var merrs terracost.ModuleErrors
+		assert.False(t, errors.As(err, &merrs))
+	})
+
+	t.Run("BestEffort", func(t *testing.T) {
+		plans, err := terracost.EstimateStack(context.Background(), backend, "/stack", terracost.WithFs(fs), terracost.WithNativeTerragrunt(), terracost.WithBestEffort())
+		require.Error(t, err)
+		require.Len(t, plans, 1)
+		assert.Equal(t, "app", plans[0].Name)
+		assert.NotNil(t, plans[0].Planned)
+
+		var merrs terracost.ModuleErrors
+		require.True(t, errors.As(err, &merrs))
+		assert.Equal(t, []string{"/stack/broken", "/stack/invalid"}, merrs.Paths())
+
+		var merr *terracost.ModuleError
+		require.True(t, errors.As(err, &merr))
+		assert.Equal(t, "/stack/broken", merr.Path)
+	})
+}
+
+func TestEstimateState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	productRepo := mock.NewProductRepository(ctrl)
+	priceRepo := mock.NewPriceRepository(ctrl)
+	backend := mock.NewBackend(ctrl)
+
+	locations := make(map[string]struct{})
+	backend.EXPECT().Products().AnyTimes().Return(productRepo)
+	backend.EXPECT().Prices().AnyTimes().Return(priceRepo)
+	productRepo.EXPECT().Filter(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(func(_ context.Context, f *product.Filter) ([]*product.Product, error) {
+		locations[*f.Location] = struct{}{}
+		return []*product.Product{{ID: 1}}, nil
+	})
+	priceRepo.EXPECT().Filter(gomock.Any(), product.ID(1), gomock.Any()).AnyTimes().Return([]*price.Price{
+		{Value: decimal.RequireFromString("0.1"), Currency: "USD"},
+	}, nil)
+
+	f, err := os.Open("testdata/aws/terraform-state.json")
+	require.NoError(t, err)
+	defer f.Close()
+
+	plan, err := terracost.EstimateState(context.Background(), backend, f, terracost.WithProviderConfig("aws", map[string]string{"region": "eu-west-3"}))
+	require.NoError(t, err)
+	assert.Nil(t, plan.Prior)
+	require.NotNil(t, plan.Planned)
+
+	addrs := make([]string, 0, len(plan.Planned.Resources))
+	for a := range plan.Planned.Resources {
+		addrs = append(addrs, a)
+	}
+	assert.ElementsMatch(t, []string{"aws_instance.web", "module.workers.aws_instance.this[0]", "module.workers.aws_instance.this[1]"}, addrs)
+	assert.Equal(t, map[string]struct{}{"eu-west-3": {}}, locations)
+}
+
+func TestEstimatePlan(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	productRepo := mock.NewProductRepository(ctrl)
+	priceRepo := mock.NewPriceRepository(ctrl)
+	backend := mock.NewBackend(ctrl)
+
+	backend.EXPECT().Products().AnyTimes().Return(productRepo)
+	backend.EXPECT().Prices().AnyTimes().Return(priceRepo)
+	productRepo.EXPECT().Filter(gomock.Any(), gomock.Any()).AnyTimes().Return([]*product.Product{{ID: 1}}, nil)
+	priceRepo.EXPECT().Filter(gomock.Any(), product.ID(1), gomock.Any()).AnyTimes().DoAndReturn(func(_ context.Context, _ product.ID, f *price.Filter) ([]*price.Price, error) {
+		// Only the USD prices are known
+		if f.Currency != nil && *f.Currency != "USD" {
+			return nil, nil
+		}
+		return []*price.Price{{Value: decimal.RequireFromString("0.1"), Currency: "USD"}}, nil
+	})
+
+	estimate := func(opts ...terracost.EstimateOption) (*cost.Plan, error) {
+		f, err := os.Open("testdata/aws/asg-plan.json")
+		require.NoError(t, err)
+		defer f.Close()
+		return terracost.EstimatePlan(context.Background(), backend, f, opts...)
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		plan, err := estimate(terracost.WithCurrency("USD"), terracost.WithStrict())
+		require.NoError(t, err)
+		require.NotNil(t, plan.Planned)
+		c, err := plan.PlannedCost()
+		require.NoError(t, err)
+		assert.True(t, c.IsPositive())
+		assert.Equal(t, "USD", c.Currency)
+	})
+
+	t.Run("NotPriced", func(t *testing.T) {
+		plan, err := estimate(terracost.WithCurrency("EUR"))
+		require.NoError(t, err)
+		for _, rd := range plan.ResourceDifferences() {
+			assert.NotEmpty(t, rd.Errors(), rd.Address)
+		}
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		_, err := estimate(terracost.WithCurrency("EUR"), terracost.WithStrict())
+		assert.ErrorIs(t, err, terracost.ErrUnpricedComponents)
+	})
+
+	t.Run("MaxPricingAge", func(t *testing.T) {
+		ingestionRepo := mock.NewIngestionRepository(ctrl)
+		ingestionRepo.EXPECT().LastSucceeded(gomock.Any(), "aws", gomock.Any(), gomock.Any()).AnyTimes().Return(nil, nil)
+		rbackend := &recorderBackend{Backend: backend, ingestions: ingestionRepo}
+
+		f, err := os.Open("testdata/aws/asg-plan.json")
+		require.NoError(t, err)
+		defer f.Close()
+
+		var outdated []error
+		_, err = terracost.EstimatePlan(context.Background(), rbackend, f, terracost.WithCurrency("USD"), terracost.WithMaxPricingAge(24*time.Hour), terracost.WithEventHandler(event.HandlerFunc(func(_ context.Context, e event.Event) {
+			if e.Type == event.PricingOutdated {
+				outdated = append(outdated, e.Err)
+			}
+		})))
+		require.NoError(t, err)
+		require.NotEmpty(t, outdated)
+
+		// Each one of the services and regions is only reported once
+		seen := make(map[string]struct{})
+		for _, err := range outdated {
+			var ferr *terracost.FreshnessError
+			require.ErrorAs(t, err, &ferr)
+			assert.Nil(t, ferr.LastIngestion)
+			assert.NotContains(t, seen, ferr.Error())
+			seen[ferr.Error()] = struct{}{}
+		}
+	})
+}
+
+func TestEstimateStack_Concurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	productRepo := mock.NewProductRepository(ctrl)
+	priceRepo := mock.NewPriceRepository(ctrl)
+	backend := mock.NewBackend(ctrl)
+
+	backend.EXPECT().Products().AnyTimes().Return(productRepo)
+	backend.EXPECT().Prices().AnyTimes().Return(priceRepo)
+	productRepo.EXPECT().Filter(gomock.Any(), gomock.Any()).AnyTimes().Return([]*product.Product{{ID: 1}}, nil)
+	priceRepo.EXPECT().Filter(gomock.Any(), product.ID(1), gomock.Any()).AnyTimes().Return([]*price.Price{
+		{Value: decimal.RequireFromString("0.1"), Currency: "USD"},
+	}, nil)
+
+	serial, err := terracost.EstimateStack(context.Background(), backend, "testdata/aws/terragrunt-native", terracost.WithNativeTerragrunt())
+	require.NoError(t, err)
+
+	concurrent, err := terracost.EstimateStack(context.Background(), backend, "testdata/aws/terragrunt-native", terracost.WithNativeTerragrunt(), terracost.WithConcurrency(4))
+	require.NoError(t, err)
+
+	require.Len(t, concurrent, len(serial))
+	for i := range serial {
+		assert.Equal(t, serial[i].Name, concurrent[i].Name)
+		sc, err := serial[i].PlannedCost()
+		require.NoError(t, err)
+		cc, err := concurrent[i].PlannedCost()
+		require.NoError(t, err)
+		assert.True(t, sc.Equal(cc.Decimal), serial[i].Name)
+	}
+}
+
+func TestEstimateStack_Events(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	productRepo := mock.NewProductRepository(ctrl)
+	priceRepo := mock.NewPriceRepository(ctrl)
+	backend := mock.NewBackend(ctrl)
+
+	backend.EXPECT().Products().AnyTimes().Return(productRepo)
+	backend.EXPECT().Prices().AnyTimes().Return(priceRepo)
+	productRepo.EXPECT().Filter(gomock.Any(), gomock.Any()).AnyTimes().Return([]*product.Product{{ID: 1}}, nil)
+	priceRepo.EXPECT().Filter(gomock.Any(), product.ID(1), gomock.Any()).AnyTimes().Return(nil, nil)
+
+	var (
+		buff   bytes.Buffer
+		events = make(map[event.Type][]event.Event)
+	)
+	_, err := terracost.EstimateStack(context.Background(), backend, "testdata/aws/terragrunt-native",
+		terracost.WithNativeTerragrunt(),
+		terracost.WithLogHandler(slog.NewTextHandler(&buff, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		terracost.WithEventHandler(event.HandlerFunc(func(_ context.Context, e event.Event) {
+			events[e.Type] = append(events[e.Type], e)
+		})),
+	)
+	require.NoError(t, err)
+
+	assert.Contains(t, buff.String(), "Units found")
+
+	modules := make([]string, 0)
+	for _, e := range events[event.ModuleDiscovered] {
+		modules = append(modules, filepath.Base(e.Module))
+	}
+	assert.ElementsMatch(t, []string{"db", "local", "queue", "skipped", "web", "worker"}, modules)
+	assert.NotEmpty(t, events[event.ResourceExtracted])
+	assert.Empty(t, events[event.ComponentPriced])
+	require.NotEmpty(t, events[event.ComponentFailed])
+	for _, e := range events[event.ComponentFailed] {
+		assert.ErrorIs(t, e.Err, cost.ErrPriceNotFound)
+	}
+}
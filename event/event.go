@@ -0,0 +1,91 @@
+// Package event has the events of the progress of the estimations and ingestions, which
+// are sent to the Handler set on the context.
+package event
+
+import "context"
+
+// Type is the type of an Event
+type Type string
+
+// List of the types of the events
+const (
+	// ModuleDiscovered is sent when a module, or a Terragrunt
+	// unit, is found on a stack to be estimated
+	ModuleDiscovered Type = "module_discovered"
+
+	// ResourceExtracted is sent for each resource of a module,
+	// plan or state before pricing its components
+	ResourceExtracted Type = "resource_extracted"
+
+	// ComponentPriced is sent when the price of a component is found
+	ComponentPriced Type = "component_priced"
+
+	// ComponentFailed is sent when the price of a component could not
+	// be found, with the reason on the Err
+	ComponentFailed Type = "component_failed"
+
+	// PricingOutdated is sent when the pricing of a service on a region used by the
+	// estimation is older than its maximum age or was never ingested, with the reason on the Err
+	PricingOutdated Type = "pricing_outdated"
+
+	// ProductIngested is sent when a product is stored by the ingestion
+	ProductIngested Type = "product_ingested"
+
+	// PriceIngested is sent when a price is stored by the ingestion
+	PriceIngested Type = "price_ingested"
+
+	// IngestionFinished is sent at the end of the ingestion, with the Err if it failed
+	IngestionFinished Type = "ingestion_finished"
+)
+
+// Event is an event of the progress of an estimation or an ingestion,
+// only the fields relevant to its Type are set
+type Event struct {
+	Type Type
+
+	// Module is the path of the module or Terragrunt unit
+	Module string
+
+	// Address is the address of the resource
+	Address string
+
+	// Component is the name of the component of the resource
+	Component string
+
+	// SKU is the SKU of the ingested product
+	SKU string
+
+	Err error
+}
+
+// Handler handles the events, it may be called concurrently
+type Handler interface {
+	HandleEvent(ctx context.Context, e Event)
+}
+
+// HandlerFunc is a function that implements Handler
+type HandlerFunc func(ctx context.Context, e Event)
+
+// HandleEvent calls f(ctx, e)
+func (f HandlerFunc) HandleEvent(ctx context.Context, e Event) { f(ctx, e) }
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx with the Handler h which
+// will receive the events of the functions called with it
+func NewContext(ctx context.Context, h Handler) context.Context {
+	return context.WithValue(ctx, contextKey{}, h)
+}
+
+// FromContext returns the Handler of the ctx, nil if it has none
+func FromContext(ctx context.Context) Handler {
+	h, _ := ctx.Value(contextKey{}).(Handler)
+	return h
+}
+
+// Emit sends the Event e to the Handler of the ctx, if any
+func Emit(ctx context.Context, e Event) {
+	if h := FromContext(ctx); h != nil {
+		h.HandleEvent(ctx, e)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/cycloidio/terracost"
 	"github.com/cycloidio/terracost/aws"
@@ -42,8 +43,44 @@ var (
 	flagestimateHCL          string = ""
 	flagProvider             string = "aws"
 	googleCredentialFilePath string = "/tmp/credentials.json"
+	flagUsage                string = ""
+	flagUsageTemplate        bool   = false
+	estimationUsage                 = usage.Default
+	flagUsageScenarios       scenarioFlags
+	flagNativeTerragrunt     bool = false
 )
 
+// scenarioFlags are the usage scenarios set with
+// '-usage-scenario name=path', which can be repeated
+type scenarioFlags []usage.Scenario
+
+func (sf *scenarioFlags) String() string {
+	names := make([]string, 0, len(*sf))
+	for _, s := range *sf {
+		names = append(names, s.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (sf *scenarioFlags) Set(v string) error {
+	name, path, ok := strings.Cut(v, "=")
+	if !ok {
+		return fmt.Errorf("invalid scenario %q, expected name=path", v)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	u, err := usage.Load(file)
+	if err != nil {
+		return err
+	}
+	*sf = append(*sf, usage.Scenario{Name: name, Usage: u})
+	return nil
+}
+
 func main() {
 
 	flag.Usage = helpUsage
@@ -54,9 +91,27 @@ func main() {
 	flag.StringVar(&flagestimateHCL, "estimate-hcl", flagestimateHCL, "terraform HCL code path to estimate (example: ../testdata/aws/stack-aws)")
 	flag.StringVar(&flagProvider, "provider", flagProvider, "Terraform provider used [aws|azure|gcp]")
 	flag.StringVar(&googleCredentialFilePath, "google-cred-file", googleCredentialFilePath, "GCP JSON credential file path (/tmp/credentials.json)")
+	flag.StringVar(&flagUsage, "usage", flagUsage, "YAML or JSON usage file path used to estimate instead of the default one (example: ./usage.yml)")
+	flag.BoolVar(&flagUsageTemplate, "usage-template", flagUsageTemplate, "Print a usage template for the -estimate-plan or -estimate-hcl resources instead of estimating")
+	flag.BoolVar(&flagNativeTerragrunt, "native-terragrunt", flagNativeTerragrunt, "Parse the Terragrunt configurations of -estimate-hcl instead of running Terragrunt")
+	flag.Var(&flagUsageScenarios, "usage-scenario", "Named YAML or JSON usage file to estimate with, can be repeated to compare scenarios (example: high=./usage-high.yml)")
 
 	flag.Parse()
 
+	if flagUsage != "" {
+		file, err := os.Open(flagUsage)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+		estimationUsage, err = usage.Load(file)
+		file.Close()
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// get command line args
 	args := flag.Args()
 	if len(args) == 0 {
@@ -67,6 +122,13 @@ func main() {
 		os.Exit(0)
 	}
 
+	// The usage template does not need the pricing
+	// data so it's generated before connecting
+	if flagUsageTemplate {
+		usageTemplate()
+		os.Exit(0)
+	}
+
 	// Use your mysql access with MultiStatements
 	db, err := sql.Open("mysql", "root:terracost@tcp(127.0.0.1:3306)/terracost_test?multiStatements=true")
 	if err != nil {
@@ -107,87 +169,98 @@ func ingest(flagProvider string, region string, backend *mysql.Backend, db *sql.
 		os.Exit(1)
 	}
 
-	if flagProvider == "aws" {
-		// Ingest supported services pricing data into the database
-		for _, s := range aws.GetSupportedServices() {
-			fmt.Printf("[%s] Ingestion\n", s)
+	var (
+		services    []string
+		newIngester terracost.IngesterFactory
+	)
+	switch flagProvider {
+	case "aws":
+		services = aws.GetSupportedServices()
+		newIngester = func(ctx context.Context, job terracost.IngestJob) (terracost.Ingester, error) {
 			op := []aws.Option{}
 			if flagIngestMinimal {
 				op = append(op, aws.WithIngestionFilter(aws.MinimalFilter))
 			}
-			ingester, err := aws.NewIngester(s, region, op...)
-			if err != nil {
-				fmt.Printf("%s\n", err)
-				os.Exit(1)
-			}
-
-			err = terracost.IngestPricing(context.Background(), backend, ingester)
-			if err != nil {
-				fmt.Printf("%s\n", err)
-				os.Exit(1)
-			}
+			return aws.NewIngester(job.Service, job.Region, op...)
 		}
-	} else if flagProvider == "azure" {
-		for _, s := range azurerm.GetSupportedServices() {
-			fmt.Printf("[%s] Ingestion\n", s)
+	case "azure":
+		services = azurerm.GetSupportedServices()
+		newIngester = func(ctx context.Context, job terracost.IngestJob) (terracost.Ingester, error) {
 			op := []azurerm.Option{}
 			if flagIngestMinimal {
 				op = append(op, azurerm.WithIngestionFilter(azurerm.MinimalFilter))
 			}
-			ingester, err := azurerm.NewIngester(context.Background(), s, region, op...)
-			if err != nil {
-				fmt.Printf("%s\n", err)
-				os.Exit(1)
-			}
+			return azurerm.NewIngester(ctx, job.Service, job.Region, op...)
+		}
+	case "gcp":
+		// Read a GCP access credentials json file
+		// https://developers.google.com/workspace/guides/create-credentials?hl=en
+		file, err := ioutil.ReadFile(googleCredentialFilePath)
+		if err != nil {
+			fmt.Printf("Failed to open google credential file: %s\n", err)
+		}
 
-			err = terracost.IngestPricing(context.Background(), backend, ingester)
-			if err != nil {
-				fmt.Printf("%s\n", err)
-				os.Exit(1)
-			}
+		type GoogleCredential struct {
+			ProjectID string `json:"project_id"`
 		}
-	} else if flagProvider == "gcp" {
-		for _, s := range google.GetSupportedServices() {
-			fmt.Printf("[%s] Ingestion\n", s)
+
+		// Define a variable to hold the data
+		var credential GoogleCredential
+		err = json.Unmarshal(file, &credential)
+		if err != nil {
+			fmt.Printf("Failed to decode JSON: %s\n", err)
+		}
+
+		services = google.GetSupportedServices()
+		newIngester = func(ctx context.Context, job terracost.IngestJob) (terracost.Ingester, error) {
 			op := []google.Option{}
 			if flagIngestMinimal {
 				op = append(op, google.WithIngestionFilter(google.MinimalFilter))
 			}
+			return google.NewIngester(ctx, file, job.Service, credential.ProjectID, job.Region, op...)
+		}
+	default:
+		return
+	}
 
-			// Read a GCP access credentials json file
-			// https://developers.google.com/workspace/guides/create-credentials?hl=en
-			file, err := ioutil.ReadFile(googleCredentialFilePath)
-			if err != nil {
-				fmt.Printf("Failed to open google credential file: %s\n", err)
-			}
-
-			type GoogleCredential struct {
-				ProjectID string `json:"project_id"`
-			}
-
-			// Define a variable to hold the data
-			var credential GoogleCredential
-			err = json.Unmarshal(file, &credential)
-			if err != nil {
-				fmt.Printf("Failed to decode JSON: %s\n", err)
-			}
-
-			googleProject := credential.ProjectID
-			googleCredentialJSON := file
-
-			ingester, err := google.NewIngester(context.Background(), googleCredentialJSON, s, googleProject, region, op...)
-
-			if err != nil {
-				fmt.Printf("%s\n", err)
-				os.Exit(1)
-			}
+	// Ingest supported services pricing data into the database
+	jobs := terracost.NewIngestJobs(flagProvider, services, []string{region})
+	results := terracost.IngestPricingJobs(context.Background(), backend, jobs, newIngester,
+		terracost.WithJobConcurrency(2),
+		terracost.WithRetries(3, 5*time.Second, time.Minute),
+	)
+
+	var failed bool
+	for _, res := range results {
+		if res.Err != nil {
+			fmt.Printf("[%s] Ingestion failed after %d attempts: %s\n", res.Job.Service, res.Attempts, res.Err)
+			failed = true
+			continue
+		}
+		fmt.Printf("[%s] Ingestion\n", res.Job.Service)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
 
-			err = terracost.IngestPricing(context.Background(), backend, ingester)
-			if err != nil {
-				fmt.Printf("%s\n", err)
-				os.Exit(1)
-			}
+func usageTemplate() {
+	var err error
+	if flagestimatePlan != "" {
+		var file *os.File
+		file, err = os.Open(flagestimatePlan)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
 		}
+		defer file.Close()
+		err = terracost.UsageTemplateFromPlan(os.Stdout, file)
+	} else {
+		err = terracost.UsageTemplateFromHCL(os.Stdout, nil, flagestimateHCL, terraform.HCLOptions{})
+	}
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
 	}
 }
 
@@ -199,7 +272,17 @@ func estimatePlan(path string, backend *mysql.Backend) {
 		os.Exit(1)
 	}
 
-	plan, err := terracost.EstimateTerraformPlan(context.Background(), backend, file, usage.Default)
+	if len(flagUsageScenarios) != 0 {
+		scenarios, err := terracost.EstimateTerraformPlanScenarios(context.Background(), backend, file, flagUsageScenarios)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+		scenariosDisplay(scenarios)
+		return
+	}
+
+	plan, err := terracost.EstimateTerraformPlan(context.Background(), backend, file, estimationUsage)
 	if err != nil {
 		fmt.Printf("%s\n", err)
 		os.Exit(1)
@@ -247,8 +330,25 @@ func estimateHCL(path string, provider string, backend *mysql.Backend) {
 		}
 	}
 	// terraform HCL directory
-	debugEnabled := true
-	planhcl, err := terracost.EstimateHCL(context.Background(), backend, nil, path, "", false, 0, usage.Default, debugEnabled, terraformProviderInitializer)
+	opts := []terracost.EstimateOption{
+		terracost.WithProviders(terraformProviderInitializer),
+		terracost.WithUsage(estimationUsage),
+		terracost.WithDebug(),
+	}
+	if flagNativeTerragrunt {
+		opts = append(opts, terracost.WithNativeTerragrunt())
+	}
+	if len(flagUsageScenarios) != 0 {
+		scenarios, err := terracost.EstimateStackScenarios(context.Background(), backend, path, flagUsageScenarios, opts...)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+		scenariosDisplay(scenarios)
+		return
+	}
+
+	planhcl, err := terracost.EstimateStack(context.Background(), backend, path, opts...)
 
 	if err != nil {
 		fmt.Printf("%s\n", err)
@@ -276,3 +376,23 @@ func estimateDisplay(resourceDiff *cost.Plan) {
 		fmt.Printf("%s: %s -> %s\n", res.Address, priorCost, plannedCost)
 	}
 }
+
+func scenariosDisplay(scenarios []cost.Scenario) {
+	for _, s := range scenarios {
+		total, err := s.PlannedCost()
+		if err != nil {
+			fmt.Printf("PlannedCost %s: %s\n", s.Name, err)
+			continue
+		}
+		fmt.Printf("Scenario %s: %s\n", s.Name, total)
+	}
+
+	ranges, err := cost.ComponentRanges(scenarios)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+	for _, cr := range ranges {
+		fmt.Printf("%s %s: %s - %s\n", cr.Address, cr.Label, cr.Min, cr.Max)
+	}
+}
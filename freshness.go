@@ -0,0 +1,81 @@
+package terracost
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cycloidio/terracost/backend"
+	"github.com/cycloidio/terracost/ingestion"
+	"github.com/cycloidio/terracost/query"
+)
+
+// FreshnessError is the pricing of a service of a provider on a region that was never
+// ingested or that was ingested longer ago than the maximum age asked
+type FreshnessError struct {
+	Provider string
+	Service  string
+	Location string
+
+	// LastIngestion is the last ingestion.Run that succeeded, nil if it was never ingested
+	LastIngestion *ingestion.Run
+}
+
+func (e *FreshnessError) Error() string {
+	if e.LastIngestion == nil {
+		return fmt.Sprintf("the pricing of %s %s on %q was never ingested", e.Provider, e.Service, e.Location)
+	}
+	return fmt.Sprintf("the pricing of %s %s on %q was last ingested on %s", e.Provider, e.Service, e.Location, e.LastIngestion.FinishedAt.Format(time.RFC3339))
+}
+
+// CheckFreshness returns a FreshnessError for each one of the providers, services and regions priced by the
+// queries that was never ingested or that was last ingested longer than maxAge ago. The components with a
+// product.Filter without them are ignored. The be must implement the backend.IngestionRecorder.
+func CheckFreshness(ctx context.Context, be backend.Backend, queries []query.Resource, maxAge time.Duration) ([]*FreshnessError, error) {
+	return checkFreshness(ctx, be, queryScopes(queries), maxAge)
+}
+
+// queryScopes returns the providers, services and regions of the product.Filter of the components of the queries
+func queryScopes(queries []query.Resource) map[ingestScope]struct{} {
+	scopes := make(map[ingestScope]struct{})
+	for _, q := range queries {
+		for _, c := range q.Components {
+			pf := c.ProductFilter
+			if pf == nil || pf.Provider == nil || pf.Service == nil || pf.Location == nil {
+				continue
+			}
+			scopes[ingestScope{provider: *pf.Provider, service: *pf.Service, location: *pf.Location}] = struct{}{}
+		}
+	}
+	return scopes
+}
+
+func checkFreshness(ctx context.Context, be backend.Backend, scopes map[ingestScope]struct{}, maxAge time.Duration) ([]*FreshnessError, error) {
+	runs := ingestionRepository(be)
+	if runs == nil {
+		return nil, fmt.Errorf("the backend does not record the ingestions")
+	}
+
+	errs := make([]*FreshnessError, 0)
+	for s := range scopes {
+		run, err := runs.LastSucceeded(ctx, s.provider, s.service, s.location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the last ingestion of %s %s on %q: %w", s.provider, s.service, s.location, err)
+		}
+		if run != nil && time.Since(run.FinishedAt) <= maxAge {
+			continue
+		}
+		errs = append(errs, &FreshnessError{
+			Provider:      s.provider,
+			Service:       s.service,
+			Location:      s.location,
+			LastIngestion: run,
+		})
+	}
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+	return errs, nil
+}
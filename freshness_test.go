@@ -0,0 +1,97 @@
+package terracost_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cycloidio/terracost"
+	"github.com/cycloidio/terracost/ingestion"
+	"github.com/cycloidio/terracost/mock"
+	"github.com/cycloidio/terracost/product"
+	"github.com/cycloidio/terracost/query"
+	"github.com/cycloidio/terracost/util"
+)
+
+func TestCheckFreshness(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ingestionRepo := mock.NewIngestionRepository(ctrl)
+	backend := &recorderBackend{Backend: mock.NewBackend(ctrl), ingestions: ingestionRepo}
+
+	component := func(service, location string) query.Component {
+		return query.Component{
+			ProductFilter: &product.Filter{
+				Provider: util.StringPtr("aws"),
+				Service:  util.StringPtr(service),
+				Location: util.StringPtr(location),
+			},
+		}
+	}
+	queries := []query.Resource{
+		{
+			Address: "aws_instance.web",
+			Components: []query.Component{
+				component("AmazonEC2", "eu-west-1"),
+				component("AmazonEC2", "eu-west-1"),
+				{ProductFilter: &product.Filter{Provider: util.StringPtr("aws")}},
+			},
+		},
+		{
+			Address:    "aws_db_instance.db",
+			Components: []query.Component{component("AmazonRDS", "eu-west-1")},
+		},
+		{
+			Address:    "aws_elasticache_cluster.cache",
+			Components: []query.Component{component("AmazonElastiCache", "eu-west-1")},
+		},
+	}
+
+	old := &ingestion.Run{FinishedAt: time.Now().Add(-48 * time.Hour)}
+	ingestionRepo.EXPECT().LastSucceeded(gomock.Any(), "aws", "AmazonEC2", "eu-west-1").Return(&ingestion.Run{FinishedAt: time.Now()}, nil)
+	ingestionRepo.EXPECT().LastSucceeded(gomock.Any(), "aws", "AmazonRDS", "eu-west-1").Return(old, nil)
+	ingestionRepo.EXPECT().LastSucceeded(gomock.Any(), "aws", "AmazonElastiCache", "eu-west-1").Return(nil, nil)
+
+	errs, err := terracost.CheckFreshness(context.Background(), backend, queries, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []*terracost.FreshnessError{
+		{Provider: "aws", Service: "AmazonElastiCache", Location: "eu-west-1"},
+		{Provider: "aws", Service: "AmazonRDS", Location: "eu-west-1", LastIngestion: old},
+	}, errs)
+}
+
+func TestCheckFreshness_NoRecorder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	queries := []query.Resource{
+		{
+			Address: "aws_instance.web",
+			Components: []query.Component{
+				{
+					ProductFilter: &product.Filter{
+						Provider: util.StringPtr("aws"),
+						Service:  util.StringPtr("AmazonEC2"),
+						Location: util.StringPtr("eu-west-1"),
+					},
+				},
+			},
+		},
+	}
+
+	_, err := terracost.CheckFreshness(context.Background(), mock.NewBackend(ctrl), queries, time.Hour)
+	assert.Error(t, err)
+}
+
+// recorderBackend is a backend.Backend that implements the backend.IngestionRecorder
+type recorderBackend struct {
+	*mock.Backend
+	ingestions ingestion.Repository
+}
+
+func (b *recorderBackend) Ingestions() ingestion.Repository { return b.ingestions }
@@ -323,3 +323,8 @@ func (ing *Ingester) fetchMachineTypes(ctx context.Context) <-chan *compute.Mach
 func (ing *Ingester) Err() error {
 	return ing.err
 }
+
+// Identity returns the provider, service and region of the ingestion.
+func (ing *Ingester) Identity() (string, string, string) {
+	return ProviderName, ing.service, ing.region
+}
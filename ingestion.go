@@ -3,8 +3,12 @@ package terracost
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/cycloidio/terracost/backend"
+	"github.com/cycloidio/terracost/event"
+	"github.com/cycloidio/terracost/ingestion"
+	"github.com/cycloidio/terracost/log"
 	"github.com/cycloidio/terracost/price"
 	"github.com/cycloidio/terracost/product"
 )
@@ -21,31 +25,262 @@ type Ingester interface {
 	Err() error
 }
 
+// Identifier is implemented by the Ingester that knows the provider, service and region it ingests, which are
+// recorded on its ingestion.Run. Otherwise they are the ones of the first product ingested.
+type Identifier interface {
+	Identity() (provider, service, region string)
+}
+
 // IngestPricing uses the Ingester to load the pricing data and stores it into the Backend.
-func IngestPricing(ctx context.Context, be backend.Backend, ingester Ingester) error {
+// The progress is sent to the event.Handler of the ctx, if any.
+func IngestPricing(ctx context.Context, be backend.Backend, ingester Ingester, opts ...IngestOption) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	skuProductID := make(map[string]product.ID)
+	o := newIngestOptions(opts...)
+	// The products that were left out would be pruned as if they were retired
+	if o.Partial && o.Prune != PruneNone {
+		return fmt.Errorf("a partial ingestion can not be pruned")
+	}
+
+	run := &ingestion.Run{StartedAt: time.Now(), Partial: o.Partial}
+	if id, ok := ingester.(Identifier); ok {
+		run.Provider, run.Service, run.Location = id.Identity()
+	}
+
+	// The run is recorded out of the transaction so the failed ones are also kept
+	var err error
+	runs := ingestionRepository(be)
+	if runs != nil {
+		run.ID, err = runs.Create(ctx, run)
+		if err != nil {
+			return fmt.Errorf("failed to create the ingestion run: %w", err)
+		}
+	}
+
+	if o.Transaction {
+		err = ingestPricingTransaction(ctx, be, ingester, run, o)
+	} else {
+		err = ingestPricing(ctx, be, ingester, run, o)
+	}
+
+	run.FinishedAt = time.Now()
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if runs != nil {
+		// The run is updated even if the ingestion was canceled
+		if uerr := runs.Update(context.WithoutCancel(ctx), run); uerr != nil && err == nil {
+			err = fmt.Errorf("failed to update the ingestion run: %w", uerr)
+		}
+	}
+
+	event.Emit(ctx, event.Event{Type: event.IngestionFinished, Err: err})
+	return err
+}
+
+// ingestionRepository returns the ingestion.Repository of the be, nil if it does not record the ingestions
+func ingestionRepository(be backend.Backend) ingestion.Repository {
+	if r, ok := be.(backend.IngestionRecorder); ok {
+		return r.Ingestions()
+	}
+	return nil
+}
+
+// ingestPricingTransaction ingests the pricing on a transaction of the be so the
+// estimations never see a partial ingestion, as it's only committed if it succeeds
+func ingestPricingTransaction(ctx context.Context, be backend.Backend, ingester Ingester, run *ingestion.Run, o *IngestOptions) error {
+	tb, ok := be.(backend.Transactioner)
+	if !ok {
+		return fmt.Errorf("the backend does not support transactions")
+	}
+	return tb.Transaction(ctx, func(be backend.Backend) error {
+		return ingestPricing(ctx, be, ingester, run, o)
+	})
+}
+
+// ingestScope is the provider, service and region of the products ingested
+type ingestScope struct {
+	provider string
+	service  string
+	location string
+}
+
+// ingestPricing stores the pricing of the ingester on the be with the counts on the run
+func ingestPricing(ctx context.Context, be backend.Backend, ingester Ingester, run *ingestion.Run, o *IngestOptions) error {
+	logger := log.FromContext(ctx)
+
+	var marker price.StaleMarker
+	if o.Prune == PruneMarkStale {
+		var ok bool
+		if marker, ok = be.Prices().(price.StaleMarker); !ok {
+			return fmt.Errorf("the price repository can not mark the prices as stale")
+		}
+	}
+
+	b := &ingestBatch{
+		be:           be,
+		run:          run,
+		skuProductID: make(map[string]product.ID),
+		seen:         make(map[ingestScope]map[product.ID][]price.ID),
+	}
+	// All the prices ingested are effective since the start of the ingestion
+	now := time.Now()
 	for pp := range ingester.Ingest(ctx, 8) {
-		if id, ok := skuProductID[pp.Product.SKU]; ok {
-			pp.Product.ID = id
-		} else {
-			var err error
-			pp.Product.ID, err = be.Products().Upsert(ctx, pp.Product)
+		if pp.EffectiveFrom.IsZero() {
+			pp.EffectiveFrom = now
+		}
+		b.pending = append(b.pending, pp)
+		if len(b.pending) >= o.BatchSize {
+			if err := b.flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	if err := b.flush(ctx); err != nil {
+		return err
+	}
+
+	if err := ingester.Err(); err != nil {
+		return fmt.Errorf("unexpected ingester error: %w", err)
+	}
+	logger.DebugContext(ctx, "Pricing ingested", "products", len(b.skuProductID))
+
+	if o.Prune == PruneNone {
+		return nil
+	}
+
+	// Only the scopes ingested are pruned, the products of them not
+	// ingested are the retired ones so all their prices are pruned
+	for scope, ingested := range b.seen {
+		prods, err := be.Products().Filter(ctx, &product.Filter{
+			Provider: &scope.provider,
+			Service:  &scope.service,
+			Location: &scope.location,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list the products of %s %s %q: %w", scope.provider, scope.service, scope.location, err)
+		}
+		for _, prod := range prods {
+			keep := ingested[prod.ID]
+			if marker != nil {
+				err = marker.MarkStaleByProductWithKeep(ctx, prod.ID, keep)
+			} else {
+				err = be.Prices().DeleteByProductWithKeep(ctx, prod.ID, keep)
+			}
 			if err != nil {
-				return fmt.Errorf("failed to upsert product (SKU=%q): %w", pp.Product.SKU, err)
+				return fmt.Errorf("failed to prune the prices of the product (SKU=%q): %w", prod.SKU, err)
 			}
-			skuProductID[pp.Product.SKU] = pp.Product.ID
 		}
+		logger.DebugContext(ctx, "Pricing pruned", "provider", scope.provider, "service", scope.service, "location", scope.location, "products", len(prods))
+	}
+	return nil
+}
+
+// ingestBatch stores the prices ingested in batches
+type ingestBatch struct {
+	be      backend.Backend
+	run     *ingestion.Run
+	pending []*price.WithProduct
+
+	skuProductID map[string]product.ID
+
+	// seen has the prices ingested of each product of each scope
+	seen map[ingestScope]map[product.ID][]price.ID
+}
+
+// flush stores the pending prices, and their products, with the bulk upsert
+// of the repositories if they have it or one by one otherwise
+func (b *ingestBatch) flush(ctx context.Context) error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	defer func() { b.pending = b.pending[:0] }()
+
+	if b.run.Provider == "" {
+		p := b.pending[0].Product
+		b.run.Provider, b.run.Service, b.run.Location = p.Provider, p.Service, p.Location
+	}
 
-		if _, err := be.Prices().Upsert(ctx, pp); err != nil {
-			return fmt.Errorf("failed to upsert price (SKU=%q): %w", pp.Product.SKU, err)
+	prods := make([]*product.Product, 0)
+	for _, pp := range b.pending {
+		if _, ok := b.skuProductID[pp.Product.SKU]; ok {
+			continue
 		}
+		// The ID is set to know it's already on the prods of the batch
+		b.skuProductID[pp.Product.SKU] = 0
+		prods = append(prods, pp.Product)
 	}
 
-	if err := ingester.Err(); err != nil {
-		return fmt.Errorf("unexpected ingester error: %w", err)
+	if len(prods) != 0 {
+		ids, err := b.upsertProducts(ctx, prods)
+		if err != nil {
+			return err
+		}
+		for i, prod := range prods {
+			b.skuProductID[prod.SKU] = ids[i]
+			event.Emit(ctx, event.Event{Type: event.ProductIngested, SKU: prod.SKU})
+		}
+		b.run.Products += len(prods)
+	}
+
+	for _, pp := range b.pending {
+		pp.Product.ID = b.skuProductID[pp.Product.SKU]
+	}
+
+	ids, err := b.upsertPrices(ctx, b.pending)
+	if err != nil {
+		return err
+	}
+	b.run.Prices += len(ids)
+	for i, pp := range b.pending {
+		event.Emit(ctx, event.Event{Type: event.PriceIngested, SKU: pp.Product.SKU})
+
+		scope := ingestScope{provider: pp.Product.Provider, service: pp.Product.Service, location: pp.Product.Location}
+		if _, ok := b.seen[scope]; !ok {
+			b.seen[scope] = make(map[product.ID][]price.ID)
+		}
+		b.seen[scope][pp.Product.ID] = append(b.seen[scope][pp.Product.ID], ids[i])
 	}
 	return nil
 }
+
+func (b *ingestBatch) upsertProducts(ctx context.Context, prods []*product.Product) ([]product.ID, error) {
+	if bu, ok := b.be.Products().(product.BulkUpserter); ok {
+		ids, err := bu.BulkUpsert(ctx, prods)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upsert %d products: %w", len(prods), err)
+		}
+		return ids, nil
+	}
+
+	ids := make([]product.ID, 0, len(prods))
+	for _, prod := range prods {
+		id, err := b.be.Products().Upsert(ctx, prod)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upsert product (SKU=%q): %w", prod.SKU, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (b *ingestBatch) upsertPrices(ctx context.Context, pps []*price.WithProduct) ([]price.ID, error) {
+	if bu, ok := b.be.Prices().(price.BulkUpserter); ok {
+		ids, err := bu.BulkUpsert(ctx, pps)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upsert %d prices: %w", len(pps), err)
+		}
+		return ids, nil
+	}
+
+	ids := make([]price.ID, 0, len(pps))
+	for _, pp := range pps {
+		id, err := b.be.Prices().Upsert(ctx, pp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upsert price (SKU=%q): %w", pp.Product.SKU, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
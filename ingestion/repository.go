@@ -0,0 +1,20 @@
+package ingestion
+
+import (
+	"context"
+)
+
+//go:generate mockgen -destination=../mock/ingestion_repository.go -mock_names=Repository=IngestionRepository -package mock github.com/cycloidio/terracost/ingestion Repository
+
+// Repository describes interactions with a storage system to deal with Run entries.
+type Repository interface {
+	// Create stores a new Run and returns its ID.
+	Create(ctx context.Context, r *Run) (ID, error)
+
+	// Update updates the Run with its ID.
+	Update(ctx context.Context, r *Run) error
+
+	// LastSucceeded returns the last Run of the provider, service and location that finished without error and
+	// is not Partial, nil if there is none.
+	LastSucceeded(ctx context.Context, provider, service, location string) (*Run, error)
+}
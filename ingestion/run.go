@@ -0,0 +1,35 @@
+package ingestion
+
+import "time"
+
+// ID represents the Run ID.
+type ID uint32
+
+// Run is an ingestion of the pricing of a service of a provider on a region.
+type Run struct {
+	ID       ID
+	Provider string
+	Service  string
+	Location string
+
+	StartedAt time.Time
+
+	// FinishedAt is zero while the Run is not finished
+	FinishedAt time.Time
+
+	// Products and Prices are the number of them stored
+	Products int
+	Prices   int
+
+	// Error is the error of the Run, empty if it succeeded
+	Error string
+
+	// Partial is set when only some of the products of the service were ingested,
+	// like with an ingestion filter, so the pricing of the service is not up to date
+	Partial bool
+}
+
+// Succeeded returns if the Run finished without error
+func (r *Run) Succeeded() bool {
+	return !r.FinishedAt.IsZero() && r.Error == ""
+}
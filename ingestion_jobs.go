@@ -0,0 +1,374 @@
+package terracost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cycloidio/terracost/backend"
+	"github.com/cycloidio/terracost/log"
+	"github.com/cycloidio/terracost/price"
+	"github.com/cycloidio/terracost/product"
+	"github.com/cycloidio/terracost/query"
+	"github.com/cycloidio/terracost/usage"
+)
+
+// IngestJob is the ingestion of the pricing of a service of a provider on a region
+type IngestJob struct {
+	Provider string
+	Service  string
+	Region   string
+
+	// Filters are the product.Filter of the components that need the pricing of the job, only
+	// the products that match any of them have to be ingested (see IngestionFilter), all of them
+	// if there are none. The jobs with Filters can not be ingested with WithPrune.
+	Filters []*product.Filter
+}
+
+func (j IngestJob) String() string {
+	return fmt.Sprintf("%s %s on %q", j.Provider, j.Service, j.Region)
+}
+
+// NewIngestJobs returns the IngestJob of each one of the services of the provider on each one of the regions
+func NewIngestJobs(provider string, services, regions []string) []IngestJob {
+	jobs := make([]IngestJob, 0, len(services)*len(regions))
+	for _, s := range services {
+		for _, r := range regions {
+			jobs = append(jobs, IngestJob{Provider: provider, Service: s, Region: r})
+		}
+	}
+	return jobs
+}
+
+// IngestionFilter returns a function that returns true for the pricing of the products that match any of the
+// Filters of the job, or for all of them if it has none. It's meant to be the ingestion filter of the Ingester
+// of the job. Like the backends, the values of the attributes are compared ignoring the case.
+func (j IngestJob) IngestionFilter() func(pp *price.WithProduct) bool {
+	if len(j.Filters) == 0 {
+		return func(*price.WithProduct) bool { return true }
+	}
+
+	matchers := make([]func(p *product.Product) bool, 0, len(j.Filters))
+	for _, f := range j.Filters {
+		matchers = append(matchers, productMatcher(f))
+	}
+	return func(pp *price.WithProduct) bool {
+		if pp.Product == nil {
+			return false
+		}
+		for _, m := range matchers {
+			if m(pp.Product) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// productMatcher returns a function that returns true if the product matches the SKU, the family and the
+// attributes of the f, the rest of it is the scope of the job. The invalid regular expressions match any value.
+func productMatcher(f *product.Filter) func(p *product.Product) bool {
+	type attrMatcher struct {
+		key   string
+		value *string
+		re    *regexp.Regexp
+	}
+	attrs := make([]attrMatcher, 0, len(f.AttributeFilters))
+	for _, af := range f.AttributeFilters {
+		am := attrMatcher{key: af.Key, value: af.Value}
+		if af.Value == nil && af.ValueRegex != nil {
+			re, err := regexp.Compile("(?i)" + *af.ValueRegex)
+			if err != nil {
+				continue
+			}
+			am.re = re
+		}
+		attrs = append(attrs, am)
+	}
+
+	return func(p *product.Product) bool {
+		if f.SKU != nil && !strings.EqualFold(*f.SKU, p.SKU) {
+			return false
+		}
+		if f.Family != nil && !strings.EqualFold(*f.Family, p.Family) {
+			return false
+		}
+		for _, am := range attrs {
+			v := p.Attributes[am.key]
+			if am.value != nil && !strings.EqualFold(*am.value, v) {
+				return false
+			}
+			if am.re != nil && !am.re.MatchString(v) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// QueryIngestJobs returns the IngestJob of the providers, services and regions of the product.Filter of the
+// components of the queries, sorted, with the filters of them. The components with a product.Filter without
+// them are ignored.
+func QueryIngestJobs(queries []query.Resource) []IngestJob {
+	filters := make(map[ingestScope][]*product.Filter)
+	addQueryFilters(filters, make(map[string]struct{}), queries)
+	return scopeIngestJobs(filters)
+}
+
+// addQueryFilters adds the product.Filter of the components of the queries to the filters of their provider,
+// service and region, the ones on seen are skipped so they are not duplicated
+func addQueryFilters(filters map[ingestScope][]*product.Filter, seen map[string]struct{}, queries []query.Resource) {
+	for _, q := range queries {
+		for _, c := range q.Components {
+			pf := c.ProductFilter
+			if pf == nil || pf.Provider == nil || pf.Service == nil || pf.Location == nil {
+				continue
+			}
+			key := productFilterKey(pf)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			s := ingestScope{provider: *pf.Provider, service: *pf.Service, location: *pf.Location}
+			filters[s] = append(filters[s], pf)
+		}
+	}
+}
+
+// productFilterKey returns a key that is the same for the equal product.Filter
+func productFilterKey(f *product.Filter) string {
+	str := func(s *string) string {
+		if s == nil {
+			return "\x00"
+		}
+		return *s
+	}
+	var b strings.Builder
+	for _, s := range []*string{f.Provider, f.SKU, f.Service, f.Family, f.Location} {
+		fmt.Fprintf(&b, "%q;", str(s))
+	}
+	for _, af := range f.AttributeFilters {
+		fmt.Fprintf(&b, "%q=%q~%q;", af.Key, str(af.Value), str(af.ValueRegex))
+	}
+	return b.String()
+}
+
+// PlanIngestJobs reads a Terraform plan from the provided io.Reader and returns the IngestJob of the pricing
+// needed to estimate it with the opts, so only them can be ingested with IngestPricingJobs
+func PlanIngestJobs(ctx context.Context, plan io.Reader, opts ...EstimateOption) ([]IngestJob, error) {
+	o := newEstimateOptions(opts...)
+	filters := o.collectFilters()
+	if _, err := estimatePlan(ctx, nil, plan, []usage.Scenario{{Usage: o.Usage}}, o); err != nil {
+		return nil, err
+	}
+	return scopeIngestJobs(filters), nil
+}
+
+// StackIngestJobs reads the Terraform modules, or the Terragrunt units, of the stackPath and returns the IngestJob
+// of the pricing needed to estimate them with the opts. With WithBestEffort the IngestJob of the modules that could
+// be read are returned with a ModuleErrors of the rest.
+func StackIngestJobs(ctx context.Context, stackPath string, opts ...EstimateOption) ([]IngestJob, error) {
+	o := newEstimateOptions(opts...)
+	filters := o.collectFilters()
+	if _, err := estimateHCL(ctx, nil, stackPath, []usage.Scenario{{Usage: o.Usage}}, o); err != nil {
+		var merrs ModuleErrors
+		if errors.As(err, &merrs) {
+			return scopeIngestJobs(filters), err
+		}
+		return nil, err
+	}
+	return scopeIngestJobs(filters), nil
+}
+
+// collectFilters makes the estimations only collect the product.Filter of their queries
+// on the returned map, which must not be read until they finish
+func (o *EstimateOptions) collectFilters() map[ingestScope][]*product.Filter {
+	var mux sync.Mutex
+	filters := make(map[ingestScope][]*product.Filter)
+	seen := make(map[string]struct{})
+	o.collectQueries = func(queries []query.Resource) {
+		mux.Lock()
+		defer mux.Unlock()
+		addQueryFilters(filters, seen, queries)
+	}
+	return filters
+}
+
+// scopeIngestJobs returns the IngestJob of the scopes with their filters, the ones without location (like the AWS
+// data transfer between regions) are ingested on each one of the regions of the provider as they can't be ingested
+// without it
+func scopeIngestJobs(scopes map[ingestScope][]*product.Filter) []IngestJob {
+	sorted := make([]ingestScope, 0, len(scopes))
+	for s := range scopes {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].provider != sorted[j].provider {
+			return sorted[i].provider < sorted[j].provider
+		}
+		if sorted[i].service != sorted[j].service {
+			return sorted[i].service < sorted[j].service
+		}
+		return sorted[i].location < sorted[j].location
+	})
+
+	regions := make(map[string][]string)
+	for _, s := range sorted {
+		if s.location != "" && !slices.Contains(regions[s.provider], s.location) {
+			regions[s.provider] = append(regions[s.provider], s.location)
+		}
+	}
+
+	uniq := make(map[ingestScope]*IngestJob, len(scopes))
+	jobs := make([]*IngestJob, 0, len(scopes))
+	add := func(s ingestScope, filters []*product.Filter) {
+		j, ok := uniq[s]
+		if !ok {
+			j = &IngestJob{Provider: s.provider, Service: s.service, Region: s.location}
+			uniq[s] = j
+			jobs = append(jobs, j)
+		}
+		j.Filters = append(j.Filters, filters...)
+	}
+	for _, s := range sorted {
+		if s.location != "" || len(regions[s.provider]) == 0 {
+			add(s, scopes[s])
+			continue
+		}
+		for _, r := range regions[s.provider] {
+			add(ingestScope{provider: s.provider, service: s.service, location: r}, scopes[s])
+		}
+	}
+
+	res := make([]IngestJob, 0, len(jobs))
+	for _, j := range jobs {
+		res = append(res, *j)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Provider != res[j].Provider {
+			return res[i].Provider < res[j].Provider
+		}
+		if res[i].Service != res[j].Service {
+			return res[i].Service < res[j].Service
+		}
+		return res[i].Region < res[j].Region
+	})
+	return res
+}
+
+// IngesterFactory returns the Ingester of the job
+type IngesterFactory func(ctx context.Context, job IngestJob) (Ingester, error)
+
+// IngestResult is the result of an IngestJob
+type IngestResult struct {
+	Job IngestJob
+
+	// Attempts is the number of times the job was run
+	Attempts int
+
+	// Err is the error of the last attempt, nil if it succeeded
+	Err error
+}
+
+// IngestPricingJobs runs the IngestPricing of the Ingester of each one of the jobs, created with the newIngester, with
+// the JobConcurrency of the opts. The jobs that fail are retried up to the MaxAttempts of the opts with an exponential
+// backoff. The jobs that are not started when the ctx is done are not run and their IngestResult has the cause of the
+// ctx as Err. It returns the IngestResult of each one of the jobs in the same order.
+func IngestPricingJobs(ctx context.Context, be backend.Backend, jobs []IngestJob, newIngester IngesterFactory, opts ...IngestOption) []IngestResult {
+	o := newIngestOptions(opts...)
+	results := make([]IngestResult, len(jobs))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, o.JobConcurrency)
+	for i, job := range jobs {
+		// The jobs that are not started when the ctx is done are not run
+		if !acquire(ctx, sem) {
+			results[i] = IngestResult{Job: job, Err: context.Cause(ctx)}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, job IngestJob) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = runIngestJob(ctx, be, job, newIngester, o, opts)
+		}(i, job)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// acquire waits for a slot on the sem, it returns false if the ctx is done before
+func acquire(ctx context.Context, sem chan struct{}) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// runIngestJob runs the job retrying it while it fails with a retryable error
+func runIngestJob(ctx context.Context, be backend.Backend, job IngestJob, newIngester IngesterFactory, o *IngestOptions, opts []IngestOption) IngestResult {
+	logger := log.FromContext(ctx)
+	res := IngestResult{Job: job}
+	// The products that do not match the filters are not ingested
+	// so all of them would be pruned as if they were retired
+	if len(job.Filters) != 0 && o.Prune != PruneNone {
+		res.Err = fmt.Errorf("the ingestion of %s with filters can not be pruned", job)
+		return res
+	}
+	backoff := o.RetryBackoff
+	for {
+		res.Attempts++
+		res.Err = ingestJob(ctx, be, job, newIngester, opts)
+		if res.Err == nil || res.Attempts >= o.MaxAttempts || ctx.Err() != nil || !o.Retryable(res.Err) {
+			break
+		}
+
+		logger.WarnContext(ctx, "Ingestion failed, retrying", "job", job.String(), "attempt", res.Attempts, "backoff", backoff, "error", res.Err)
+		select {
+		case <-ctx.Done():
+			res.Err = context.Cause(ctx)
+			return res
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if o.MaxRetryBackoff > 0 && backoff > o.MaxRetryBackoff {
+			backoff = o.MaxRetryBackoff
+		}
+	}
+	return res
+}
+
+func ingestJob(ctx context.Context, be backend.Backend, job IngestJob, newIngester IngesterFactory, opts []IngestOption) error {
+	ingester, err := newIngester(ctx, job)
+	if err != nil {
+		return fmt.Errorf("failed to create the ingester of %s: %w", job, err)
+	}
+	// Only the products of the filters are ingested
+	if len(job.Filters) != 0 {
+		opts = append([]IngestOption{WithPartial()}, opts...)
+	}
+	if err := IngestPricing(ctx, be, ingester, opts...); err != nil {
+		return fmt.Errorf("failed to ingest %s: %w", job, err)
+	}
+	return nil
+}
+
+// retryable is the default IngestOptions.Retryable, all the errors but the cancellations are retried
+func retryable(err error) bool {
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
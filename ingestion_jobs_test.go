@@ -0,0 +1,354 @@
+package terracost
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cycloidio/terracost/ingestion"
+	"github.com/cycloidio/terracost/mock"
+	"github.com/cycloidio/terracost/price"
+	"github.com/cycloidio/terracost/product"
+	"github.com/cycloidio/terracost/query"
+	"github.com/cycloidio/terracost/util"
+)
+
+func TestNewIngestJobs(t *testing.T) {
+	jobs := NewIngestJobs("aws", []string{"AmazonEC2", "AmazonRDS"}, []string{"eu-west-1", "us-east-1"})
+	assert.Equal(t, []IngestJob{
+		{Provider: "aws", Service: "AmazonEC2", Region: "eu-west-1"},
+		{Provider: "aws", Service: "AmazonEC2", Region: "us-east-1"},
+		{Provider: "aws", Service: "AmazonRDS", Region: "eu-west-1"},
+		{Provider: "aws", Service: "AmazonRDS", Region: "us-east-1"},
+	}, jobs)
+}
+
+func TestQueryIngestJobs(t *testing.T) {
+	filter := func(provider, service, location string) *product.Filter {
+		return &product.Filter{
+			Provider: util.StringPtr(provider),
+			Service:  util.StringPtr(service),
+			Location: util.StringPtr(location),
+		}
+	}
+	compute := func(location string) *product.Filter {
+		f := filter("aws", "AmazonEC2", location)
+		f.Family = util.StringPtr("Compute Instance")
+		return f
+	}
+	storage := filter("aws", "AmazonEC2", "us-east-1")
+	storage.Family = util.StringPtr("Storage")
+	data := filter("aws", "AWSDataTransfer", "")
+	vm := filter("azurerm", "Virtual Machines", "westeurope")
+	queries := []query.Resource{
+		{
+			Address: "aws_instance.a",
+			Components: []query.Component{
+				{Name: "Compute", ProductFilter: compute("us-east-1")},
+				{Name: "Storage", ProductFilter: storage},
+				{Name: "Data", ProductFilter: data},
+				{Name: "Unknown", ProductFilter: &product.Filter{Provider: util.StringPtr("aws")}},
+			},
+		},
+		{
+			Address: "aws_instance.b",
+			Components: []query.Component{
+				{Name: "Compute", ProductFilter: compute("eu-west-1")},
+				{Name: "Data", ProductFilter: filter("aws", "AWSDataTransfer", "")},
+			},
+		},
+		{
+			Address: "aws_instance.c",
+			Components: []query.Component{
+				{Name: "Compute", ProductFilter: compute("us-east-1")},
+			},
+		},
+		{
+			Address: "azurerm_linux_virtual_machine.d",
+			Components: []query.Component{
+				{Name: "Compute", ProductFilter: vm},
+			},
+		},
+	}
+
+	assert.Equal(t, []IngestJob{
+		{Provider: "aws", Service: "AWSDataTransfer", Region: "eu-west-1", Filters: []*product.Filter{data}},
+		{Provider: "aws", Service: "AWSDataTransfer", Region: "us-east-1", Filters: []*product.Filter{data}},
+		{Provider: "aws", Service: "AmazonEC2", Region: "eu-west-1", Filters: []*product.Filter{compute("eu-west-1")}},
+		{Provider: "aws", Service: "AmazonEC2", Region: "us-east-1", Filters: []*product.Filter{compute("us-east-1"), storage}},
+		{Provider: "azurerm", Service: "Virtual Machines", Region: "westeurope", Filters: []*product.Filter{vm}},
+	}, QueryIngestJobs(queries))
+}
+
+func TestIngestJob_IngestionFilter(t *testing.T) {
+	pp := func(family, sku string, attrs map[string]string) *price.WithProduct {
+		return &price.WithProduct{Product: &product.Product{Family: family, SKU: sku, Attributes: attrs}}
+	}
+	m5 := pp("Compute Instance", "SKU1", map[string]string{"instanceType": "m5.xlarge", "tenancy": "Shared"})
+	t3 := pp("Compute Instance", "SKU2", map[string]string{"instanceType": "t3.micro", "tenancy": "Shared"})
+	gp2 := pp("Storage", "SKU3", map[string]string{"volumeApiName": "gp2"})
+
+	t.Run("NoFilters", func(t *testing.T) {
+		filter := IngestJob{Provider: "aws", Service: "AmazonEC2", Region: "eu-west-1"}.IngestionFilter()
+		assert.True(t, filter(m5))
+		assert.True(t, filter(gp2))
+	})
+	t.Run("Filters", func(t *testing.T) {
+		filter := IngestJob{
+			Provider: "aws", Service: "AmazonEC2", Region: "eu-west-1",
+			Filters: []*product.Filter{
+				{
+					Provider: util.StringPtr("aws"),
+					Location: util.StringPtr("eu-west-1"),
+					Family:   util.StringPtr("compute instance"),
+					AttributeFilters: []*product.AttributeFilter{
+						{Key: "instanceType", Value: util.StringPtr("M5.XLARGE")},
+						{Key: "tenancy", ValueRegex: util.StringPtr("^shared$")},
+					},
+				},
+				{SKU: util.StringPtr("SKU3")},
+			},
+		}.IngestionFilter()
+		assert.True(t, filter(m5))
+		assert.False(t, filter(t3))
+		assert.True(t, filter(gp2))
+		assert.False(t, filter(&price.WithProduct{}))
+	})
+}
+
+func TestPlanIngestJobs(t *testing.T) {
+	f, err := os.Open("testdata/aws/asg-plan.json")
+	require.NoError(t, err)
+	defer f.Close()
+
+	jobs, err := PlanIngestJobs(context.Background(), f)
+	require.NoError(t, err)
+	assert.Equal(t, []IngestJob{
+		{Provider: "aws", Service: "AmazonCloudWatch", Region: "eu-west-1"},
+		{Provider: "aws", Service: "AmazonEC2", Region: "eu-west-1"},
+	}, jobScopes(t, jobs))
+}
+
+// jobScopes returns the jobs without their filters, checking that all of them have some
+func jobScopes(t *testing.T, jobs []IngestJob) []IngestJob {
+	scopes := make([]IngestJob, 0, len(jobs))
+	for _, j := range jobs {
+		assert.NotEmpty(t, j.Filters, j.String())
+		scopes = append(scopes, IngestJob{Provider: j.Provider, Service: j.Service, Region: j.Region})
+	}
+	return scopes
+}
+
+func TestStackIngestJobs(t *testing.T) {
+	jobs, err := StackIngestJobs(context.Background(), "testdata/aws/stack-expansion")
+	require.NoError(t, err)
+	assert.Equal(t, []IngestJob{
+		{Provider: "aws", Service: "AWSDataTransfer", Region: "eu-west-1"},
+		{Provider: "aws", Service: "AmazonEC2", Region: "eu-west-1"},
+		{Provider: "aws", Service: "AmazonS3", Region: "eu-west-1"},
+	}, jobScopes(t, jobs))
+
+	jobs, err = StackIngestJobs(context.Background(), "testdata/aws/terragrunt-native", WithNativeTerragrunt(), WithConcurrency(4))
+	require.NoError(t, err)
+	assert.Equal(t, []IngestJob{
+		{Provider: "aws", Service: "AmazonEC2", Region: "eu-west-1"},
+	}, jobScopes(t, jobs))
+}
+
+func TestIngestPricingJobs(t *testing.T) {
+	errTransient := errors.New("connection reset")
+	errPermanent := errors.New("unsupported service")
+
+	// newIngester returns an Ingester of the jobs that fails with the errors
+	// of the job in order and then succeeds
+	newIngester := func(ctrl *gomock.Controller, errs map[string][]error) IngesterFactory {
+		var mux sync.Mutex
+		return func(_ context.Context, job IngestJob) (Ingester, error) {
+			mux.Lock()
+			defer mux.Unlock()
+
+			var err error
+			if jerrs := errs[job.Service]; len(jerrs) > 0 {
+				err, errs[job.Service] = jerrs[0], jerrs[1:]
+			}
+
+			ingester := mock.NewIngester(ctrl)
+			ingester.EXPECT().Ingest(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ int) <-chan *price.WithProduct {
+				results := make(chan *price.WithProduct)
+				close(results)
+				return results
+			})
+			ingester.EXPECT().Err().Return(err)
+			return ingester, nil
+		}
+	}
+
+	newBackend := func(ctrl *gomock.Controller) *mock.Backend {
+		return mock.NewBackend(ctrl)
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		jobs := NewIngestJobs("aws", []string{"AmazonEC2", "AmazonRDS", "AmazonS3"}, []string{"eu-west-1"})
+		results := IngestPricingJobs(context.Background(), newBackend(ctrl), jobs, newIngester(ctrl, nil), WithJobConcurrency(2))
+
+		require.Len(t, results, 3)
+		for i, res := range results {
+			assert.Equal(t, jobs[i], res.Job)
+			assert.Equal(t, 1, res.Attempts)
+			assert.NoError(t, res.Err)
+		}
+	})
+	t.Run("Retries", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		jobs := NewIngestJobs("aws", []string{"AmazonEC2", "AmazonRDS", "AmazonS3"}, []string{"eu-west-1"})
+		errs := map[string][]error{
+			"AmazonEC2": {errTransient},
+			"AmazonRDS": {errTransient, errTransient, errTransient},
+			"AmazonS3":  {errPermanent},
+		}
+		results := IngestPricingJobs(context.Background(), newBackend(ctrl), jobs, newIngester(ctrl, errs),
+			WithJobConcurrency(3),
+			WithRetries(3, time.Millisecond, 2*time.Millisecond),
+			WithRetryable(func(err error) bool { return errors.Is(err, errTransient) }),
+		)
+
+		require.Len(t, results, 3)
+		assert.Equal(t, 2, results[0].Attempts)
+		assert.NoError(t, results[0].Err)
+
+		assert.Equal(t, 3, results[1].Attempts)
+		assert.ErrorIs(t, results[1].Err, errTransient)
+
+		assert.Equal(t, 1, results[2].Attempts)
+		assert.ErrorIs(t, results[2].Err, errPermanent)
+	})
+	t.Run("IngesterError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		jobs := NewIngestJobs("aws", []string{"AmazonEC2"}, []string{"eu-west-1"})
+		results := IngestPricingJobs(context.Background(), newBackend(ctrl), jobs, func(_ context.Context, _ IngestJob) (Ingester, error) {
+			return nil, errPermanent
+		}, WithRetries(2, time.Millisecond, 0))
+
+		require.Len(t, results, 1)
+		assert.Equal(t, 2, results[0].Attempts)
+		assert.ErrorIs(t, results[0].Err, errPermanent)
+	})
+	t.Run("Canceled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		jobs := NewIngestJobs("aws", []string{"AmazonEC2"}, []string{"eu-west-1"})
+		results := IngestPricingJobs(ctx, newBackend(ctrl), jobs, func(_ context.Context, _ IngestJob) (Ingester, error) {
+			cancel()
+			return nil, errTransient
+		}, WithRetries(3, time.Hour, 0))
+
+		require.Len(t, results, 1)
+		assert.Equal(t, 1, results[0].Attempts)
+		assert.ErrorIs(t, results[0].Err, errTransient)
+	})
+	t.Run("CanceledNotStarted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// The first job blocks the only slot until the ctx is canceled
+		// so the other jobs are never started
+		errCause := errors.New("shutting down")
+		ctx, cancel := context.WithCancelCause(context.Background())
+		var calls []string
+		jobs := NewIngestJobs("aws", []string{"AmazonEC2", "AmazonRDS", "AmazonS3"}, []string{"eu-west-1"})
+		results := IngestPricingJobs(ctx, newBackend(ctrl), jobs, func(ctx context.Context, job IngestJob) (Ingester, error) {
+			calls = append(calls, job.Service)
+			cancel(errCause)
+			<-ctx.Done()
+			return nil, context.Cause(ctx)
+		}, WithJobConcurrency(1), WithRetries(3, time.Hour, 0))
+
+		require.Len(t, results, 3)
+		assert.Equal(t, []string{"AmazonEC2"}, calls)
+
+		assert.Equal(t, 1, results[0].Attempts)
+		assert.ErrorIs(t, results[0].Err, errCause)
+		for _, res := range results[1:] {
+			assert.Equal(t, 0, res.Attempts)
+			assert.ErrorIs(t, res.Err, errCause)
+		}
+	})
+	t.Run("CanceledBefore", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		jobs := NewIngestJobs("aws", []string{"AmazonEC2", "AmazonRDS"}, []string{"eu-west-1"})
+		results := IngestPricingJobs(ctx, newBackend(ctrl), jobs, func(_ context.Context, _ IngestJob) (Ingester, error) {
+			t.Fatal("the ingester of a job that was not started was created")
+			return nil, nil
+		})
+
+		require.Len(t, results, 2)
+		for i, res := range results {
+			assert.Equal(t, jobs[i], res.Job)
+			assert.Equal(t, 0, res.Attempts)
+			assert.ErrorIs(t, res.Err, context.Canceled)
+		}
+	})
+	t.Run("Filtered", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// The run of a job with filters is partial so the
+		// pricing of the job is not considered up to date
+		ingestionRepo := mock.NewIngestionRepository(ctrl)
+		var run *ingestion.Run
+		ingestionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(ingestion.ID(1), nil)
+		ingestionRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, r *ingestion.Run) error {
+			run = r
+			return nil
+		})
+
+		jobs := []IngestJob{{
+			Provider: "aws", Service: "AmazonEC2", Region: "eu-west-1",
+			Filters: []*product.Filter{{Family: util.StringPtr("Compute Instance")}},
+		}}
+		results := IngestPricingJobs(context.Background(), &recorderBackend{Backend: newBackend(ctrl), ingestions: ingestionRepo}, jobs, newIngester(ctrl, nil))
+
+		require.Len(t, results, 1)
+		require.NoError(t, results[0].Err)
+		require.NotNil(t, run)
+		assert.True(t, run.Partial)
+		assert.True(t, run.Succeeded())
+	})
+	t.Run("PruneFiltered", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		jobs := []IngestJob{{
+			Provider: "aws", Service: "AmazonEC2", Region: "eu-west-1",
+			Filters: []*product.Filter{{Family: util.StringPtr("Compute Instance")}},
+		}}
+		results := IngestPricingJobs(context.Background(), newBackend(ctrl), jobs, func(_ context.Context, _ IngestJob) (Ingester, error) {
+			t.Fatal("the ingester must not be created")
+			return nil, nil
+		}, WithPrune(PruneDelete))
+
+		require.Len(t, results, 1)
+		assert.Equal(t, 0, results[0].Attempts)
+		assert.Error(t, results[0].Err)
+	})
+}
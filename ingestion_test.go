@@ -2,12 +2,17 @@ package terracost
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/cycloidio/terracost/backend"
+	"github.com/cycloidio/terracost/event"
+	"github.com/cycloidio/terracost/ingestion"
 	"github.com/cycloidio/terracost/mock"
 	"github.com/cycloidio/terracost/price"
 	"github.com/cycloidio/terracost/product"
@@ -48,6 +53,7 @@ func TestIngestPricing(t *testing.T) {
 
 	productRepo := mock.NewProductRepository(ctrl)
 	priceRepo := mock.NewPriceRepository(ctrl)
+	ingestionRepo := mock.NewIngestionRepository(ctrl)
 	backend := mock.NewBackend(ctrl)
 	ingester := mock.NewIngester(ctrl)
 
@@ -70,6 +76,268 @@ func TestIngestPricing(t *testing.T) {
 	priceRepo.EXPECT().Upsert(gomock.Any(), priceProducts[0]).Return(price.ID(1), nil)
 	priceRepo.EXPECT().Upsert(gomock.Any(), priceProducts[1]).Return(price.ID(2), nil)
 
-	err := IngestPricing(context.Background(), backend, ingester)
+	var types []event.Type
+	ctx := event.NewContext(context.Background(), event.HandlerFunc(func(_ context.Context, e event.Event) {
+		types = append(types, e.Type)
+	}))
+
+	var run *ingestion.Run
+	ingestionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(ingestion.ID(1), nil)
+	ingestionRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, r *ingestion.Run) error {
+		run = r
+		return nil
+	})
+
+	err := IngestPricing(ctx, &recorderBackend{Backend: backend, ingestions: ingestionRepo}, ingester)
 	require.NoError(t, err)
+	assert.Equal(t, []event.Type{event.ProductIngested, event.PriceIngested, event.PriceIngested, event.IngestionFinished}, types)
+
+	require.NotNil(t, run)
+	assert.Equal(t, ingestion.ID(1), run.ID)
+	assert.Equal(t, "provider", run.Provider)
+	assert.Equal(t, "service", run.Service)
+	assert.Equal(t, "location", run.Location)
+	assert.Equal(t, 1, run.Products)
+	assert.Equal(t, 2, run.Prices)
+	assert.True(t, run.Succeeded())
+}
+
+// staleMarkerRepository is a price.Repository that implements the price.StaleMarker
+type staleMarkerRepository struct {
+	*mock.PriceRepository
+	marked map[product.ID][]price.ID
+}
+
+func (r *staleMarkerRepository) MarkStaleByProductWithKeep(_ context.Context, productID product.ID, keep []price.ID) error {
+	r.marked[productID] = keep
+	return nil
+}
+
+func TestIngestPricing_Prune(t *testing.T) {
+	prod := &product.Product{
+		Provider: "provider",
+		SKU:      "prod1",
+		Service:  "service",
+		Location: "location",
+	}
+	pp := &price.WithProduct{
+		Product: prod,
+		Price: price.Price{
+			Unit:     "Hrs",
+			Currency: "USD",
+			Value:    decimal.RequireFromString("1.23"),
+		},
+	}
+
+	setup := func(t *testing.T, ingErr error) (*mock.Backend, *mock.ProductRepository, *mock.PriceRepository, *mock.Ingester) {
+		ctrl := gomock.NewController(t)
+
+		productRepo := mock.NewProductRepository(ctrl)
+		priceRepo := mock.NewPriceRepository(ctrl)
+		backend := mock.NewBackend(ctrl)
+		ingester := mock.NewIngester(ctrl)
+
+		backend.EXPECT().Products().AnyTimes().Return(productRepo)
+		ingester.EXPECT().Ingest(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, chSize int) <-chan *price.WithProduct {
+			results := make(chan *price.WithProduct, chSize)
+			results <- pp
+			close(results)
+			return results
+		})
+		ingester.EXPECT().Err().Return(ingErr)
+
+		productRepo.EXPECT().Upsert(gomock.Any(), prod).Return(product.ID(1), nil)
+		priceRepo.EXPECT().Upsert(gomock.Any(), pp).Return(price.ID(10), nil)
+		return backend, productRepo, priceRepo, ingester
+	}
+
+	t.Run("Delete", func(t *testing.T) {
+		backend, productRepo, priceRepo, ingester := setup(t, nil)
+		backend.EXPECT().Prices().AnyTimes().Return(priceRepo)
+
+		productRepo.EXPECT().Filter(gomock.Any(), &product.Filter{
+			Provider: &prod.Provider,
+			Service:  &prod.Service,
+			Location: &prod.Location,
+		}).Return([]*product.Product{{ID: 1, SKU: "prod1"}, {ID: 2, SKU: "retired"}}, nil)
+		priceRepo.EXPECT().DeleteByProductWithKeep(gomock.Any(), product.ID(1), []price.ID{10}).Return(nil)
+		priceRepo.EXPECT().DeleteByProductWithKeep(gomock.Any(), product.ID(2), nil).Return(nil)
+
+		err := IngestPricing(context.Background(), backend, ingester, WithPrune(PruneDelete))
+		require.NoError(t, err)
+	})
+
+	t.Run("MarkStale", func(t *testing.T) {
+		backend, productRepo, priceRepo, ingester := setup(t, nil)
+		repo := &staleMarkerRepository{PriceRepository: priceRepo, marked: make(map[product.ID][]price.ID)}
+		backend.EXPECT().Prices().AnyTimes().Return(repo)
+
+		productRepo.EXPECT().Filter(gomock.Any(), gomock.Any()).Return([]*product.Product{{ID: 1, SKU: "prod1"}, {ID: 2, SKU: "retired"}}, nil)
+
+		err := IngestPricing(context.Background(), backend, ingester, WithPrune(PruneMarkStale))
+		require.NoError(t, err)
+		assert.Equal(t, map[product.ID][]price.ID{1: {10}, 2: nil}, repo.marked)
+	})
+
+	t.Run("IngesterError", func(t *testing.T) {
+		backend, _, priceRepo, ingester := setup(t, errors.New("network"))
+		backend.EXPECT().Prices().AnyTimes().Return(priceRepo)
+
+		err := IngestPricing(context.Background(), backend, ingester, WithPrune(PruneDelete))
+		assert.Error(t, err)
+	})
+}
+
+// recorderBackend is a backend.Backend that implements the backend.IngestionRecorder
+type recorderBackend struct {
+	*mock.Backend
+	ingestions ingestion.Repository
+}
+
+func (b *recorderBackend) Ingestions() ingestion.Repository { return b.ingestions }
+
+// transactionBackend is a backend.Backend that implements the backend.Transactioner
+type transactionBackend struct {
+	*mock.Backend
+	committed bool
+}
+
+func (b *transactionBackend) Transaction(_ context.Context, fn func(be backend.Backend) error) error {
+	if err := fn(b.Backend); err != nil {
+		return err
+	}
+	b.committed = true
+	return nil
+}
+
+func TestIngestPricing_Transaction(t *testing.T) {
+	prod := &product.Product{Provider: "provider", SKU: "prod1"}
+	pp := &price.WithProduct{Product: prod, Price: price.Price{Unit: "Hrs", Currency: "USD"}}
+
+	setup := func(t *testing.T, ingErr error) (*mock.Backend, *mock.Ingester) {
+		ctrl := gomock.NewController(t)
+
+		productRepo := mock.NewProductRepository(ctrl)
+		priceRepo := mock.NewPriceRepository(ctrl)
+		backend := mock.NewBackend(ctrl)
+		ingester := mock.NewIngester(ctrl)
+
+		backend.EXPECT().Products().AnyTimes().Return(productRepo)
+		backend.EXPECT().Prices().AnyTimes().Return(priceRepo)
+		ingester.EXPECT().Ingest(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, chSize int) <-chan *price.WithProduct {
+			results := make(chan *price.WithProduct, chSize)
+			results <- pp
+			close(results)
+			return results
+		})
+		ingester.EXPECT().Err().Return(ingErr)
+
+		productRepo.EXPECT().Upsert(gomock.Any(), prod).Return(product.ID(1), nil)
+		priceRepo.EXPECT().Upsert(gomock.Any(), pp).Return(price.ID(1), nil)
+		return backend, ingester
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		be, ingester := setup(t, nil)
+		tb := &transactionBackend{Backend: be}
+
+		err := IngestPricing(context.Background(), tb, ingester, WithTransaction())
+		require.NoError(t, err)
+		assert.True(t, tb.committed)
+	})
+
+	t.Run("IngesterError", func(t *testing.T) {
+		be, ingester := setup(t, errors.New("network"))
+		tb := &transactionBackend{Backend: be}
+
+		err := IngestPricing(context.Background(), tb, ingester, WithTransaction())
+		assert.Error(t, err)
+		assert.False(t, tb.committed)
+	})
+
+	t.Run("NotSupported", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		err := IngestPricing(context.Background(), mock.NewBackend(ctrl), mock.NewIngester(ctrl), WithTransaction())
+		assert.Error(t, err)
+	})
+}
+
+// bulkProductRepository is a product.Repository that implements the product.BulkUpserter
+type bulkProductRepository struct {
+	*mock.ProductRepository
+	batches [][]*product.Product
+}
+
+func (r *bulkProductRepository) BulkUpsert(_ context.Context, ps []*product.Product) ([]product.ID, error) {
+	r.batches = append(r.batches, ps)
+	ids := make([]product.ID, 0, len(ps))
+	for _, p := range ps {
+		ids = append(ids, product.ID(len(p.SKU)))
+	}
+	return ids, nil
+}
+
+// bulkPriceRepository is a price.Repository that implements the price.BulkUpserter
+type bulkPriceRepository struct {
+	*mock.PriceRepository
+	batches [][]*price.WithProduct
+}
+
+func (r *bulkPriceRepository) BulkUpsert(_ context.Context, ps []*price.WithProduct) ([]price.ID, error) {
+	r.batches = append(r.batches, append([]*price.WithProduct(nil), ps...))
+	ids := make([]price.ID, 0, len(ps))
+	for i := range ps {
+		ids = append(ids, price.ID(i+1))
+	}
+	return ids, nil
+}
+
+func TestIngestPricing_Bulk(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	prods := []*product.Product{{Provider: "provider", SKU: "a"}, {Provider: "provider", SKU: "bb"}}
+	pps := []*price.WithProduct{
+		{Product: prods[0], Price: price.Price{Unit: "Hrs", Currency: "USD"}},
+		{Product: prods[0], Price: price.Price{Unit: "GB", Currency: "USD"}},
+		{Product: prods[1], Price: price.Price{Unit: "Hrs", Currency: "USD"}},
+	}
+
+	productRepo := &bulkProductRepository{ProductRepository: mock.NewProductRepository(ctrl)}
+	priceRepo := &bulkPriceRepository{PriceRepository: mock.NewPriceRepository(ctrl)}
+	backend := mock.NewBackend(ctrl)
+	ingester := mock.NewIngester(ctrl)
+
+	backend.EXPECT().Products().AnyTimes().Return(productRepo)
+	backend.EXPECT().Prices().AnyTimes().Return(priceRepo)
+	ingester.EXPECT().Ingest(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, chSize int) <-chan *price.WithProduct {
+		results := make(chan *price.WithProduct, chSize)
+		for _, pp := range pps {
+			results <- pp
+		}
+		close(results)
+		return results
+	})
+	ingester.EXPECT().Err().Return(nil)
+
+	err := IngestPricing(context.Background(), backend, ingester, WithBatchSize(2))
+	require.NoError(t, err)
+
+	require.Len(t, productRepo.batches, 2)
+	assert.Equal(t, []*product.Product{prods[0]}, productRepo.batches[0])
+	assert.Equal(t, []*product.Product{prods[1]}, productRepo.batches[1])
+
+	require.Len(t, priceRepo.batches, 2)
+	assert.Equal(t, pps[:2], priceRepo.batches[0])
+	assert.Equal(t, pps[2:], priceRepo.batches[1])
+	assert.Equal(t, product.ID(1), prods[0].ID)
+	assert.Equal(t, product.ID(2), prods[1].ID)
+}
+
+func TestIngestPricing_PartialPrune(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	err := IngestPricing(context.Background(), mock.NewBackend(ctrl), mock.NewIngester(ctrl), WithPartial(), WithPrune(PruneDelete))
+	assert.Error(t, err)
 }
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"context"
 	"os"
 
 	"log/slog"
@@ -19,3 +20,24 @@ func init() {
 		Level: Level,
 	}))
 }
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx with the logger l, which is
+// used instead of the Logger by the functions called with it
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// WithHandler returns a copy of ctx with a logger with the handler h
+func WithHandler(ctx context.Context, h slog.Handler) context.Context {
+	return NewContext(ctx, slog.New(h))
+}
+
+// FromContext returns the logger of the ctx or the Logger if it has none
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok && l != nil {
+		return l
+	}
+	return Logger
+}
@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/cycloidio/terracost/ingestion (interfaces: Repository)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	ingestion "github.com/cycloidio/terracost/ingestion"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// IngestionRepository is a mock of Repository interface.
+type IngestionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *IngestionRepositoryMockRecorder
+}
+
+// IngestionRepositoryMockRecorder is the mock recorder for IngestionRepository.
+type IngestionRepositoryMockRecorder struct {
+	mock *IngestionRepository
+}
+
+// NewIngestionRepository creates a new mock instance.
+func NewIngestionRepository(ctrl *gomock.Controller) *IngestionRepository {
+	mock := &IngestionRepository{ctrl: ctrl}
+	mock.recorder = &IngestionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *IngestionRepository) EXPECT() *IngestionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *IngestionRepository) Create(arg0 context.Context, arg1 *ingestion.Run) (ingestion.ID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(ingestion.ID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *IngestionRepositoryMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*IngestionRepository)(nil).Create), arg0, arg1)
+}
+
+// LastSucceeded mocks base method.
+func (m *IngestionRepository) LastSucceeded(arg0 context.Context, arg1, arg2, arg3 string) (*ingestion.Run, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastSucceeded", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*ingestion.Run)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LastSucceeded indicates an expected call of LastSucceeded.
+func (mr *IngestionRepositoryMockRecorder) LastSucceeded(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastSucceeded", reflect.TypeOf((*IngestionRepository)(nil).LastSucceeded), arg0, arg1, arg2, arg3)
+}
+
+// Update mocks base method.
+func (m *IngestionRepository) Update(arg0 context.Context, arg1 *ingestion.Run) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *IngestionRepositoryMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*IngestionRepository)(nil).Update), arg0, arg1)
+}
@@ -1,8 +1,14 @@
 package mysql
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+
 	"github.com/cycloidio/sqlr"
 
+	"github.com/cycloidio/terracost/backend"
+	"github.com/cycloidio/terracost/ingestion"
 	"github.com/cycloidio/terracost/price"
 	"github.com/cycloidio/terracost/product"
 )
@@ -10,17 +16,19 @@ import (
 // Backend is the MySQL implementation of the costestimation.Backend, using repositories that connect
 // to a MySQL database.
 type Backend struct {
-	querier     sqlr.Querier
-	productRepo *ProductRepository
-	priceRepo   *PriceRepository
+	querier       sqlr.Querier
+	productRepo   *ProductRepository
+	priceRepo     *PriceRepository
+	ingestionRepo *IngestionRepository
 }
 
-// NewBackend returns a new Backend with a product.Repository and a price.Repository included.
+// NewBackend returns a new Backend with a product.Repository, a price.Repository and an ingestion.Repository included.
 func NewBackend(querier sqlr.Querier) *Backend {
 	return &Backend{
-		querier:     querier,
-		productRepo: NewProductRepository(querier),
-		priceRepo:   NewPriceRepository(querier),
+		querier:       querier,
+		productRepo:   NewProductRepository(querier),
+		priceRepo:     NewPriceRepository(querier),
+		ingestionRepo: NewIngestionRepository(querier),
 	}
 }
 
@@ -29,3 +37,42 @@ func (b *Backend) Products() product.Repository { return b.productRepo }
 
 // Prices returns the price.Repository that uses the Backend's querier.
 func (b *Backend) Prices() price.Repository { return b.priceRepo }
+
+// Ingestions returns the ingestion.Repository that uses the Backend's querier.
+func (b *Backend) Ingestions() ingestion.Repository { return b.ingestionRepo }
+
+// txBeginner is a querier that can start a transaction, like the *sql.DB
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Transaction calls fn with a Backend that uses a transaction which is only committed if fn returns no error.
+// If the querier of the Backend is already a *sql.Tx, fn is called with the Backend itself so the changes are
+// part of it, and if it can not start a transaction an error is returned without calling fn.
+func (b *Backend) Transaction(ctx context.Context, fn func(be backend.Backend) error) error {
+	if _, ok := b.querier.(*sql.Tx); ok {
+		return fn(b)
+	}
+
+	db, ok := b.querier.(txBeginner)
+	if !ok {
+		return fmt.Errorf("the querier %T can not start a transaction", b.querier)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(NewBackend(tx)); err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			return fmt.Errorf("%w (failed to rollback: %s)", err, rerr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
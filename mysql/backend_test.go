@@ -0,0 +1,100 @@
+package mysql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cycloidio/sqlr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cycloidio/terracost/backend"
+	"github.com/cycloidio/terracost/mysql"
+	"github.com/cycloidio/terracost/product"
+)
+
+func TestBackend_Transaction(t *testing.T) {
+	prod := &product.Product{
+		Provider:   "aws",
+		SKU:        "PRODUCT",
+		Service:    "service",
+		Family:     "family",
+		Location:   "location",
+		Attributes: map[string]string{"key": "value"},
+	}
+
+	t.Run("Commit", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO pricing_products .+`).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err = mysql.NewBackend(db).Transaction(context.Background(), func(be backend.Backend) error {
+			_, err := be.Products().Upsert(context.Background(), prod)
+			return err
+		})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO pricing_products .+`).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectRollback()
+
+		ferr := errors.New("failed")
+		err = mysql.NewBackend(db).Transaction(context.Background(), func(be backend.Backend) error {
+			if _, err := be.Products().Upsert(context.Background(), prod); err != nil {
+				return err
+			}
+			return ferr
+		})
+		assert.ErrorIs(t, err, ferr)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("AlreadyTransaction", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO pricing_products .+`).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		tx, err := db.Begin()
+		require.NoError(t, err)
+
+		err = mysql.NewBackend(tx).Transaction(context.Background(), func(be backend.Backend) error {
+			_, err := be.Products().Upsert(context.Background(), prod)
+			return err
+		})
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("NotSupported", func(t *testing.T) {
+		called := false
+		err := mysql.NewBackend(querier{}).Transaction(context.Background(), func(be backend.Backend) error {
+			called = true
+			return nil
+		})
+		assert.Error(t, err)
+		assert.False(t, called)
+	})
+}
+
+// querier is a sqlr.Querier that can not start a transaction
+type querier struct {
+	sqlr.Querier
+}
@@ -99,5 +99,10 @@ func parsePriceFilter(filter *price.Filter, productID product.ID) *Where {
 		}
 	}
 
+	if filter.AsOf != nil {
+		asOf := filter.AsOf.UTC()
+		w.add("effective_from <= ? AND (effective_to IS NULL OR effective_to > ?)", asOf, asOf)
+	}
+
 	return w
 }
@@ -0,0 +1,125 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/cycloidio/sqlr"
+
+	"github.com/cycloidio/terracost/ingestion"
+)
+
+// IngestionRepository implements the ingestion.Repository.
+type IngestionRepository struct {
+	querier sqlr.Querier
+}
+
+// NewIngestionRepository returns an implementation of ingestion.Repository.
+func NewIngestionRepository(querier sqlr.Querier) *IngestionRepository {
+	return &IngestionRepository{querier: querier}
+}
+
+type dbIngestion struct {
+	ID         ingestion.ID
+	Provider   string
+	Service    string
+	Location   string
+	StartedAt  nullTime
+	FinishedAt nullTime
+	Products   int
+	Prices     int
+	Error      sql.NullString
+	Partial    bool
+}
+
+func (i *dbIngestion) toDomainEntity() *ingestion.Run {
+	return &ingestion.Run{
+		ID:         i.ID,
+		Provider:   i.Provider,
+		Service:    i.Service,
+		Location:   i.Location,
+		StartedAt:  i.StartedAt.Time,
+		FinishedAt: i.FinishedAt.Time,
+		Products:   i.Products,
+		Prices:     i.Prices,
+		Error:      i.Error.String,
+		Partial:    i.Partial,
+	}
+}
+
+// dbTime returns the t as a DATETIME value, NULL if it's zero
+func dbTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Truncate(time.Second)
+}
+
+// Create inserts the ingestion.Run and returns its ID.
+func (r *IngestionRepository) Create(ctx context.Context, run *ingestion.Run) (ingestion.ID, error) {
+	q := `
+		INSERT INTO pricing_ingestions (provider, service, location, started_at, finished_at, products, prices, error, partial)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	res, err := r.querier.ExecContext(ctx, q, run.Provider, run.Service, run.Location, dbTime(run.StartedAt), dbTime(run.FinishedAt),
+		run.Products, run.Prices, sql.NullString{String: run.Error, Valid: run.Error != ""}, run.Partial)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return ingestion.ID(id), nil
+}
+
+// Update updates the ingestion.Run with its ID.
+func (r *IngestionRepository) Update(ctx context.Context, run *ingestion.Run) error {
+	q := `
+		UPDATE pricing_ingestions
+		SET provider = ?, service = ?, location = ?, started_at = ?, finished_at = ?, products = ?, prices = ?, error = ?, partial = ?
+		WHERE id = ?
+	`
+
+	_, err := r.querier.ExecContext(ctx, q, run.Provider, run.Service, run.Location, dbTime(run.StartedAt), dbTime(run.FinishedAt),
+		run.Products, run.Prices, sql.NullString{String: run.Error, Valid: run.Error != ""}, run.Partial, run.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// LastSucceeded returns the last ingestion.Run of the provider, service and location that finished without error
+// and is not partial, nil if there is none.
+func (r *IngestionRepository) LastSucceeded(ctx context.Context, provider, service, location string) (*ingestion.Run, error) {
+	q := `
+		SELECT id, provider, service, location, started_at, finished_at, products, prices, error, partial
+		FROM pricing_ingestions
+		WHERE provider = ? AND service = ? AND location = ? AND finished_at IS NOT NULL AND error IS NULL AND partial = FALSE
+		ORDER BY finished_at DESC
+		LIMIT 1
+	`
+
+	row := r.querier.QueryRowContext(ctx, q, provider, service, location)
+	run, err := scanIngestion(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return run, nil
+}
+
+func scanIngestion(row sqlr.Scanner) (*ingestion.Run, error) {
+	var i dbIngestion
+	err := row.Scan(&i.ID, &i.Provider, &i.Service, &i.Location, &i.StartedAt, &i.FinishedAt, &i.Products, &i.Prices, &i.Error, &i.Partial)
+	if err != nil {
+		return nil, err
+	}
+	return i.toDomainEntity(), nil
+}
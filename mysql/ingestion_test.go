@@ -0,0 +1,104 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cycloidio/terracost/ingestion"
+	"github.com/cycloidio/terracost/mysql"
+)
+
+var ingestionColumns = []string{"id", "provider", "service", "location", "started_at", "finished_at", "products", "prices", "error", "partial"}
+
+func TestIngestionRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := mysql.NewIngestionRepository(db)
+
+	started := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectExec(`INSERT INTO pricing_ingestions .+ VALUES .+`).
+		WithArgs("aws", "AmazonEC2", "eu-west-1", started, nil, 0, 0, sql.NullString{}, true).
+		WillReturnResult(sqlmock.NewResult(4, 1))
+
+	id, err := repo.Create(context.Background(), &ingestion.Run{Provider: "aws", Service: "AmazonEC2", Location: "eu-west-1", StartedAt: started, Partial: true})
+	require.NoError(t, err)
+	require.Equal(t, ingestion.ID(4), id)
+}
+
+func TestIngestionRepository_Update(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := mysql.NewIngestionRepository(db)
+
+	started := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	finished := started.Add(time.Hour)
+	mock.ExpectExec(`UPDATE pricing_ingestions SET .+ WHERE id = \?`).
+		WithArgs("aws", "AmazonEC2", "eu-west-1", started, finished, 10, 20, sql.NullString{String: "failed", Valid: true}, false, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.Update(context.Background(), &ingestion.Run{
+		ID:         4,
+		Provider:   "aws",
+		Service:    "AmazonEC2",
+		Location:   "eu-west-1",
+		StartedAt:  started,
+		FinishedAt: finished,
+		Products:   10,
+		Prices:     20,
+		Error:      "failed",
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIngestionRepository_LastSucceeded(t *testing.T) {
+	t.Run("Found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		repo := mysql.NewIngestionRepository(db)
+
+		rows := mock.NewRows(ingestionColumns).AddRow(4, "aws", "AmazonEC2", "eu-west-1", "2024-01-02 03:04:05", "2024-01-02 04:04:05", 10, 20, nil, false)
+		mock.ExpectQuery(`SELECT .+ FROM pricing_ingestions WHERE provider = \? AND service = \? AND location = \? AND finished_at IS NOT NULL AND error IS NULL AND partial = FALSE ORDER BY finished_at DESC LIMIT 1`).
+			WithArgs("aws", "AmazonEC2", "eu-west-1").
+			WillReturnRows(rows)
+
+		run, err := repo.LastSucceeded(context.Background(), "aws", "AmazonEC2", "eu-west-1")
+		require.NoError(t, err)
+		require.Equal(t, &ingestion.Run{
+			ID:         4,
+			Provider:   "aws",
+			Service:    "AmazonEC2",
+			Location:   "eu-west-1",
+			StartedAt:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			FinishedAt: time.Date(2024, 1, 2, 4, 4, 5, 0, time.UTC),
+			Products:   10,
+			Prices:     20,
+		}, run)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		repo := mysql.NewIngestionRepository(db)
+
+		mock.ExpectQuery(`SELECT .+ FROM pricing_ingestions .+`).
+			WillReturnRows(mock.NewRows(ingestionColumns))
+
+		run, err := repo.LastSucceeded(context.Background(), "aws", "AmazonEC2", "eu-west-1")
+		require.NoError(t, err)
+		require.Nil(t, run)
+	})
+}
@@ -0,0 +1,11 @@
+package migrations
+
+// v3StalePrices adds the stale flag to the prices that are
+// no longer on the pricing of the providers when ingesting
+var v3StalePrices = Migration{
+	Name: "Add stale flag to the prices",
+	SQL: `
+		ALTER TABLE pricing_product_prices
+			ADD COLUMN stale BOOLEAN NOT NULL DEFAULT FALSE;
+	`,
+}
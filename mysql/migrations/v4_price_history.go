@@ -0,0 +1,23 @@
+package migrations
+
+// v4PriceHistory keeps the previous values of the prices with the range of time
+// in which they were effective, so the unique key is now only on the current ones.
+// The existing prices are the current ones since always, except the stale ones
+var v4PriceHistory = Migration{
+	Name: "Add effective range to the prices",
+	SQL: `
+		ALTER TABLE pricing_product_prices
+			ADD COLUMN effective_from DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00',
+			ADD COLUMN effective_to DATETIME NULL,
+			ADD COLUMN is_current TINYINT AS (IF(effective_to IS NULL, 1, NULL)) STORED;
+
+		UPDATE pricing_product_prices
+			SET effective_to = UTC_TIMESTAMP()
+			WHERE stale = TRUE;
+
+		ALTER TABLE pricing_product_prices
+			ADD CONSTRAINT UNIQUE uq__product_id__hash__is_current (product_id, hash, is_current);
+		ALTER TABLE pricing_product_prices
+			DROP INDEX uq__product_id__hash;
+	`,
+}
@@ -0,0 +1,22 @@
+package migrations
+
+// v5Ingestions adds the table of the ingestion runs so it's known when
+// each service of each provider was ingested on each region
+var v5Ingestions = Migration{
+	Name: "Add ingestions",
+	SQL: `
+		CREATE TABLE pricing_ingestions (
+			id INT(8) UNSIGNED AUTO_INCREMENT,
+			provider VARCHAR(16) NOT NULL,
+			service VARCHAR(100) NOT NULL,
+			location VARCHAR(100) NOT NULL,
+			started_at DATETIME NOT NULL,
+			finished_at DATETIME NULL,
+			products INT UNSIGNED NOT NULL DEFAULT 0,
+			prices INT UNSIGNED NOT NULL DEFAULT 0,
+			error TEXT NULL,
+			PRIMARY KEY (id),
+			INDEX idx__provider__service__location__finished_at (provider, service, location, finished_at)
+		);
+	`,
+}
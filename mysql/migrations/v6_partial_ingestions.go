@@ -0,0 +1,11 @@
+package migrations
+
+// v6PartialIngestions adds the partial flag to the ingestion runs that only
+// ingested some of the products, which do not make the pricing up to date
+var v6PartialIngestions = Migration{
+	Name: "Add partial flag to the ingestions",
+	SQL: `
+		ALTER TABLE pricing_ingestions
+			ADD COLUMN partial BOOLEAN NOT NULL DEFAULT FALSE;
+	`,
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"golang.org/x/text/currency"
 
@@ -26,13 +27,15 @@ func NewPriceRepository(querier sqlr.Querier) *PriceRepository {
 }
 
 type dbPrice struct {
-	ID         price.ID
-	ProductID  product.ID
-	Hash       string
-	Currency   string
-	Value      decimal.Decimal
-	Unit       string
-	Attributes string
+	ID            price.ID
+	ProductID     product.ID
+	Hash          string
+	Currency      string
+	Value         decimal.Decimal
+	Unit          string
+	Attributes    string
+	EffectiveFrom nullTime
+	EffectiveTo   nullTime
 }
 
 func (p *dbPrice) toDomainEntity() *price.Price {
@@ -45,6 +48,9 @@ func (p *dbPrice) toDomainEntity() *price.Price {
 		Value:      p.Value,
 		Unit:       p.Unit,
 		Attributes: attributes,
+
+		EffectiveFrom: p.EffectiveFrom.Time,
+		EffectiveTo:   p.EffectiveTo.Time,
 	}
 }
 
@@ -59,21 +65,32 @@ func newPrice(pwp *price.WithProduct) (*dbPrice, error) {
 		return nil, err
 	}
 
+	from := pwp.EffectiveFrom
+	if from.IsZero() {
+		from = time.Now()
+	}
+
 	return &dbPrice{
-		ProductID:  pwp.Product.ID,
-		Hash:       pwp.GenerateHash(),
-		Currency:   cur.String(),
-		Value:      pwp.Value,
-		Unit:       pwp.Unit,
-		Attributes: string(attributes),
+		ProductID:     pwp.Product.ID,
+		Hash:          pwp.GenerateHash(),
+		Currency:      cur.String(),
+		Value:         pwp.Value,
+		Unit:          pwp.Unit,
+		Attributes:    string(attributes),
+		EffectiveFrom: nullTime{Time: from.UTC().Truncate(time.Second), Valid: true},
 	}, nil
 }
 
 // Filter returns all the price.Price that belong to a given product with given product.ID and that matches the price.Filter.
+// They are the current ones unless the price.Filter has AsOf.
 func (r *PriceRepository) Filter(ctx context.Context, productID product.ID, filter *price.Filter) ([]*price.Price, error) {
 	where := parsePriceFilter(filter, productID)
+	if filter == nil || filter.AsOf == nil {
+		where.add("effective_to IS NULL")
+		where.add("stale = FALSE")
+	}
 	q := fmt.Sprintf(`
-		SELECT id, hash, product_id, currency, price, unit, attributes
+		SELECT id, hash, product_id, currency, price, unit, attributes, effective_from, effective_to
 		FROM pricing_product_prices
 		WHERE %s
 	`, where.String())
@@ -98,7 +115,8 @@ func (r *PriceRepository) Filter(ctx context.Context, productID product.ID, filt
 	return ps, nil
 }
 
-// Upsert updates a price.WithProduct if it exists or inserts it otherwise.
+// Upsert updates the current price.WithProduct if it exists or inserts it otherwise. If the value of the current
+// one changed it's closed at the EffectiveFrom of the new one, which is inserted.
 func (r *PriceRepository) Upsert(ctx context.Context, pwp *price.WithProduct) (price.ID, error) {
 	p, err := newPrice(pwp)
 	if err != nil {
@@ -106,17 +124,27 @@ func (r *PriceRepository) Upsert(ctx context.Context, pwp *price.WithProduct) (p
 	}
 
 	q := `
-		INSERT INTO pricing_product_prices (product_id, hash, currency, price, unit, attributes)
-		VALUES (?, ?, ?, ?, ?, ?)
+		UPDATE pricing_product_prices
+		SET effective_to = ?
+		WHERE product_id = ? AND hash = ? AND effective_to IS NULL AND price <> CAST(? AS DECIMAL(24,10))
+	`
+	if _, err := r.querier.ExecContext(ctx, q, p.EffectiveFrom.Time, p.ProductID, p.Hash, p.Value); err != nil {
+		return 0, err
+	}
+
+	q = `
+		INSERT INTO pricing_product_prices (product_id, hash, currency, price, unit, attributes, effective_from)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 		ON DUPLICATE KEY UPDATE
 			id = LAST_INSERT_ID(id),
 			currency = VALUES(currency),
 			price = VALUES(price),
 			unit = VALUES(unit),
-			attributes = VALUES(attributes)
+			attributes = VALUES(attributes),
+			stale = FALSE
 	`
 
-	res, err := r.querier.ExecContext(ctx, q, p.ProductID, p.Hash, p.Currency, p.Value, p.Unit, p.Attributes)
+	res, err := r.querier.ExecContext(ctx, q, p.ProductID, p.Hash, p.Currency, p.Value, p.Unit, p.Attributes, p.EffectiveFrom.Time)
 	if err != nil {
 		return 0, err
 	}
@@ -128,18 +156,115 @@ func (r *PriceRepository) Upsert(ctx context.Context, pwp *price.WithProduct) (p
 	return price.ID(id), nil
 }
 
-// DeleteByProductWithKeep deletes all the prices of the product with given product.ID except the ones in the keep slice.
-func (r *PriceRepository) DeleteByProductWithKeep(ctx context.Context, productID product.ID, keep []price.ID) error {
-	marks := make([]string, 0, len(keep))
-	values := make([]interface{}, 0, len(keep)+1)
-	values = append(values, productID)
+// BulkUpsert updates the price.WithProduct that exist and inserts the rest with a single query, returning their IDs
+// in the same order.
+func (r *PriceRepository) BulkUpsert(ctx context.Context, pwps []*price.WithProduct) ([]price.ID, error) {
+	if len(pwps) == 0 {
+		return nil, nil
+	}
 
-	for _, v := range keep {
-		marks = append(marks, "?")
-		values = append(values, v)
+	type priceKey struct {
+		productID product.ID
+		hash      string
+	}
+
+	var effectiveTo time.Time
+	marks := make([]string, 0, len(pwps))
+	keys := make([]string, 0, len(pwps))
+	changes := make([]string, 0, len(pwps))
+	values := make([]interface{}, 0, len(pwps)*7)
+	keyValues := make([]interface{}, 0, len(pwps)*2)
+	changeValues := make([]interface{}, 0, len(pwps)*3)
+	pks := make([]priceKey, 0, len(pwps))
+	for _, pwp := range pwps {
+		p, err := newPrice(pwp)
+		if err != nil {
+			return nil, err
+		}
+		marks = append(marks, "(?, ?, ?, ?, ?, ?, ?)")
+		keys = append(keys, "(?, ?)")
+		changes = append(changes, "(product_id = ? AND hash = ? AND price <> CAST(? AS DECIMAL(24,10)))")
+		values = append(values, p.ProductID, p.Hash, p.Currency, p.Value, p.Unit, p.Attributes, p.EffectiveFrom.Time)
+		keyValues = append(keyValues, p.ProductID, p.Hash)
+		changeValues = append(changeValues, p.ProductID, p.Hash, p.Value)
+		pks = append(pks, priceKey{productID: p.ProductID, hash: p.Hash})
+		// All the prices changed are closed at the same time
+		if from := p.EffectiveFrom.Time; from.After(effectiveTo) {
+			effectiveTo = from
+		}
+	}
+
+	// The current prices with a different value are closed so the new ones are inserted
+	q := fmt.Sprintf(`
+		UPDATE pricing_product_prices
+		SET effective_to = ?
+		WHERE effective_to IS NULL AND (%s)
+	`, strings.Join(changes, " OR "))
+
+	if _, err := r.querier.ExecContext(ctx, q, append([]interface{}{effectiveTo}, changeValues...)...); err != nil {
+		return nil, err
+	}
+
+	q = fmt.Sprintf(`
+		INSERT INTO pricing_product_prices (product_id, hash, currency, price, unit, attributes, effective_from)
+		VALUES %s
+		ON DUPLICATE KEY UPDATE
+			currency = VALUES(currency),
+			price = VALUES(price),
+			unit = VALUES(unit),
+			attributes = VALUES(attributes),
+			stale = FALSE
+	`, strings.Join(marks, ", "))
+
+	if _, err := r.querier.ExecContext(ctx, q, values...); err != nil {
+		return nil, err
+	}
+
+	// The IDs of the rows are not returned by a multi-row
+	// INSERT so they are read by their unique key
+	q = fmt.Sprintf(`
+		SELECT id, product_id, hash
+		FROM pricing_product_prices
+		WHERE effective_to IS NULL AND (product_id, hash) IN (%s)
+	`, strings.Join(keys, ", "))
+
+	rows, err := r.querier.QueryContext(ctx, q, keyValues...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	q := fmt.Sprintf(`DELETE FROM pricing_product_prices WHERE product_id = ? AND id NOT IN (%s)`, strings.Join(marks, ","))
+	found := make(map[priceKey]price.ID, len(pwps))
+	for rows.Next() {
+		var (
+			id price.ID
+			pk priceKey
+		)
+		if err := rows.Scan(&id, &pk.productID, &pk.hash); err != nil {
+			return nil, err
+		}
+		found[pk] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]price.ID, 0, len(pwps))
+	for i, pk := range pks {
+		id, ok := found[pk]
+		if !ok {
+			return nil, fmt.Errorf("price (SKU=%q) not found after upsert", pwps[i].Product.SKU)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// DeleteByProductWithKeep deletes all the current prices of the product with given product.ID except the ones in the
+// keep slice, the previous values of the prices are kept.
+func (r *PriceRepository) DeleteByProductWithKeep(ctx context.Context, productID product.ID, keep []price.ID) error {
+	where, values := whereProductWithKeep(productID, keep)
+	q := fmt.Sprintf(`DELETE FROM pricing_product_prices WHERE %s AND effective_to IS NULL`, where)
 
 	_, err := r.querier.ExecContext(ctx, q, values...)
 	if err != nil {
@@ -148,11 +273,70 @@ func (r *PriceRepository) DeleteByProductWithKeep(ctx context.Context, productID
 	return nil
 }
 
+// MarkStaleByProductWithKeep marks as stale all the current prices of the product with given product.ID except the
+// ones in the keep slice, which closes them so they are no longer the current ones.
+func (r *PriceRepository) MarkStaleByProductWithKeep(ctx context.Context, productID product.ID, keep []price.ID) error {
+	where, values := whereProductWithKeep(productID, keep)
+	q := fmt.Sprintf(`UPDATE pricing_product_prices SET stale = TRUE, effective_to = ? WHERE %s AND effective_to IS NULL`, where)
+
+	_, err := r.querier.ExecContext(ctx, q, append([]interface{}{time.Now().UTC().Truncate(time.Second)}, values...)...)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// whereProductWithKeep returns the condition, and its values, of the prices of the
+// productID that are not in the keep slice
+func whereProductWithKeep(productID product.ID, keep []price.ID) (string, []interface{}) {
+	values := make([]interface{}, 0, len(keep)+1)
+	values = append(values, productID)
+	if len(keep) == 0 {
+		return "product_id = ?", values
+	}
+
+	marks := make([]string, 0, len(keep))
+	for _, v := range keep {
+		marks = append(marks, "?")
+		values = append(values, v)
+	}
+	return fmt.Sprintf("product_id = ? AND id NOT IN (%s)", strings.Join(marks, ",")), values
+}
+
 func scanPrice(row sqlr.Scanner) (*price.Price, error) {
 	var p dbPrice
-	err := row.Scan(&p.ID, &p.Hash, &p.ProductID, &p.Currency, &p.Value, &p.Unit, &p.Attributes)
+	err := row.Scan(&p.ID, &p.Hash, &p.ProductID, &p.Currency, &p.Value, &p.Unit, &p.Attributes, &p.EffectiveFrom, &p.EffectiveTo)
 	if err != nil {
 		return nil, err
 	}
 	return p.toDomainEntity(), nil
 }
+
+// nullTime is a DATETIME column that may be NULL, it's read with or without the parseTime of the DSN
+type nullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+// Scan implements the sql.Scanner interface
+func (t *nullTime) Scan(v interface{}) error {
+	var err error
+	switch v := v.(type) {
+	case nil:
+		*t = nullTime{}
+		return nil
+	case time.Time:
+		t.Time = v
+	case []byte:
+		t.Time, err = time.Parse(time.DateTime, string(v))
+	case string:
+		t.Time, err = time.Parse(time.DateTime, v)
+	default:
+		return fmt.Errorf("unsupported time %T", v)
+	}
+	if err != nil {
+		return err
+	}
+	t.Valid = true
+	return nil
+}
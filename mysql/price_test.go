@@ -3,6 +3,7 @@ package mysql_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/shopspring/decimal"
@@ -13,7 +14,7 @@ import (
 	"github.com/cycloidio/terracost/product"
 )
 
-var priceColumns = []string{"id", "hash", "product_id", "currency", "price", "unit", "attributes"}
+var priceColumns = []string{"id", "hash", "product_id", "currency", "price", "unit", "attributes", "effective_from", "effective_to"}
 
 func TestPriceRepository_FilterByProduct(t *testing.T) {
 	t.Run("NoFilters", func(t *testing.T) {
@@ -23,7 +24,7 @@ func TestPriceRepository_FilterByProduct(t *testing.T) {
 
 		repo := mysql.NewPriceRepository(db)
 
-		rows := mock.NewRows(priceColumns).AddRow(1, "HASH", 1, "USD", decimal.RequireFromString("1.23"), "Hrs", `{"key":"value"}`)
+		rows := mock.NewRows(priceColumns).AddRow(1, "HASH", 1, "USD", decimal.RequireFromString("1.23"), "Hrs", `{"key":"value"}`, "2024-01-02 03:04:05", nil)
 		mock.ExpectQuery(`SELECT .+ FROM .+ WHERE product_id = \?`).
 			WithArgs(1).
 			WillReturnRows(rows)
@@ -38,6 +39,8 @@ func TestPriceRepository_FilterByProduct(t *testing.T) {
 				Currency:   "USD",
 				Value:      decimal.RequireFromString("1.23"),
 				Attributes: map[string]string{"key": "value"},
+
+				EffectiveFrom: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
 			},
 		}
 
@@ -51,7 +54,7 @@ func TestPriceRepository_FilterByProduct(t *testing.T) {
 
 		repo := mysql.NewPriceRepository(db)
 
-		rows := mock.NewRows(priceColumns).AddRow(1, "HASH", 1, "USD", decimal.RequireFromString("1.23"), "Hrs", `{"key":"value"}`)
+		rows := mock.NewRows(priceColumns).AddRow(1, "HASH", 1, "USD", decimal.RequireFromString("1.23"), "Hrs", `{"key":"value"}`, "2024-01-02 03:04:05", nil)
 		mock.ExpectQuery(`SELECT .+ FROM .+ WHERE product_id = \? AND unit = \? AND currency = \?`).
 			WithArgs(1, "Hrs", "USD").
 			WillReturnRows(rows)
@@ -70,6 +73,8 @@ func TestPriceRepository_FilterByProduct(t *testing.T) {
 				Currency:   "USD",
 				Value:      decimal.RequireFromString("1.23"),
 				Attributes: map[string]string{"key": "value"},
+
+				EffectiveFrom: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
 			},
 		}
 
@@ -83,7 +88,7 @@ func TestPriceRepository_FilterByProduct(t *testing.T) {
 
 		repo := mysql.NewPriceRepository(db)
 
-		rows := mock.NewRows(priceColumns).AddRow(1, "HASH", 1, "USD", decimal.RequireFromString("1.23"), "Hrs", `{"key":"value","other":"value2"}`)
+		rows := mock.NewRows(priceColumns).AddRow(1, "HASH", 1, "USD", decimal.RequireFromString("1.23"), "Hrs", `{"key":"value","other":"value2"}`, "2024-01-02 03:04:05", nil)
 		mock.ExpectQuery(`SELECT .+ FROM .+ WHERE product_id = \? AND JSON_UNQUOTE\(JSON_EXTRACT\(attributes, '\$\.key'\)\) = \? AND JSON_UNQUOTE\(JSON_EXTRACT\(attributes, '\$\.other'\)\) RLIKE \?`).
 			WithArgs(1, "value", "lue").
 			WillReturnRows(rows)
@@ -107,9 +112,147 @@ func TestPriceRepository_FilterByProduct(t *testing.T) {
 					"key":   "value",
 					"other": "value2",
 				},
+				EffectiveFrom: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
 			},
 		}
 
 		require.Equal(t, expected, prices)
 	})
 }
+
+func TestPriceRepository_FilterAsOf(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := mysql.NewPriceRepository(db)
+
+	asOf := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	rows := mock.NewRows(priceColumns).AddRow(1, "HASH", 1, "USD", decimal.RequireFromString("1.23"), "Hrs", `{}`, "2024-01-01 00:00:00", "2024-06-01 00:00:00")
+	mock.ExpectQuery(`SELECT .+ FROM .+ WHERE product_id = \? AND effective_from <= \? AND \(effective_to IS NULL OR effective_to > \?\)\s*$`).
+		WithArgs(1, asOf, asOf).
+		WillReturnRows(rows)
+
+	prices, err := repo.Filter(context.Background(), product.ID(1), &price.Filter{AsOf: &asOf})
+	require.NoError(t, err)
+
+	expected := []*price.Price{
+		{
+			ID:            1,
+			Unit:          "Hrs",
+			Currency:      "USD",
+			Value:         decimal.RequireFromString("1.23"),
+			Attributes:    map[string]string{},
+			EffectiveFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			EffectiveTo:   time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	require.Equal(t, expected, prices)
+}
+
+func TestPriceRepository_Upsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := mysql.NewPriceRepository(db)
+
+	from := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	pwp := &price.WithProduct{
+		Product: &product.Product{ID: 1},
+		Price:   price.Price{Unit: "Hrs", Currency: "USD", Value: decimal.RequireFromString("1.23"), EffectiveFrom: from},
+	}
+
+	mock.ExpectExec(`UPDATE pricing_product_prices SET effective_to = \? WHERE product_id = \? AND hash = \? AND effective_to IS NULL AND price <> .+`).
+		WithArgs(from, 1, pwp.GenerateHash(), pwp.Value).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO pricing_product_prices .+ VALUES .+ ON DUPLICATE KEY UPDATE .+`).
+		WithArgs(1, pwp.GenerateHash(), "USD", pwp.Value, "Hrs", `null`, from).
+		WillReturnResult(sqlmock.NewResult(12, 1))
+
+	id, err := repo.Upsert(context.Background(), pwp)
+	require.NoError(t, err)
+	require.Equal(t, price.ID(12), id)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPriceRepository_DeleteByProductWithKeep(t *testing.T) {
+	t.Run("Keep", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		repo := mysql.NewPriceRepository(db)
+
+		mock.ExpectExec(`DELETE FROM pricing_product_prices WHERE product_id = \? AND id NOT IN \(\?,\?\) AND effective_to IS NULL`).
+			WithArgs(1, 2, 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err = repo.DeleteByProductWithKeep(context.Background(), product.ID(1), []price.ID{2, 3})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("NoKeep", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		repo := mysql.NewPriceRepository(db)
+
+		mock.ExpectExec(`DELETE FROM pricing_product_prices WHERE product_id = \? AND effective_to IS NULL`).
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		err = repo.DeleteByProductWithKeep(context.Background(), product.ID(1), nil)
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPriceRepository_MarkStaleByProductWithKeep(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := mysql.NewPriceRepository(db)
+
+	mock.ExpectExec(`UPDATE pricing_product_prices SET stale = TRUE, effective_to = \? WHERE product_id = \? AND id NOT IN \(\?\) AND effective_to IS NULL`).
+		WithArgs(sqlmock.AnyArg(), 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.MarkStaleByProductWithKeep(context.Background(), product.ID(1), []price.ID{2})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPriceRepository_BulkUpsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := mysql.NewPriceRepository(db)
+
+	from := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	prod := &product.Product{ID: 1, SKU: "A"}
+	pwps := []*price.WithProduct{
+		{Product: prod, Price: price.Price{Unit: "Hrs", Currency: "USD", Value: decimal.RequireFromString("1.23"), EffectiveFrom: from}},
+		{Product: prod, Price: price.Price{Unit: "GB", Currency: "USD", Value: decimal.RequireFromString("0.1"), EffectiveFrom: from}},
+	}
+
+	mock.ExpectExec(`UPDATE pricing_product_prices SET effective_to = \? WHERE effective_to IS NULL AND \(\(product_id = \? AND hash = \? AND price <> .+\) OR \(.+\)\)`).
+		WithArgs(from, 1, pwps[0].GenerateHash(), pwps[0].Value, 1, pwps[1].GenerateHash(), pwps[1].Value).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO pricing_product_prices .+ VALUES \(\?, \?, \?, \?, \?, \?, \?\), \(\?, \?, \?, \?, \?, \?, \?\) ON DUPLICATE KEY UPDATE .+`).
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectQuery(`SELECT id, product_id, hash FROM pricing_product_prices WHERE effective_to IS NULL AND \(product_id, hash\) IN \(\(\?, \?\), \(\?, \?\)\)`).
+		WithArgs(1, pwps[0].GenerateHash(), 1, pwps[1].GenerateHash()).
+		WillReturnRows(mock.NewRows([]string{"id", "product_id", "hash"}).
+			AddRow(5, 1, pwps[1].GenerateHash()).
+			AddRow(4, 1, pwps[0].GenerateHash()))
+
+	ids, err := repo.BulkUpsert(context.Background(), pwps)
+	require.NoError(t, err)
+	require.Equal(t, []price.ID{4, 5}, ids)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
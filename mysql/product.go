@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/cycloidio/sqlr"
 
@@ -129,6 +130,79 @@ func (r *ProductRepository) Upsert(ctx context.Context, prod *product.Product) (
 	return product.ID(id), nil
 }
 
+// BulkUpsert updates the product.Product that exist and inserts the rest with a single query, returning their IDs
+// in the same order.
+func (r *ProductRepository) BulkUpsert(ctx context.Context, prods []*product.Product) ([]product.ID, error) {
+	if len(prods) == 0 {
+		return nil, nil
+	}
+
+	marks := make([]string, 0, len(prods))
+	keys := make([]string, 0, len(prods))
+	values := make([]interface{}, 0, len(prods)*6)
+	keyValues := make([]interface{}, 0, len(prods)*3)
+	for _, prod := range prods {
+		p, err := newProduct(prod)
+		if err != nil {
+			return nil, err
+		}
+		marks = append(marks, "(?, ?, ?, ?, ?, ?)")
+		keys = append(keys, "(?, ?, ?)")
+		values = append(values, p.Provider, p.SKU, p.Service, p.Family, p.Location, p.Attributes)
+		keyValues = append(keyValues, p.Provider, p.SKU, p.Location)
+	}
+
+	q := fmt.Sprintf(`
+		INSERT INTO pricing_products (provider, sku, service, family, location, attributes)
+		VALUES %s
+		ON DUPLICATE KEY UPDATE
+			attributes = VALUES(attributes)
+	`, strings.Join(marks, ", "))
+
+	if _, err := r.querier.ExecContext(ctx, q, values...); err != nil {
+		return nil, err
+	}
+
+	// The IDs of the rows are not returned by a multi-row
+	// INSERT so they are read by their unique key
+	q = fmt.Sprintf(`
+		SELECT id, provider, sku, location
+		FROM pricing_products
+		WHERE (provider, sku, location) IN (%s)
+	`, strings.Join(keys, ", "))
+
+	rows, err := r.querier.QueryContext(ctx, q, keyValues...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[[3]string]product.ID, len(prods))
+	for rows.Next() {
+		var (
+			id                      product.ID
+			provider, sku, location string
+		)
+		if err := rows.Scan(&id, &provider, &sku, &location); err != nil {
+			return nil, err
+		}
+		found[[3]string{provider, sku, location}] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]product.ID, 0, len(prods))
+	for _, p := range prods {
+		id, ok := found[[3]string{p.Provider, p.SKU, p.Location}]
+		if !ok {
+			return nil, fmt.Errorf("product (SKU=%q, Location=%q) not found after upsert", p.SKU, p.Location)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
 func scanProduct(row sqlr.Scanner) (*product.Product, error) {
 	var p dbProduct
 	err := row.Scan(&p.ID, &p.Provider, &p.SKU, &p.Service, &p.Family, &p.Location, &p.Attributes)
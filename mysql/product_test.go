@@ -171,6 +171,84 @@ func TestProductRepository_Upsert(t *testing.T) {
 	require.Equal(t, product.ID(123), id)
 }
 
+func TestProductRepository_BulkUpsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := mysql.NewProductRepository(db)
+
+	mock.ExpectExec(`INSERT INTO .+ VALUES \(\?, \?, \?, \?, \?, \?\), \(\?, \?, \?, \?, \?, \?\) ON DUPLICATE KEY UPDATE .+`).
+		WithArgs("aws", "A", "service", "family", "location", `{"key":"value"}`, "aws", "B", "service", "family", "location", `{}`).
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectQuery(`SELECT id, provider, sku, location FROM pricing_products WHERE \(provider, sku, location\) IN \(\(\?, \?, \?\), \(\?, \?, \?\)\)`).
+		WithArgs("aws", "A", "location", "aws", "B", "location").
+		WillReturnRows(mock.NewRows([]string{"id", "provider", "sku", "location"}).AddRow(7, "aws", "B", "location").AddRow(3, "aws", "A", "location"))
+
+	prods := []*product.Product{
+		{
+			Provider:   "aws",
+			SKU:        "A",
+			Service:    "service",
+			Family:     "family",
+			Location:   "location",
+			Attributes: map[string]string{"key": "value"},
+		},
+		{
+			Provider:   "aws",
+			SKU:        "B",
+			Service:    "service",
+			Family:     "family",
+			Location:   "location",
+			Attributes: map[string]string{},
+		},
+	}
+
+	ids, err := repo.BulkUpsert(context.Background(), prods)
+	require.NoError(t, err)
+	require.Equal(t, []product.ID{3, 7}, ids)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_BulkUpsert_SameSKUInLocations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := mysql.NewProductRepository(db)
+
+	mock.ExpectExec(`INSERT INTO .+ VALUES \(\?, \?, \?, \?, \?, \?\), \(\?, \?, \?, \?, \?, \?\) ON DUPLICATE KEY UPDATE .+`).
+		WithArgs("aws", "A", "service", "family", "eu-west-1", `{}`, "aws", "A", "service", "family", "eu-west-3", `{}`).
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectQuery(`SELECT id, provider, sku, location FROM pricing_products WHERE \(provider, sku, location\) IN \(\(\?, \?, \?\), \(\?, \?, \?\)\)`).
+		WithArgs("aws", "A", "eu-west-1", "aws", "A", "eu-west-3").
+		WillReturnRows(mock.NewRows([]string{"id", "provider", "sku", "location"}).AddRow(7, "aws", "A", "eu-west-3").AddRow(3, "aws", "A", "eu-west-1"))
+
+	prods := []*product.Product{
+		{
+			Provider:   "aws",
+			SKU:        "A",
+			Service:    "service",
+			Family:     "family",
+			Location:   "eu-west-1",
+			Attributes: map[string]string{},
+		},
+		{
+			Provider:   "aws",
+			SKU:        "A",
+			Service:    "service",
+			Family:     "family",
+			Location:   "eu-west-3",
+			Attributes: map[string]string{},
+		},
+	}
+
+	ids, err := repo.BulkUpsert(context.Background(), prods)
+	require.NoError(t, err)
+	require.Equal(t, []product.ID{3, 7}, ids)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func strPtr(s string) *string {
 	return &s
 }
@@ -0,0 +1,391 @@
+package terracost
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/cycloidio/terracost/event"
+	"github.com/cycloidio/terracost/log"
+	"github.com/cycloidio/terracost/query"
+	"github.com/cycloidio/terracost/terraform"
+	"github.com/cycloidio/terracost/usage"
+)
+
+// EstimateOptions are the options of the estimations of plans, states and HCL,
+// they are set with the EstimateOption functions
+type EstimateOptions struct {
+	// Usage is the usage of the resources, usage.Default by default
+	Usage usage.Usage
+
+	// ProviderInitializers are the providers supported, all of them by default
+	ProviderInitializers []terraform.ProviderInitializer
+
+	// ProviderConfigs are the configurations of the providers by name (like 'aws')
+	// when the estimated source has none, which is the case of the states
+	ProviderConfigs map[string]map[string]string
+
+	// Fs is the file system of the HCL, the OS by default
+	Fs afero.Fs
+
+	// ModulePath is the path of the module to estimate on the HCL stack, if it's
+	// not the root of it
+	ModulePath string
+
+	// HCL are the options to read the HCL and fetch its modules
+	HCL terraform.HCLOptions
+
+	// ForceTerragrunt runs Terragrunt even if the ModulePath has no configuration
+	ForceTerragrunt bool
+
+	// TerragruntParallelism is the parallelism of Terragrunt, its default if 0
+	TerragruntParallelism int
+
+	// NativeTerragrunt parses the Terragrunt configurations instead of running
+	// Terragrunt, so nothing is executed nor written to the OS
+	NativeTerragrunt bool
+
+	// BestEffort continues with the rest of the Terragrunt modules when one fails,
+	// returning the plans of the ones it could estimate with the errors of the
+	// others as a ModuleErrors
+	BestEffort bool
+
+	// Debug adds the Terragrunt logs to its errors
+	Debug bool
+
+	// Concurrency is the number of Terragrunt modules estimated at the same time, 1 by default
+	Concurrency int
+
+	// Logger is the logger of the estimation, the one of the context
+	// (see log.NewContext) or log.Logger by default
+	Logger *slog.Logger
+
+	// EventHandler receives the events of the progress of the estimation
+	EventHandler event.Handler
+
+	// Currency is the currency of the prices, any by default
+	Currency string
+
+	// Strict returns an error if any component could not be priced
+	// instead of reporting it on the cost.Component
+	Strict bool
+
+	// AsOf uses the prices that were effective at that time instead of the current ones
+	AsOf time.Time
+
+	// MaxPricingAge warns, with the logger and the event.PricingOutdated, when the pricing of the
+	// services and regions used was ingested longer than it ago or never, not checked if 0
+	MaxPricingAge time.Duration
+
+	// freshnessChecked are the ingestScope already checked by the MaxPricingAge
+	freshnessChecked sync.Map
+
+	// collectQueries, if set, receives the queries of the modules instead of estimating them
+	collectQueries func(queries []query.Resource)
+}
+
+// EstimateOption sets an option of the EstimateOptions
+type EstimateOption func(*EstimateOptions)
+
+// newEstimateOptions returns the EstimateOptions with the opts applied over the defaults
+func newEstimateOptions(opts ...EstimateOption) *EstimateOptions {
+	o := &EstimateOptions{
+		Usage:       usage.Default,
+		Concurrency: 1,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if len(o.ProviderInitializers) == 0 {
+		o.ProviderInitializers = getDefaultProviders()
+	}
+	if o.Fs == nil {
+		o.Fs = afero.NewOsFs()
+	}
+	if o.Concurrency < 1 {
+		o.Concurrency = 1
+	}
+	return o
+}
+
+// context returns the ctx with the Logger and the EventHandler so they are used by
+// the packages called with it, the Logger is the one of the ctx if not set
+func (o *EstimateOptions) context(ctx context.Context) context.Context {
+	if o.Logger == nil {
+		o.Logger = log.FromContext(ctx)
+	} else {
+		ctx = log.NewContext(ctx, o.Logger)
+	}
+	if o.HCL.Logger == nil {
+		o.HCL.Logger = o.Logger
+	}
+	if o.EventHandler != nil {
+		ctx = event.NewContext(ctx, o.EventHandler)
+	}
+	return ctx
+}
+
+// WithUsage sets the usage of the resources
+func WithUsage(u usage.Usage) EstimateOption {
+	return func(o *EstimateOptions) { o.Usage = u }
+}
+
+// WithProviders sets the providers supported
+func WithProviders(pis ...terraform.ProviderInitializer) EstimateOption {
+	return func(o *EstimateOptions) { o.ProviderInitializers = append(o.ProviderInitializers, pis...) }
+}
+
+// WithProviderConfig sets the configuration of the provider with the name (like 'aws')
+// used when the source has none, like the 'region' of the resources of a state
+func WithProviderConfig(name string, values map[string]string) EstimateOption {
+	return func(o *EstimateOptions) {
+		if o.ProviderConfigs == nil {
+			o.ProviderConfigs = make(map[string]map[string]string)
+		}
+		o.ProviderConfigs[name] = values
+	}
+}
+
+// WithFs sets the file system of the HCL
+func WithFs(fs afero.Fs) EstimateOption {
+	return func(o *EstimateOptions) { o.Fs = fs }
+}
+
+// WithModulePath sets the path of the module to estimate on the HCL stack
+func WithModulePath(p string) EstimateOption {
+	return func(o *EstimateOptions) { o.ModulePath = p }
+}
+
+// WithHCLOptions sets the options to read the HCL, merged with the ones set before:
+// only the fields set on the hclOpts are replaced and the VarFiles, Vars and
+// credentials are added to the previous ones
+func WithHCLOptions(hclOpts terraform.HCLOptions) EstimateOption {
+	return func(o *EstimateOptions) { o.HCL = mergeHCLOptions(o.HCL, hclOpts) }
+}
+
+// mergeHCLOptions returns the dst with the fields set on the src
+func mergeHCLOptions(dst, src terraform.HCLOptions) terraform.HCLOptions {
+	dst.VarFiles = append(append([]string(nil), dst.VarFiles...), src.VarFiles...)
+	dst.Vars = mergeMaps(dst.Vars, src.Vars)
+	dst.Credentials = mergeMaps(dst.Credentials, src.Credentials)
+	dst.GitCredentials = mergeMaps(dst.GitCredentials, src.GitCredentials)
+	if src.ModuleCacheDir != "" {
+		dst.ModuleCacheDir = src.ModuleCacheDir
+	}
+	if src.Offline {
+		dst.Offline = true
+	}
+	if src.ModuleFetcher != nil {
+		dst.ModuleFetcher = src.ModuleFetcher
+	}
+	if src.Logger != nil {
+		dst.Logger = src.Logger
+	}
+	return dst
+}
+
+// mergeMaps returns a new map with the values of a and b, the ones of b
+// taking precedence, or nil if both are empty
+func mergeMaps[V any](a, b map[string]V) map[string]V {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	m := make(map[string]V, len(a)+len(b))
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
+
+// WithModuleFetcher sets the terraform.ModuleFetcher of the remote modules of the HCL
+func WithModuleFetcher(mf terraform.ModuleFetcher) EstimateOption {
+	return func(o *EstimateOptions) { o.HCL.ModuleFetcher = mf }
+}
+
+// WithForceTerragrunt runs Terragrunt even if the module has no configuration
+func WithForceTerragrunt() EstimateOption {
+	return func(o *EstimateOptions) { o.ForceTerragrunt = true }
+}
+
+// WithTerragruntParallelism sets the parallelism of Terragrunt
+func WithTerragruntParallelism(p int) EstimateOption {
+	return func(o *EstimateOptions) { o.TerragruntParallelism = p }
+}
+
+// WithNativeTerragrunt parses the Terragrunt configurations instead of running Terragrunt
+func WithNativeTerragrunt() EstimateOption {
+	return func(o *EstimateOptions) { o.NativeTerragrunt = true }
+}
+
+// WithBestEffort keeps estimating the rest of the Terragrunt modules when one fails
+func WithBestEffort() EstimateOption {
+	return func(o *EstimateOptions) { o.BestEffort = true }
+}
+
+// WithDebug adds the Terragrunt logs to its errors
+func WithDebug() EstimateOption {
+	return func(o *EstimateOptions) { o.Debug = true }
+}
+
+// WithConcurrency sets the number of Terragrunt modules estimated at the same time
+func WithConcurrency(n int) EstimateOption {
+	return func(o *EstimateOptions) { o.Concurrency = n }
+}
+
+// WithLogger sets the logger of the estimation
+func WithLogger(l *slog.Logger) EstimateOption {
+	return func(o *EstimateOptions) { o.Logger = l }
+}
+
+// WithLogHandler sets a logger with the handler h for the estimation
+func WithLogHandler(h slog.Handler) EstimateOption {
+	return func(o *EstimateOptions) { o.Logger = slog.New(h) }
+}
+
+// WithEventHandler sets the handler of the events of the progress of the estimation
+func WithEventHandler(h event.Handler) EstimateOption {
+	return func(o *EstimateOptions) { o.EventHandler = h }
+}
+
+// WithCurrency only uses the prices with the currency
+func WithCurrency(c string) EstimateOption {
+	return func(o *EstimateOptions) { o.Currency = c }
+}
+
+// WithStrict returns an error if any component could not be priced
+func WithStrict() EstimateOption {
+	return func(o *EstimateOptions) { o.Strict = true }
+}
+
+// WithMaxPricingAge warns when the pricing used was ingested longer than d ago or never
+func WithMaxPricingAge(d time.Duration) EstimateOption {
+	return func(o *EstimateOptions) { o.MaxPricingAge = d }
+}
+
+// WithAsOf uses the prices that were effective at the time t instead of the current ones
+func WithAsOf(t time.Time) EstimateOption {
+	return func(o *EstimateOptions) { o.AsOf = t }
+}
+
+// PruneMode is what IngestPricing does with the prices that were not ingested
+type PruneMode int
+
+// List of the PruneModes
+const (
+	// PruneNone keeps all the prices, it's the default
+	PruneNone PruneMode = iota
+
+	// PruneDelete deletes the prices that were not ingested
+	PruneDelete
+
+	// PruneMarkStale marks the prices that were not ingested as stale so they are not
+	// used by the estimations, the price.Repository has to implement price.StaleMarker
+	PruneMarkStale
+)
+
+// IngestOptions are the options of IngestPricing, they are set with the IngestOption functions
+type IngestOptions struct {
+	// Prune is what is done with the prices of the provider, service and region ingested that
+	// are no longer on the pricing once the ingestion finished successfully, PruneNone by default
+	Prune PruneMode
+
+	// Transaction stores all the pricing in a transaction of the backend.Backend, which has to
+	// implement backend.Transactioner, that is only committed if the ingestion finishes successfully
+	Transaction bool
+
+	// BatchSize is the number of prices stored at once by the repositories that implement
+	// product.BulkUpserter and price.BulkUpserter, 500 by default
+	BatchSize int
+
+	// JobConcurrency is the number of jobs run at the same time by IngestPricingJobs, 1 by default
+	JobConcurrency int
+
+	// MaxAttempts is the number of times a job of IngestPricingJobs is run if it fails, 1 by default
+	MaxAttempts int
+
+	// RetryBackoff is the time waited before retrying a job, doubled on each attempt up to
+	// the MaxRetryBackoff if set, 1 second by default
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+
+	// Retryable returns if a job that failed with the error can be retried, all the errors but
+	// the cancellations of the context by default
+	Retryable func(err error) bool
+
+	// Partial records the ingestion.Run as Partial, as the Ingester only ingests some of the products
+	// of the service, so it's not taken into account by CheckFreshness. It can not be used with Prune.
+	Partial bool
+}
+
+// IngestOption sets an option of the IngestOptions
+type IngestOption func(*IngestOptions)
+
+// newIngestOptions returns the IngestOptions with the opts applied over the defaults
+func newIngestOptions(opts ...IngestOption) *IngestOptions {
+	o := &IngestOptions{
+		BatchSize:      500,
+		JobConcurrency: 1,
+		MaxAttempts:    1,
+		RetryBackoff:   time.Second,
+		Retryable:      retryable,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.BatchSize < 1 {
+		o.BatchSize = 1
+	}
+	if o.JobConcurrency < 1 {
+		o.JobConcurrency = 1
+	}
+	if o.MaxAttempts < 1 {
+		o.MaxAttempts = 1
+	}
+	return o
+}
+
+// WithPrune sets what is done with the prices that were not ingested
+func WithPrune(m PruneMode) IngestOption {
+	return func(o *IngestOptions) { o.Prune = m }
+}
+
+// WithPartial records the ingestion as partial, see IngestOptions.Partial
+func WithPartial() IngestOption {
+	return func(o *IngestOptions) { o.Partial = true }
+}
+
+// WithTransaction stores all the pricing in a transaction that is only committed if the ingestion succeeds
+func WithTransaction() IngestOption {
+	return func(o *IngestOptions) { o.Transaction = true }
+}
+
+// WithBatchSize sets the number of prices stored at once by the repositories that support it
+func WithBatchSize(n int) IngestOption {
+	return func(o *IngestOptions) { o.BatchSize = n }
+}
+
+// WithJobConcurrency sets the number of jobs run at the same time by IngestPricingJobs
+func WithJobConcurrency(n int) IngestOption {
+	return func(o *IngestOptions) { o.JobConcurrency = n }
+}
+
+// WithRetries runs the jobs of IngestPricingJobs that fail up to maxAttempts times, waiting the backoff
+// before the first retry and doubling it on each one of the next ones up to the maxBackoff, if not 0
+func WithRetries(maxAttempts int, backoff, maxBackoff time.Duration) IngestOption {
+	return func(o *IngestOptions) {
+		o.MaxAttempts = maxAttempts
+		o.RetryBackoff = backoff
+		o.MaxRetryBackoff = maxBackoff
+	}
+}
+
+// WithRetryable sets the function that returns if a job that failed with an error can be retried
+func WithRetryable(fn func(err error) bool) IngestOption {
+	return func(o *IngestOptions) { o.Retryable = fn }
+}
@@ -0,0 +1,35 @@
+package terracost
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cycloidio/terracost/terraform"
+)
+
+func TestWithHCLOptions(t *testing.T) {
+	mf := terraform.NewLocalModuleFetcher("/modules")
+
+	o := newEstimateOptions(
+		WithModuleFetcher(mf),
+		WithBestEffort(),
+		WithHCLOptions(terraform.HCLOptions{
+			VarFiles: []string{"a.tfvars"},
+			Vars:     map[string]string{"a": "1", "b": "1"},
+		}),
+		WithNativeTerragrunt(),
+		WithHCLOptions(terraform.HCLOptions{
+			VarFiles: []string{"b.tfvars"},
+			Vars:     map[string]string{"b": "2"},
+			Offline:  true,
+		}),
+	)
+
+	assert.True(t, o.BestEffort)
+	assert.True(t, o.NativeTerragrunt)
+	assert.NotNil(t, o.HCL.ModuleFetcher)
+	assert.True(t, o.HCL.Offline)
+	assert.Equal(t, []string{"a.tfvars", "b.tfvars"}, o.HCL.VarFiles)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, o.HCL.Vars)
+}
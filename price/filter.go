@@ -1,10 +1,16 @@
 package price
 
+import "time"
+
 // Filter is used to filter prices.
 type Filter struct {
 	Unit             *string
 	Currency         *string
 	AttributeFilters []*AttributeFilter
+
+	// AsOf filters the prices that were effective at that time
+	// instead of the current ones
+	AsOf *time.Time
 }
 
 // AttributeFilter is used for filtering of prices by attribute.
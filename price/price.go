@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/shopspring/decimal"
 
@@ -22,6 +23,14 @@ type Price struct {
 	Currency   string
 	Value      decimal.Decimal
 	Attributes map[string]string
+
+	// EffectiveFrom is when the Value started to be the price, when it's
+	// ingested it's the time of the ingestion if not set
+	EffectiveFrom time.Time
+
+	// EffectiveTo is when the Value stopped to be the price,
+	// zero if it's the current one
+	EffectiveTo time.Time
 }
 
 var (
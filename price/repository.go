@@ -19,3 +19,18 @@ type Repository interface {
 	// DeleteByProductWithKeep deletes all Prices of the specified product.ID except the ones with ID in the keep slice.
 	DeleteByProductWithKeep(ctx context.Context, productID product.ID, keep []ID) error
 }
+
+// StaleMarker is implemented by the Repository that can keep the retired prices marked as
+// stale instead of deleting them, the stale prices are not returned by the Filter.
+type StaleMarker interface {
+	// MarkStaleByProductWithKeep marks as stale all Prices of the specified product.ID except the ones with ID in
+	// the keep slice, which are the only ones not stale.
+	MarkStaleByProductWithKeep(ctx context.Context, productID product.ID, keep []ID) error
+}
+
+// BulkUpserter is implemented by the Repository that can upsert many Prices at once.
+type BulkUpserter interface {
+	// BulkUpsert updates the Prices or creates the ones that don't already exist, returning their
+	// IDs in the same order. The Product of each one of them must have its ID.
+	BulkUpsert(ctx context.Context, ps []*WithProduct) ([]ID, error)
+}
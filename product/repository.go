@@ -17,3 +17,10 @@ type Repository interface {
 	// Upsert updates a Product or creates a new one if it doesn't already exist.
 	Upsert(ctx context.Context, p *Product) (ID, error)
 }
+
+// BulkUpserter is implemented by the Repository that can upsert many Products at once.
+type BulkUpserter interface {
+	// BulkUpsert updates the Products or creates the ones that don't already exist, returning their
+	// IDs in the same order.
+	BulkUpsert(ctx context.Context, ps []*Product) ([]ID, error)
+}
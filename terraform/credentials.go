@@ -0,0 +1,115 @@
+package terraform
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/spf13/afero"
+)
+
+// GitCredentials are the credentials used to clone the modules of a Git host
+type GitCredentials struct {
+	// Username and Password (or token) are used for the HTTPS sources,
+	// if only the Password is set the Username defaults to 'oauth2'
+	Username string
+	Password string
+
+	// SSHKey is the PEM encoded private key used for the SSH sources
+	SSHKey []byte
+}
+
+// cliConfig is the part of the Terraform CLI configuration we care about
+type cliConfig struct {
+	Credentials []struct {
+		Host  string `hcl:"host,label"`
+		Token string `hcl:"token"`
+	} `hcl:"credentials,block"`
+
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// LoadCredentials reads the registry credentials from the Terraform CLI configuration file on the path,
+// like '.terraformrc' or 'credentials.tfrc.json'. It returns the token of each 'credentials' block by host.
+func LoadCredentials(fs afero.Fs, path string) (map[string]string, error) {
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file %q: %w", path, err)
+	}
+
+	var (
+		f     *hcl.File
+		diags hcl.Diagnostics
+	)
+	p := hclparse.NewParser()
+	if strings.HasSuffix(path, ".json") {
+		f, diags = p.ParseJSON(b, path)
+	} else {
+		f, diags = p.ParseHCL(b, path)
+	}
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse credentials file %q: %w", path, diags)
+	}
+
+	var cfg cliConfig
+	diags = gohcl.DecodeBody(f.Body, nil, &cfg)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to decode credentials file %q: %w", path, diags)
+	}
+
+	creds := make(map[string]string, len(cfg.Credentials))
+	for _, c := range cfg.Credentials {
+		creds[c.Host] = c.Token
+	}
+	return creds, nil
+}
+
+// withGitCredentials returns the package address pkg with the credentials of its host set,
+// if it is not a Git package or there are no credentials for it, it's returned as it is
+func withGitCredentials(pkg string, creds map[string]GitCredentials) (string, error) {
+	if len(creds) == 0 {
+		return pkg, nil
+	}
+
+	getter, src, ok := strings.Cut(pkg, "::")
+	if !ok || getter != "git" {
+		return pkg, nil
+	}
+
+	u, err := url.Parse(src)
+	if err != nil {
+		return "", fmt.Errorf("invalid Git module address: %w", err)
+	}
+
+	c, ok := creds[u.Hostname()]
+	if !ok {
+		return pkg, nil
+	}
+
+	switch u.Scheme {
+	case "ssh":
+		if len(c.SSHKey) == 0 {
+			return pkg, nil
+		}
+		q := u.Query()
+		q.Set("sshkey", base64.StdEncoding.EncodeToString(c.SSHKey))
+		u.RawQuery = q.Encode()
+	case "https", "http":
+		if c.Password == "" || u.User != nil {
+			return pkg, nil
+		}
+		un := c.Username
+		if un == "" {
+			un = "oauth2"
+		}
+		u.User = url.UserPassword(un, c.Password)
+	default:
+		return pkg, nil
+	}
+
+	return fmt.Sprintf("%s::%s", getter, u.String()), nil
+}
@@ -0,0 +1,26 @@
+package terraform_test
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cycloidio/terracost/terraform"
+)
+
+func TestLoadCredentials(t *testing.T) {
+	fs := afero.NewOsFs()
+	for _, p := range []string{"../testdata/terraform/terraformrc", "../testdata/terraform/credentials.tfrc.json"} {
+		t.Run(p, func(t *testing.T) {
+			creds, err := terraform.LoadCredentials(fs, p)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]string{"app.example.com": "registry-token"}, creds)
+		})
+	}
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := terraform.LoadCredentials(fs, "../testdata/terraform/potato")
+		assert.Error(t, err)
+	})
+}
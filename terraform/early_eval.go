@@ -0,0 +1,146 @@
+package terraform
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/terraform/configs"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// moduleCallStaticAttributes are the attributes of the module calls that Terraform
+// requires to be static and OpenTofu allows to use variables and locals on
+var moduleCallStaticAttributes = []string{"source", "version"}
+
+// loadConfigDir loads the module on the dir with the parser. OpenTofu allows to use variables and locals on
+// the 'source' and 'version' of the module calls, which Terraform does not support, so if the module fails
+// to load those are evaluated with the context returned by getEvalCtx and the module is loaded again.
+func loadConfigDir(logger *slog.Logger, fs afero.Fs, parser *configs.Parser, dir string, getEvalCtx func(*configs.Module) (*hcl.EvalContext, error)) (*configs.Module, error) {
+	mod, diags := parser.LoadConfigDir(dir)
+	if !diags.HasErrors() {
+		return mod, nil
+	}
+	if mod == nil {
+		return nil, diags
+	}
+
+	evalCtx, err := getEvalCtx(mod)
+	if err != nil {
+		return nil, err
+	}
+
+	efs, ok, err := evalModuleCallAttributes(fs, dir, evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, diags
+	}
+
+	logger.Debug("hcl: Evaluated module calls early", "path", dir)
+	mod, diags = configs.NewParser(efs).LoadConfigDir(dir)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return mod, nil
+}
+
+// hclEdit is the replacement of the range of a file
+type hclEdit struct {
+	rng   hcl.Range
+	value string
+}
+
+// evalModuleCallAttributes evaluates the non static module call attributes of the files on the dir with the
+// evalCtx. It returns a fs with the files of the dir with those attributes replaced by their values and
+// if any was replaced.
+func evalModuleCallAttributes(fs afero.Fs, dir string, evalCtx *hcl.EvalContext) (afero.Fs, bool, error) {
+	files, err := afero.Glob(fs, path.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list files of %q: %w", dir, err)
+	}
+
+	// The evaluated files are written to a layer in
+	// memory so the original ones are not modified
+	efs := afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(fs), afero.NewMemMapFs())
+	changed := false
+	for _, fn := range files {
+		src, err := afero.ReadFile(fs, fn)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read %q: %w", fn, err)
+		}
+
+		f, diags := hclsyntax.ParseConfig(src, fn, hcl.InitialPos)
+		if diags.HasErrors() {
+			// The parser will already report it
+			continue
+		}
+		body, ok := f.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		edits := make([]hclEdit, 0)
+		for _, b := range body.Blocks {
+			if b.Type != "module" || len(b.Labels) == 0 {
+				continue
+			}
+			for _, an := range moduleCallStaticAttributes {
+				attr, ok := b.Body.Attributes[an]
+				if !ok {
+					continue
+				}
+				if _, diags := attr.Expr.Value(nil); !diags.HasErrors() {
+					continue
+				}
+
+				val, diags := attr.Expr.Value(evalCtx)
+				if diags.HasErrors() {
+					return nil, false, fmt.Errorf("failed to evaluate %q of module %q: %w", an, b.Labels[0], diags)
+				}
+				if val.IsNull() || !val.IsWhollyKnown() || !val.Type().Equals(cty.String) {
+					return nil, false, fmt.Errorf("failed to evaluate %q of module %q: it must be a known string", an, b.Labels[0])
+				}
+				edits = append(edits, hclEdit{rng: attr.Expr.Range(), value: val.AsString()})
+			}
+		}
+		if len(edits) == 0 {
+			continue
+		}
+
+		// They are applied from the end so the
+		// ranges of the previous ones are still valid
+		sort.Slice(edits, func(i, j int) bool {
+			return edits[i].rng.Start.Byte > edits[j].rng.Start.Byte
+		})
+		for _, e := range edits {
+			nsrc := make([]byte, 0, len(src))
+			nsrc = append(nsrc, src[:e.rng.Start.Byte]...)
+			nsrc = append(nsrc, hclQuote(e.value)...)
+			nsrc = append(nsrc, src[e.rng.End.Byte:]...)
+			src = nsrc
+		}
+
+		err = afero.WriteFile(efs, fn, src, 0644)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to write %q: %w", fn, err)
+		}
+		changed = true
+	}
+
+	return efs, changed, nil
+}
+
+// hclQuote returns the s as an HCL string literal
+func hclQuote(s string) string {
+	q := strconv.Quote(s)
+	q = strings.ReplaceAll(q, "${", "$${")
+	return strings.ReplaceAll(q, "%{", "%%{")
+}
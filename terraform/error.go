@@ -7,4 +7,12 @@ var (
 	ErrNoQueries       = errors.New("no terraform entities found, looks empty")
 	ErrNoKnownProvider = errors.New("terraform providers are not yet supported")
 	ErrNoProviders     = errors.New("no valid providers found")
+
+	// ErrModuleNotInstalled is returned when on offline mode a remote
+	// module is not installed nor on the module cache
+	ErrModuleNotInstalled = errors.New("module not installed")
+
+	// ErrUnsupportedPlanFormat is returned when the format
+	// version of the plan is not supported
+	ErrUnsupportedPlanFormat = errors.New("unsupported plan format version")
 )
@@ -0,0 +1,165 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-svchost"
+	"github.com/hashicorp/terraform-svchost/auth"
+	"github.com/hashicorp/terraform-svchost/disco"
+	"github.com/hashicorp/terraform/addrs"
+	"github.com/hashicorp/terraform/getmodules"
+	"github.com/hashicorp/terraform/registry"
+	"github.com/hashicorp/terraform/registry/regsrc"
+	"github.com/spf13/afero"
+
+	"github.com/cycloidio/terracost/util"
+)
+
+// ModuleFetcher fetches the remote modules called from the HCL
+type ModuleFetcher interface {
+	// ModuleVersions returns all the versions of the registry module
+	ModuleVersions(ctx context.Context, module addrs.ModuleRegistryPackage) ([]string, error)
+
+	// ModuleLocation returns the address of the package with the
+	// version of the registry module
+	ModuleLocation(ctx context.Context, module addrs.ModuleRegistryPackage, version string) (string, error)
+
+	// FetchPackage downloads the package on the address pkg to the
+	// dir, which does not exist yet
+	FetchPackage(ctx context.Context, dir, pkg string) error
+}
+
+// remoteModuleFetcher fetches the modules from the registries
+// and the remote sources like Terraform does
+type remoteModuleFetcher struct {
+	client  *registry.Client
+	fetcher *getmodules.PackageFetcher
+}
+
+// NewModuleFetcher returns a ModuleFetcher that fetches the modules from the registries and remote sources, like
+// Terraform does. The credentials are the tokens of the private registries by host, as returned by LoadCredentials.
+func NewModuleFetcher(credentials map[string]string) (ModuleFetcher, error) {
+	creds := make(map[svchost.Hostname]map[string]interface{}, len(credentials))
+	for h, t := range credentials {
+		host, err := svchost.ForComparison(h)
+		if err != nil {
+			return nil, fmt.Errorf("invalid registry host %q: %w", h, err)
+		}
+		creds[host] = map[string]interface{}{"token": t}
+	}
+
+	return &remoteModuleFetcher{
+		client:  registry.NewClient(disco.NewWithCredentialsSource(auth.StaticCredentialsSource(creds)), nil),
+		fetcher: getmodules.NewPackageFetcher(),
+	}, nil
+}
+
+// ModuleVersions returns all the versions of the registry module
+func (f *remoteModuleFetcher) ModuleVersions(ctx context.Context, module addrs.ModuleRegistryPackage) ([]string, error) {
+	resp, err := f.client.ModuleVersions(ctx, regsrc.ModuleFromRegistryPackageAddr(module))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Modules) == 0 {
+		return nil, nil
+	}
+
+	versions := make([]string, 0, len(resp.Modules[0].Versions))
+	for _, v := range resp.Modules[0].Versions {
+		versions = append(versions, v.Version)
+	}
+	return versions, nil
+}
+
+// ModuleLocation returns the address of the package with the version of the registry module
+func (f *remoteModuleFetcher) ModuleLocation(ctx context.Context, module addrs.ModuleRegistryPackage, version string) (string, error) {
+	return f.client.ModuleLocation(ctx, regsrc.ModuleFromRegistryPackageAddr(module), version)
+}
+
+// FetchPackage downloads the package on the address pkg to the dir
+func (f *remoteModuleFetcher) FetchPackage(ctx context.Context, dir, pkg string) error {
+	return f.fetcher.FetchPackage(ctx, dir, pkg)
+}
+
+// localModuleFetcher fetches the modules from a local directory
+type localModuleFetcher struct {
+	dir string
+}
+
+// NewLocalModuleFetcher returns a ModuleFetcher that fetches the modules from the OS directory dir instead of the
+// network. It's meant to be used on tests or on environments without access to the registries and Git hosts.
+//
+// The registry modules are expected on 'HOST/NAMESPACE/NAME/SYSTEM/VERSION', like 'registry.terraform.io/terraform-aws-modules/vpc/aws/5.0.0',
+// and the rest of the packages on 'HOST/PATH' or 'HOST/PATH/REF' if they have a 'ref' query, like 'github.com/cycloidio/terracost/v0.5.2'.
+// Local file packages are copied from its path.
+func NewLocalModuleFetcher(dir string) ModuleFetcher {
+	return &localModuleFetcher{dir: dir}
+}
+
+// ModuleVersions returns all the versions of the registry module
+func (f *localModuleFetcher) ModuleVersions(ctx context.Context, module addrs.ModuleRegistryPackage) ([]string, error) {
+	entries, err := os.ReadDir(f.modulePath(module))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read module %s: %w", module, err)
+	}
+
+	versions := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			versions = append(versions, e.Name())
+		}
+	}
+	return versions, nil
+}
+
+// ModuleLocation returns the path of the version of the registry module
+func (f *localModuleFetcher) ModuleLocation(ctx context.Context, module addrs.ModuleRegistryPackage, version string) (string, error) {
+	p, err := filepath.Abs(filepath.Join(f.modulePath(module), version))
+	if err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+// FetchPackage copies the directory of the package pkg to the dir
+func (f *localModuleFetcher) FetchPackage(ctx context.Context, dir, pkg string) error {
+	src := pkg
+	if _, s, ok := strings.Cut(pkg, "::"); ok {
+		src = s
+	}
+
+	u, err := url.Parse(src)
+	if err != nil {
+		return fmt.Errorf("invalid package address %q: %w", pkg, err)
+	}
+
+	var p string
+	if u.Scheme == "file" {
+		p = u.Path
+	} else {
+		p = filepath.Join(f.dir, u.Hostname(), strings.TrimSuffix(u.Path, ".git"))
+		if ref := u.Query().Get("ref"); ref != "" {
+			p = filepath.Join(p, ref)
+		}
+	}
+
+	if ok, _ := afero.DirExists(afero.NewOsFs(), p); !ok {
+		return fmt.Errorf("package %q not found on %q", pkg, p)
+	}
+
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return fmt.Errorf("could not create path %q: %w", dir, err)
+	}
+	return util.FromOSToAfero(afero.NewOsFs(), p, dir)
+}
+
+// modulePath returns the path of the registry module
+func (f *localModuleFetcher) modulePath(module addrs.ModuleRegistryPackage) string {
+	return filepath.Join(f.dir, module.Host.String(), module.Namespace, module.Name, module.TargetSystem)
+}
@@ -1,9 +1,8 @@
 package terraform
 
 import (
-	"context"
 	"fmt"
-	"os"
+	"log/slog"
 	"path"
 	"sort"
 	"strconv"
@@ -14,18 +13,14 @@ import (
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/hashicorp/terraform/addrs"
 	"github.com/hashicorp/terraform/configs"
-	"github.com/hashicorp/terraform/getmodules"
 	"github.com/hashicorp/terraform/lang"
-	"github.com/hashicorp/terraform/registry"
-	"github.com/hashicorp/terraform/registry/regsrc"
 	"github.com/spf13/afero"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 	"github.com/zclconf/go-cty/cty/gocty"
 
-	"github.com/cycloidio/terracost/log"
 	"github.com/cycloidio/terracost/query"
 	"github.com/cycloidio/terracost/usage"
-	"github.com/cycloidio/terracost/util"
 )
 
 const (
@@ -36,15 +31,56 @@ const (
 )
 
 // ExtractQueriesFromHCL returns the resources found in the module identified by the modPath.
+// The inputs are the values of the variables set by Terragrunt.
 func ExtractQueriesFromHCL(fs afero.Fs, providerInitializers []ProviderInitializer, modPath string, u usage.Usage, inputs map[string]interface{}) ([]query.Resource, string, error) {
+	return ExtractQueriesFromHCLWithOptions(fs, providerInitializers, modPath, u, inputs, HCLOptions{})
+}
+
+// ExtractQueriesFromHCLWithOptions is like ExtractQueriesFromHCL but the opts allow to set
+// the variable definition files and values of the root module and how the remote modules are fetched.
+func ExtractQueriesFromHCLWithOptions(fs afero.Fs, providerInitializers []ProviderInitializer, modPath string, u usage.Usage, inputs map[string]interface{}, opts HCLOptions) ([]query.Resource, string, error) {
+	mod, modName, err := ReadHCLModule(fs, providerInitializers, modPath, inputs, opts)
+	if err != nil {
+		return nil, modName, err
+	}
+	return mod.Queries(u), modName, nil
+}
+
+// HCLModule is a module read by ReadHCLModule, the queries of its resources
+// are returned by Queries with each usage without reading it again
+type HCLModule struct {
+	instances []hclModuleInstance
+}
+
+// hclModuleInstance are the resources of an instance of a module, which can reference each other,
+// with the providers of them
+type hclModuleInstance struct {
+	providers map[string]Provider
+	resources map[string]Resource
+}
+
+// ReadHCLModule reads the module identified by the modPath, and its child modules, like ExtractQueriesFromHCLWithOptions.
+// It also returns the names of the module calls of the root module, even with the errors.
+func ReadHCLModule(fs afero.Fs, providerInitializers []ProviderInitializer, modPath string, inputs map[string]interface{}, opts HCLOptions) (*HCLModule, string, error) {
 	parser := configs.NewParser(fs)
-	log.Logger.Debug("hcl: Loading module", "path", modPath)
-	mod, diags := parser.LoadConfigDir(modPath)
-	if diags.HasErrors() {
-		return nil, "", fmt.Errorf(diags.Error())
+	logger := opts.logger()
+	logger.Debug("hcl: Loading module", "path", modPath)
+	rootEvalCtx := func(mod *configs.Module) (*hcl.EvalContext, error) {
+		vars, err := getRootVariableValues(fs, parser, mod, modPath, inputs, opts)
+		if err != nil {
+			return nil, err
+		}
+		return getEvalCtx(logger, mod, vars), nil
+	}
+	mod, err := loadConfigDir(logger, fs, parser, modPath, rootEvalCtx)
+	if err != nil {
+		return nil, "", err
 	}
 
-	evalCtx := getEvalCtx(mod, nil, inputs)
+	evalCtx, err := rootEvalCtx(mod)
+	if err != nil {
+		return nil, "", err
+	}
 
 	modules := make([]string, 0, 0)
 	for k := range mod.ModuleCalls {
@@ -54,7 +90,7 @@ func ExtractQueriesFromHCL(fs afero.Fs, providerInitializers []ProviderInitializ
 
 	modName := strings.Join(modules, ", ")
 
-	providers, err := getHCLProviders(mod, evalCtx, providerInitializers)
+	providers, err := getHCLProviders(logger, mod, evalCtx, providerInitializers)
 	if err != nil {
 		return nil, modName, err
 	}
@@ -63,24 +99,68 @@ func ExtractQueriesFromHCL(fs afero.Fs, providerInitializers []ProviderInitializ
 	for pn := range providers {
 		pns = append(pns, pn)
 	}
-	log.Logger.Debug("hcl: Providers found", "providers", pns)
+	opts.logger().Debug("hcl: Providers found", "providers", pns)
+
+	mi, err := newModuleInstaller(fs, modPath, opts)
+	if err != nil {
+		return nil, modName, err
+	}
 
-	queries, err := extractHCLModule(fs, providers, parser, modPath, "", mod, 1, evalCtx, u)
+	instances, err := extractHCLModule(fs, providers, parser, mi, modPath, "", "", mod, 1, evalCtx)
 	if err != nil {
 		return nil, modName, err
 	}
 
-	err = validateProviders(queries, providers)
+	// The providers are validated on the resources as the
+	// components of them depend on the usage of the queries
+	resources := make([]query.Resource, 0)
+	for _, mi := range instances {
+		for _, r := range mi.resources {
+			resources = append(resources, query.Resource{Address: r.Address, Type: r.Type, Provider: r.ProviderName})
+		}
+	}
+	err = validateProviders(resources, providers)
 	if err != nil {
 		return nil, modName, err
 	}
 
-	return queries, modName, nil
+	return &HCLModule{instances: instances}, modName, nil
 }
 
-// extractHCLModule returns the resources found in the provided module.
-func extractHCLModule(fs afero.Fs, providers map[string]Provider, parser *configs.Parser, modPath, modName string, mod *configs.Module, mcount int, evalCtx *hcl.EvalContext, u usage.Usage) ([]query.Resource, error) {
-	queries := make([]query.Resource, 0, len(mod.ManagedResources))
+// Queries returns the resources of the module with the usage u
+func (m *HCLModule) Queries(u usage.Usage) []query.Resource {
+	queries := make([]query.Resource, 0)
+	for _, mi := range m.instances {
+		// The values of the resources are copied so the
+		// usage of each call is not shared with the others
+		rss := make(map[string]Resource, len(mi.resources))
+		for k, r := range mi.resources {
+			values := make(map[string]interface{}, len(r.Values)+1)
+			for vk, v := range r.Values {
+				values[vk] = v
+			}
+			values[usage.Key] = u.GetResourceUsage(r.Address, r.Type)
+			r.Values = values
+			rss[k] = r
+		}
+
+		for _, r := range rss {
+			provider := mi.providers[r.ProviderName]
+			queries = append(queries, query.Resource{
+				Address:    r.Address,
+				Type:       r.Type,
+				Provider:   r.ProviderName,
+				Components: provider.ResourceComponents(rss, r),
+			})
+		}
+	}
+	return queries
+}
+
+// extractHCLModule returns the resources found in the provided module and its child modules. The modKey is the path of
+// module calls from the root module, like 'ec2.ebs', used to find the installed remote modules.
+func extractHCLModule(fs afero.Fs, providers map[string]Provider, parser *configs.Parser, mi *moduleInstaller, modPath, modName, modKey string, mod *configs.Module, mcount int, evalCtx *hcl.EvalContext) ([]hclModuleInstance, error) {
+	logger := mi.opts.logger()
 
 	rss := make(map[string]Resource)
 	for rk, rv := range mod.ManagedResources {
@@ -91,72 +171,17 @@ func extractHCLModule(fs afero.Fs, providers map[string]Provider, parser *config
 		}
 		provider := providers[providerKey]
 
-		each := make(map[string]interface{})
-		if rv.ForEach != nil {
-			if fe, ok := rv.ForEach.(*hclsyntax.ForExpr); ok {
-				fev, err := fe.Value(evalCtx)
-				if err != nil {
-					log.Logger.Error("hcl: could not get value from ForEach", err, err)
-				} else {
-					v, ok := convertCtyValue("", nil, fev)
-					if !ok {
-						// TODO: Return an error?
-					}
-					each = v.(map[string]interface{})
-				}
-			}
-		}
-		// When we only have to do it once
-		if len(each) == 0 {
-			each[""] = nil
-		}
 		// Parse the HCL body of the resource block and evaluate it. The JSON (in the form of map[string]interface{} type)
 		// is then placed into the cfg.
 		body, ok := rv.Config.(*hclsyntax.Body)
 		if !ok {
 			return nil, fmt.Errorf("invalid resource configuration body")
 		}
-		for k, v := range each {
-			if v != nil {
-				delete(evalCtx.Variables, "each")
-				vals := make(map[string]cty.Value)
-				types := make(map[string]cty.Type)
-				switch vt := v.(type) {
-				case map[string]interface{}:
-					for kv, vv := range vt {
-						it, err := goTypeToCty(vv)
-						if err != nil {
-							continue
-						}
-						ctyv, err := gocty.ToCtyValue(vv, it)
-						if err != nil {
-							continue
-						}
-						vals[kv] = ctyv
-						types[kv] = it
-					}
-				default:
-					it, err := goTypeToCty(vt)
-					if err != nil {
-						continue
-					}
-					ctyv, err := gocty.ToCtyValue(vt, it)
-					if err != nil {
-						continue
-					}
-					vals[k] = ctyv
-					types[k] = it
-				}
-
-				ctyv, err := gocty.ToCtyValue(vals, cty.Object(types))
-				if err != nil {
-					continue
-				}
 
-				// TODO: potentially overwrite the rk with the [k]
-				evalCtx.Variables["each"] = cty.ObjectVal(map[string]cty.Value{"value": ctyv})
-			}
-			cfg := getBodyJSON(modName, body, evalCtx)
+		// Each instance of the resource, expanded from the 'count' or 'for_each',
+		// is evaluated with its own 'count.index' or 'each.key'/'each.value'
+		for _, inst := range expandInstances(logger, rv.Count, rv.ForEach, evalCtx) {
+			cfg := getBodyJSON(logger, modName, body, withInstanceVars(evalCtx, inst.vars))
 			// We delete the `for_each` key as we do not need it
 			delete(cfg, "for_each")
 
@@ -169,57 +194,21 @@ func extractHCLModule(fs afero.Fs, providers map[string]Provider, parser *config
 						nrk = fmt.Sprintf("%s.%s", modName, rk)
 					}
 				}
-				// k is empty when there is not 'for_each'
-				if k == "" {
-					// Assume this is a single instance of this resource unless the cfg contains the "count" parameter.
-					count := 1
-					if c, ok := cfg["count"]; ok {
-						if cf, ok := c.(float64); ok {
-							count = int(cf)
-						}
-					}
-
-					if count != 1 {
-						log.Logger.Debug("hcl: Found count on resource", "count", count, "resource", nrk)
-					}
-					for i := 0; i < count; i++ {
-						addr := nrk
-						if count > 1 {
-							addr = fmt.Sprintf("%s[%d]", nrk, i)
-						}
-
-						// Only retrieve resource if the provider is valid. If it's not, the comps will be nil, which signifies
-						// that the resource was "skipped" from estimation.
-						if provider != nil {
-							rss[addr] = Resource{
-								Address:      addr,
-								Index:        i,
-								Mode:         "managed",
-								Type:         rv.Type,
-								Name:         rv.Name,
-								ProviderName: rv.Provider.Type,
-								Values:       cfg,
-							}
-							log.Logger.Debug("hcl: Found resource", "resource", rss[addr])
-						}
-					}
-				} else {
-					addr := nrk
-					addr = fmt.Sprintf("%s[%s]", nrk, k)
-
-					// Only retrieve resource if the provider is valid. If it's not, the comps will be nil, which signifies
-					// that the resource was "skipped" from estimation.
-					if provider != nil {
-						rss[addr] = Resource{
-							Address:      addr,
-							Mode:         "managed",
-							Type:         rv.Type,
-							Name:         rv.Name,
-							ProviderName: rv.Provider.Type,
-							Values:       cfg,
-						}
-						log.Logger.Debug("hcl: Found resource", "resource", rss[addr])
+				addr := nrk + inst.key
+
+				// Only retrieve resource if the provider is valid. If it's not, the comps will be nil, which signifies
+				// that the resource was "skipped" from estimation.
+				if provider != nil {
+					rss[addr] = Resource{
+						Address:      addr,
+						Index:        inst.index,
+						Mode:         "managed",
+						Type:         rv.Type,
+						Name:         rv.Name,
+						ProviderName: rv.Provider.Type,
+						Values:       cfg,
 					}
+					mi.opts.logger().Debug("hcl: Found resource", "resource", rss[addr])
 				}
 			}
 		}
@@ -267,44 +256,30 @@ func extractHCLModule(fs afero.Fs, providers map[string]Provider, parser *config
 				rss[kr].Values[k] = vals
 			}
 		}
-		r.Values[usage.Key] = u.GetUsage(r.Type)
-		provider := providers[r.ProviderName]
-		queries = append(queries, query.Resource{
-			Address:    r.Address,
-			Type:       r.Type,
-			Provider:   r.ProviderName,
-			Components: provider.ResourceComponents(rss, r),
-		})
 	}
+	instances := []hclModuleInstance{{providers: providers, resources: rss}}
 
 	// Recursively extract resources from all child module calls.
 	for mk, mv := range mod.ModuleCalls {
 		p := joinPath(modPath, mv.SourceAddr.String())
 
-		log.Logger.Debug("hcl: Found child module", "path", p)
+		nextModKey := mk
+		if modKey != "" {
+			nextModKey = fmt.Sprintf("%s.%s", modKey, mk)
+		}
+
+		mi.opts.logger().Debug("hcl: Found child module", "path", p)
 		// EntersNewPackage checks if the module is a local
 		// one or a Remote one.
 		if mv.EntersNewPackage() {
-			dir, err := installModule(fs, mv)
+			// The module call is not changed as it's shared by all
+			// the instances of the module, with 'count' or 'for_each'
+			dir, err := mi.install(nextModKey, mv)
 			if err != nil {
 				return nil, fmt.Errorf("failed to install remote module: %w", err)
 			}
-			maddr, err := addrs.ParseModuleSource(dir)
-			if err != nil {
-				return nil, fmt.Errorf("failed parse module source %q: %w", dir, err)
-			}
-			// We ignore the SourceAddrRange for now
-			// as it's the reference to where the Source is
-			// located on the file which we do not need anymore
-			mv.SourceAddr = maddr
-			mv.SourceAddrRaw = dir
 			p = dir
-			log.Logger.Debug("hcl: Was a remote module, pulled to new path", "path", p, "source_addr", maddr, "source_addr_raw", dir)
-		}
-
-		child, diags := parser.LoadConfigDir(p)
-		if diags.HasErrors() {
-			return nil, fmt.Errorf("failed to load config dir: %w", diags)
+			mi.opts.logger().Debug("hcl: Was a remote module, pulled to new path", "path", p)
 		}
 
 		body, ok := mv.Config.(*hclsyntax.Body)
@@ -312,57 +287,12 @@ func extractHCLModule(fs afero.Fs, providers map[string]Provider, parser *config
 			return nil, fmt.Errorf("invalid module call body")
 		}
 
-		// Extract variables from the module call block to pass down to the module. It's a map of
-		// variable names to their evaluated values.
-		vars := make(map[string]cty.Value)
-		for _, attr := range body.Attributes {
-			// TODO: Check if the attribute has variables
-			// and if so read those first, add the values to the CTX
-			// and then evaluate the current one with that new context
-			// with the defined value
-
-			if _, ok := vars[attr.Name]; ok {
-				// This means it has been pulled before as a dependency
-				continue
-			}
-			for _, vr := range attr.Expr.Variables() {
-				v := string(hclwrite.TokensForTraversal(vr).Bytes())
-				sv := strings.Split(v, ".")
-				if val, ok := vars[sv[1]]; ok {
-					appendToCtx(evalCtx, sv[0], sv[1], val)
-				} else if sv[0] == "var" || sv[0] == "local" {
-					depAttr, ok := body.Attributes[sv[1]]
-					if ok {
-						val, diags := depAttr.Expr.Value(evalCtx)
-						if diags != nil && diags.HasErrors() {
-							log.Logger.Error("hcl: Error on abstracting value for 'vars'", "name", depAttr.Name, "reason", diags.Error())
-							continue
-						}
-						appendToCtx(evalCtx, sv[0], depAttr.Name, val)
-						vars[depAttr.Name] = val
-					}
-				}
-			}
-			val, diags := attr.Expr.Value(evalCtx)
-			if diags != nil && diags.HasErrors() {
-				log.Logger.Error("hcl: Error on abstracting value for 'vars'", "name", attr.Name, "reason", diags.Error())
-				continue
-			}
-			vars[attr.Name] = val
-		}
-
-		nextEvalCtx := getEvalCtx(child, vars, nil)
-
-		// TODO: Check if this should use nextEvalCtx
-		log.Logger.Debug("hcl: Fetching module count")
-		mcfg := getBodyJSON(modName, body, nextEvalCtx)
-		nmcount := 1
-		if c, ok := mcfg["count"]; ok {
-			if cf, ok := c.(float64); ok {
-				nmcount = int(cf)
-			}
+		child, err := loadConfigDir(logger, fs, parser, p, func(child *configs.Module) (*hcl.EvalContext, error) {
+			return getEvalCtx(logger, child, getModuleCallVars(logger, body, evalCtx)), nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config dir: %w", err)
 		}
-		log.Logger.Debug("hcl: End fetching module count")
 
 		// If the module call contains a `providers` block, it should replace the implicit provider
 		// inheritance. Instead, a new map of parent to child providers is created.
@@ -390,73 +320,225 @@ func extractHCLModule(fs afero.Fs, providers map[string]Provider, parser *config
 			nextModPath = fmt.Sprintf("module.%s", mk)
 		}
 
-		qs, err := extractHCLModule(fs, childProvs, parser, p, nextModPath, child, nmcount, nextEvalCtx, u)
+		// A module call with 'for_each' is expanded into one module instance per element,
+		// each one of them receiving the inputs evaluated with its own 'each.key'/'each.value'
+		if mv.ForEach != nil {
+			if each, ok := evalForEach(logger, mv.ForEach, evalCtx); ok {
+				mi.opts.logger().Debug("hcl: Found for_each on module", "module", nextModPath, "count", len(each))
+				for _, k := range sortedKeys(each) {
+					vars := getModuleCallVars(logger, body, withInstanceVars(evalCtx, map[string]cty.Value{"each": each[k]}))
+					nextEvalCtx := getEvalCtx(logger, child, vars)
+
+					is, err := extractHCLModule(fs, childProvs, parser, mi, p, fmt.Sprintf("%s[%q]", nextModPath, k), nextModKey, child, 1, nextEvalCtx)
+					if err != nil {
+						return nil, err
+					}
+					instances = append(instances, is...)
+				}
+				continue
+			}
+		}
+
+		vars := getModuleCallVars(logger, body, evalCtx)
+		nextEvalCtx := getEvalCtx(logger, child, vars)
+
+		// TODO: Check if this should use nextEvalCtx
+		mi.opts.logger().Debug("hcl: Fetching module count")
+		mcfg := getBodyJSON(logger, modName, body, nextEvalCtx)
+		nmcount := 1
+		if c, ok := mcfg["count"]; ok {
+			if cf, ok := c.(float64); ok {
+				nmcount = int(cf)
+			}
+		}
+		mi.opts.logger().Debug("hcl: End fetching module count")
+
+		is, err := extractHCLModule(fs, childProvs, parser, mi, p, nextModPath, nextModKey, child, nmcount, nextEvalCtx)
 		if err != nil {
 			return nil, err
 		}
-		queries = append(queries, qs...)
+		instances = append(instances, is...)
 	}
 
-	return queries, nil
+	return instances, nil
 }
 
-// installModule will walk the ModuleCall and download any remote module
-func installModule(fs afero.Fs, mc *configs.ModuleCall) (string, error) {
-	dir, err := os.MkdirTemp("", "terracost")
-	if err != nil {
-		return "", fmt.Errorf("failed to create a temp dir: %w", err)
-	}
-	// We remove it to create just the folder and then
-	// we pass the 'dir' path to the Fetcher and we know
-	// it's a valid unexistent dir. If we did not delete it
-	// the 'FetchPackage' would try to 'git pull' instead
-	// of 'git clone'
-	os.RemoveAll(dir)
-	fetcher := getmodules.NewPackageFetcher()
-	src := mc.SourceAddr.String()
-	ctx := context.Background()
-	if strings.Contains(src, "registry") {
-		rm, err := regsrc.ParseModuleSource(src)
-		if err != nil {
-			return "", fmt.Errorf("failed to parse module source %s: %w", src, err)
+// getModuleCallVars extracts the variables from the module call block to pass down to the module.
+// It's a map of variable names to their evaluated values.
+func getModuleCallVars(logger *slog.Logger, body *hclsyntax.Body, evalCtx *hcl.EvalContext) map[string]cty.Value {
+	vars := make(map[string]cty.Value)
+	for _, attr := range body.Attributes {
+		// TODO: Check if the attribute has variables
+		// and if so read those first, add the values to the CTX
+		// and then evaluate the current one with that new context
+		// with the defined value
+
+		if _, ok := vars[attr.Name]; ok {
+			// This means it has been pulled before as a dependency
+			continue
 		}
-		// we initialize the client with default values
-		// so everything is nil
-		rc := registry.NewClient(nil, nil)
-		noVersion := ""
-		ml, err := rc.ModuleLocation(ctx, rm, noVersion)
-		if err != nil {
-			return "", fmt.Errorf("failed to locate module %s: %w", src, err)
+		for _, vr := range attr.Expr.Variables() {
+			v := string(hclwrite.TokensForTraversal(vr).Bytes())
+			sv := strings.Split(v, ".")
+			// The instance variables (each/count) are already
+			// set on the context and must not be overwritten
+			if len(sv) < 2 || sv[0] == "each" || sv[0] == "count" {
+				continue
+			}
+			if val, ok := vars[sv[1]]; ok {
+				appendToCtx(logger, evalCtx, sv[0], sv[1], val)
+			} else if sv[0] == "var" || sv[0] == "local" {
+				depAttr, ok := body.Attributes[sv[1]]
+				if ok {
+					val, diags := depAttr.Expr.Value(evalCtx)
+					if diags != nil && diags.HasErrors() {
+						logger.Error("hcl: Error on abstracting value for 'vars'", "name", depAttr.Name, "reason", diags.Error())
+						continue
+					}
+					appendToCtx(logger, evalCtx, sv[0], depAttr.Name, val)
+					vars[depAttr.Name] = val
+				}
+			}
+		}
+		val, diags := attr.Expr.Value(evalCtx)
+		if diags != nil && diags.HasErrors() {
+			logger.Error("hcl: Error on abstracting value for 'vars'", "name", attr.Name, "reason", diags.Error())
+			continue
 		}
-		src = ml
+		vars[attr.Name] = val
 	}
-	err = fetcher.FetchPackage(ctx, dir, src)
-	if err != nil {
-		return "", fmt.Errorf("failed to download the module %q: %w", src, err)
+	return vars
+}
+
+// hclInstance is a single instance of a resource or module call, expanded
+// from its 'count' or 'for_each' meta-arguments
+type hclInstance struct {
+	// key is the suffix of the instance on the address, like '[0]' or '["name"]',
+	// it's empty when there is only one instance
+	key string
+
+	// index is the 'count.index' or 'each.key' of the instance
+	index interface{}
+
+	// vars are the 'count' or 'each' variables that have to be
+	// set on the context when evaluating the instance
+	vars map[string]cty.Value
+}
+
+// expandInstances returns all the instances of a block with the count and forEach meta-arguments.
+// If none is set, or the values are not known, a single instance is returned.
+func expandInstances(logger *slog.Logger, count, forEach hcl.Expression, evalCtx *hcl.EvalContext) []hclInstance {
+	if forEach != nil {
+		each, ok := evalForEach(logger, forEach, evalCtx)
+		if ok {
+			instances := make([]hclInstance, 0, len(each))
+			for _, k := range sortedKeys(each) {
+				instances = append(instances, hclInstance{
+					key:   fmt.Sprintf("[%q]", k),
+					index: k,
+					vars:  map[string]cty.Value{"each": each[k]},
+				})
+			}
+			return instances
+		}
 	}
 
-	// If the dir already exists we assume it's the right Fs
-	// so no need to copy the content
-	if ok, _ := afero.DirExists(fs, dir); ok {
-		return dir, nil
+	// Assume this is a single instance unless the "count" is set and known
+	n := 1
+	if count != nil {
+		val, diags := count.Value(evalCtx)
+		if !diags.HasErrors() && val.IsWhollyKnown() && !val.IsNull() && val.Type() == cty.Number {
+			c, _ := val.AsBigFloat().Int64()
+			n = int(c)
+			logger.Debug("hcl: Found count", "count", n)
+		}
 	}
 
-	// Once everything is copied we can remove it
-	defer os.RemoveAll(dir)
+	instances := make([]hclInstance, 0, n)
+	for i := 0; i < n; i++ {
+		inst := hclInstance{
+			index: i,
+			vars: map[string]cty.Value{
+				"count": cty.ObjectVal(map[string]cty.Value{"index": cty.NumberIntVal(int64(i))}),
+			},
+		}
+		if n > 1 {
+			inst.key = fmt.Sprintf("[%d]", i)
+		}
+		instances = append(instances, inst)
+	}
+	return instances
+}
 
-	err = fs.MkdirAll(dir, 0700)
-	if err != nil {
-		return "", fmt.Errorf("could not create path %q: %w", dir, err)
+// evalForEach evaluates the 'for_each' expression and returns the 'each' object of every element
+// indexed by its key. Maps and objects use their keys and sets use the values as keys, as Terraform does.
+// The second return is false if the value could not be evaluated or is not known yet.
+func evalForEach(logger *slog.Logger, expr hcl.Expression, evalCtx *hcl.EvalContext) (map[string]cty.Value, bool) {
+	val, diags := expr.Value(evalCtx)
+	if diags.HasErrors() {
+		logger.Debug("hcl: could not get value from for_each", "reason", diags.Error())
+		return nil, false
 	}
-	err = util.FromOSToAfero(fs, dir, dir)
-	if err != nil {
-		return "", fmt.Errorf("failed to copy the module to %s: %w", dir, err)
+	if val.IsNull() || !val.IsWhollyKnown() || !val.CanIterateElements() {
+		return nil, false
 	}
-	return dir, nil
+
+	ty := val.Type()
+	each := make(map[string]cty.Value)
+	for it := val.ElementIterator(); it.Next(); {
+		k, v := it.Element()
+		if ty.IsSetType() {
+			k = v
+		}
+		sk, err := convert.Convert(k, cty.String)
+		if err != nil || sk.IsNull() {
+			logger.Debug("hcl: invalid for_each key", "key", k.GoString())
+			continue
+		}
+		each[sk.AsString()] = cty.ObjectVal(map[string]cty.Value{
+			"key":   sk,
+			"value": v,
+		})
+	}
+	return each, true
+}
+
+// withInstanceVars returns a copy of the evalCtx with the vars set, so the
+// instance variables do not leak to other blocks evaluated with evalCtx
+func withInstanceVars(evalCtx *hcl.EvalContext, vars map[string]cty.Value) *hcl.EvalContext {
+	nctx := &hcl.EvalContext{
+		Variables: make(map[string]cty.Value, len(evalCtx.Variables)+len(vars)),
+		Functions: evalCtx.Functions,
+	}
+	for k, v := range evalCtx.Variables {
+		nctx.Variables[k] = v
+	}
+	for k, v := range vars {
+		nctx.Variables[k] = v
+	}
+	return nctx
+}
+
+// sortedKeys returns the keys of m sorted, the numeric keys (from lists)
+// are sorted by their value so they keep the order of the list
+func sortedKeys(m map[string]cty.Value) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ni, erri := strconv.Atoi(keys[i])
+		nj, errj := strconv.Atoi(keys[j])
+		if erri == nil && errj == nil {
+			return ni < nj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
 }
 
 // getEvalCtx returns the evaluation context of the given module with variable values set.
-func getEvalCtx(mod *configs.Module, vars map[string]cty.Value, inputs map[string]interface{}) *hcl.EvalContext {
+func getEvalCtx(logger *slog.Logger, mod *configs.Module, vars map[string]cty.Value) *hcl.EvalContext {
 	lvars := make(map[string]interface{})
 	llocal := make(map[string]interface{})
 	// Set default values for undefined variables.
@@ -467,28 +549,10 @@ func getEvalCtx(mod *configs.Module, vars map[string]cty.Value, inputs map[strin
 	for vk, vv := range mod.Variables {
 		if _, ok := vars[vk]; !ok {
 			vars[vk] = vv.Default
-			lv, ok := convertCtyValue("", nil, vv.Default)
-			if ok {
-				lvars[vk] = lv
-			}
 		}
-		if iv, ok := inputs[vk]; ok {
-			iv, it := convertGoTypesToExpectedCtyType(iv, vv.Type)
-			ctyv, err := gocty.ToCtyValue(iv, it)
-			if err != nil {
-				log.Logger.Error("hcl: Error on abstracting value for 'input'", "key", vk, "reason", err.Error())
-				// NOTE: There are some types that we don't how to
-				// parse yet but we want to continue so we ignore
-				// the error
-				continue
-			}
-			vars[vk] = ctyv
-
-			lv, ok := convertCtyValue("", nil, ctyv)
-			if ok {
-				lvars[vk] = lv
-			}
-			continue
+		lv, ok := convertCtyValue("", nil, vars[vk])
+		if ok {
+			lvars[vk] = lv
 		}
 	}
 
@@ -502,12 +566,18 @@ func getEvalCtx(mod *configs.Module, vars map[string]cty.Value, inputs map[strin
 		Functions: scope.Functions(),
 	}
 
+	// Set the data sources so the attributes defined on
+	// them can be referenced with `data.*`
+	if len(mod.DataResources) != 0 {
+		evalCtx.Variables["data"] = getDataSourcesValue(mod, evalCtx)
+	}
+
 	// Set values of locals.
 	lm := make(map[string]cty.Value)
 	for lk, lv := range mod.Locals {
 		val, diags := lv.Expr.Value(evalCtx)
 		if diags != nil && diags.HasErrors() {
-			log.Logger.Error("hcl: Error on abstracting value for 'local'", "key", lk, "reason", diags.Error())
+			logger.Error("hcl: Error on abstracting value for 'local'", "key", lk, "reason", diags.Error())
 			continue
 		}
 		lm[lk] = val
@@ -518,12 +588,53 @@ func getEvalCtx(mod *configs.Module, vars map[string]cty.Value, inputs map[strin
 	}
 	evalCtx.Variables["local"] = cty.ObjectVal(lm)
 
-	log.Logger.Debug("hcl: New variables/locals found", "var", lvars, "local", llocal)
+	logger.Debug("hcl: New variables/locals found", "var", lvars, "local", llocal)
 
 	return evalCtx
 }
 
-func appendToCtx(ctx *hcl.EvalContext, t, name string, v cty.Value) {
+// getDataSourcesValue returns the data sources of the module as an object of types with each data source
+// on it, in the same form as they are accessed with `data.TYPE.NAME`. As data sources are only read on apply
+// they only have the attributes defined on the configuration, any other attribute will be unknown.
+func getDataSourcesValue(mod *configs.Module, evalCtx *hcl.EvalContext) cty.Value {
+	types := make(map[string]map[string]cty.Value)
+	for _, dr := range mod.DataResources {
+		if _, ok := types[dr.Type]; !ok {
+			types[dr.Type] = make(map[string]cty.Value)
+		}
+
+		// The data sources with count or for_each are multiple instances
+		// so we cannot know which one would be referenced
+		if dr.Count != nil || dr.ForEach != nil {
+			types[dr.Type][dr.Name] = cty.DynamicVal
+			continue
+		}
+
+		body, ok := dr.Config.(*hclsyntax.Body)
+		if !ok {
+			types[dr.Type][dr.Name] = cty.DynamicVal
+			continue
+		}
+
+		attrs := make(map[string]cty.Value)
+		for ak, av := range body.Attributes {
+			val, diags := av.Expr.Value(evalCtx)
+			if diags.HasErrors() {
+				continue
+			}
+			attrs[ak] = val
+		}
+		types[dr.Type][dr.Name] = cty.ObjectVal(attrs)
+	}
+
+	data := make(map[string]cty.Value)
+	for t, ds := range types {
+		data[t] = cty.ObjectVal(ds)
+	}
+	return cty.ObjectVal(data)
+}
+
+func appendToCtx(logger *slog.Logger, ctx *hcl.EvalContext, t, name string, v cty.Value) {
 	vars := ctx.Variables[t]
 
 	mvars := make(map[string]cty.Value)
@@ -533,7 +644,7 @@ func appendToCtx(ctx *hcl.EvalContext, t, name string, v cty.Value) {
 		k, v := iter.Element()
 		var key string
 		if err := gocty.FromCtyValue(k, &key); err != nil {
-			log.Logger.Error("hcl: Failed to get KEY from Context to append", "key", k, "reason", err.Error())
+			logger.Error("hcl: Failed to get KEY from Context to append", "key", k, "reason", err.Error())
 		}
 		mvars[key] = v
 	}
@@ -544,7 +655,7 @@ func appendToCtx(ctx *hcl.EvalContext, t, name string, v cty.Value) {
 
 // convertGoTypesToExpectedCtyType will take a GO value and a cty.Type and convert the GO value into the cty.Type as much
 // as possible by trying to weak-type assertions
-func convertGoTypesToExpectedCtyType(v interface{}, t cty.Type) (interface{}, cty.Type) {
+func convertGoTypesToExpectedCtyType(logger *slog.Logger, v interface{}, t cty.Type) (interface{}, cty.Type) {
 	var (
 		nv interface{}
 		nt cty.Type = t
@@ -609,10 +720,10 @@ func convertGoTypesToExpectedCtyType(v interface{}, t cty.Type) (interface{}, ct
 		// the type of the value
 		ct, err := goTypeToCty(v)
 		if err != nil {
-			log.Logger.Error("hcl: Error on abstracting DynamicPseudoType", "error", err.Error())
+			logger.Error("hcl: Error on abstracting DynamicPseudoType", "error", err.Error())
 			return nil, nt
 		}
-		nv, _ = convertGoTypesToExpectedCtyType(v, ct)
+		nv, _ = convertGoTypesToExpectedCtyType(logger, v, ct)
 		nt = ct
 	default:
 		// Here we check for complex types
@@ -632,10 +743,10 @@ func convertGoTypesToExpectedCtyType(v interface{}, t cty.Type) (interface{}, ct
 			for vk, vv := range vm {
 				if t.HasAttribute(vk) {
 					at := t.AttributeType(vk)
-					cfg[vk], _ = convertGoTypesToExpectedCtyType(vv, at)
+					cfg[vk], _ = convertGoTypesToExpectedCtyType(logger, vv, at)
 				} else {
 					// If the recieving object does not have the expected
-					cfg[vk], _ = convertGoTypesToExpectedCtyType(vv, cty.String)
+					cfg[vk], _ = convertGoTypesToExpectedCtyType(logger, vv, cty.String)
 				}
 			}
 			nv = cfg
@@ -648,7 +759,7 @@ func convertGoTypesToExpectedCtyType(v interface{}, t cty.Type) (interface{}, ct
 				return nil, nt
 			}
 			for vk, vv := range vm {
-				mv[vk], _ = convertGoTypesToExpectedCtyType(vv, *et)
+				mv[vk], _ = convertGoTypesToExpectedCtyType(logger, vv, *et)
 			}
 			nv = mv
 			break
@@ -660,7 +771,7 @@ func convertGoTypesToExpectedCtyType(v interface{}, t cty.Type) (interface{}, ct
 				return nil, nt
 			}
 			for _, vv := range va {
-				nnv, _ := convertGoTypesToExpectedCtyType(vv, *et)
+				nnv, _ := convertGoTypesToExpectedCtyType(logger, vv, *et)
 				lv = append(lv, nnv)
 			}
 			nv = lv
@@ -672,13 +783,13 @@ func convertGoTypesToExpectedCtyType(v interface{}, t cty.Type) (interface{}, ct
 }
 
 // getBodyJSON gets all the variables in a JSON format of the actual representation and the references it may have
-func getBodyJSON(modulePrefix string, b *hclsyntax.Body, evalCtx *hcl.EvalContext) map[string]interface{} {
+func getBodyJSON(logger *slog.Logger, modulePrefix string, b *hclsyntax.Body, evalCtx *hcl.EvalContext) map[string]interface{} {
 	cfg := make(map[string]interface{})
 	// Each attribute of the body is casted to the correct type and placed into the cfg map.
 	for attrk, attrv := range b.Attributes {
 		val, diags := attrv.Expr.Value(evalCtx)
 		if diags != nil && diags.HasErrors() && !val.IsKnown() && len(attrv.Expr.Variables()) == 0 {
-			log.Logger.Error("hcl: Error on abstracting value for 'attribute'", "name", attrk, "reason", diags.Error())
+			logger.Error("hcl: Error on abstracting value for 'attribute'", "name", attrk, "reason", diags.Error())
 			continue
 		}
 
@@ -689,7 +800,18 @@ func getBodyJSON(modulePrefix string, b *hclsyntax.Body, evalCtx *hcl.EvalContex
 		cfg[attrk] = vv
 	}
 	for _, block := range b.Blocks {
-		ncfg := getBodyJSON(modulePrefix, block.Body, evalCtx)
+		if block.Type == "dynamic" {
+			name, ncfgs := getDynamicBlockJSON(logger, modulePrefix, block, evalCtx)
+			if len(ncfgs) == 0 {
+				continue
+			}
+			if _, ok := cfg[name]; !ok {
+				cfg[name] = make([]interface{}, 0)
+			}
+			cfg[name] = append(cfg[name].([]interface{}), ncfgs...)
+			continue
+		}
+		ncfg := getBodyJSON(logger, modulePrefix, block.Body, evalCtx)
 		// We continue to not add empty information to the config
 		// so it's clean and only has required information
 		if len(ncfg) == 0 {
@@ -704,6 +826,59 @@ func getBodyJSON(modulePrefix string, b *hclsyntax.Body, evalCtx *hcl.EvalContex
 	return cfg
 }
 
+// getDynamicBlockJSON expands the 'dynamic' block into the blocks it generates. It returns
+// the name of the generated blocks and the JSON of each one of them, evaluated with
+// the iterator set to the element it was generated from.
+func getDynamicBlockJSON(logger *slog.Logger, modulePrefix string, block *hclsyntax.Block, evalCtx *hcl.EvalContext) (string, []interface{}) {
+	if len(block.Labels) == 0 {
+		return "", nil
+	}
+	name := block.Labels[0]
+
+	// The iterator defaults to the name of the block
+	// if it's not defined with the 'iterator' argument
+	iterator := name
+	if attr, ok := block.Body.Attributes["iterator"]; ok {
+		tr, diags := hcl.AbsTraversalForExpr(attr.Expr)
+		if diags.HasErrors() {
+			logger.Error("hcl: Invalid iterator on dynamic block", "name", name, "reason", diags.Error())
+			return name, nil
+		}
+		iterator = tr.RootName()
+	}
+
+	forEach, ok := block.Body.Attributes["for_each"]
+	if !ok {
+		return name, nil
+	}
+	each, ok := evalForEach(logger, forEach.Expr, evalCtx)
+	if !ok {
+		logger.Debug("hcl: Unknown for_each on dynamic block", "name", name)
+		return name, nil
+	}
+
+	var content *hclsyntax.Body
+	for _, b := range block.Body.Blocks {
+		if b.Type == "content" {
+			content = b.Body
+			break
+		}
+	}
+	if content == nil {
+		return name, nil
+	}
+
+	cfgs := make([]interface{}, 0, len(each))
+	for _, k := range sortedKeys(each) {
+		ncfg := getBodyJSON(logger, modulePrefix, content, withInstanceVars(evalCtx, map[string]cty.Value{iterator: each[k]}))
+		if len(ncfg) == 0 {
+			continue
+		}
+		cfgs = append(cfgs, ncfg)
+	}
+	return name, cfgs
+}
+
 // convertCtyValue converts the value v to a normal type, the second
 // return indicates if there is something to convert or no
 func convertCtyValue(modulePrefix string, attrvars []hcl.Traversal, val cty.Value) (interface{}, bool) {
@@ -731,7 +906,8 @@ func convertCtyValue(modulePrefix string, attrvars []hcl.Traversal, val cty.Valu
 		}
 		return val.True(), true
 	default:
-		if val.Type().IsTupleType() {
+		ty := val.Type()
+		if val.IsKnown() && (ty.IsTupleType() || ty.IsListType() || ty.IsSetType()) {
 			values := make([]interface{}, 0, 0)
 			iter := val.ElementIterator()
 			for iter.Next() {
@@ -757,6 +933,14 @@ func convertCtyValue(modulePrefix string, attrvars []hcl.Traversal, val cty.Valu
 					}
 					values = append(values, nval.True())
 				default:
+					// Known collections and objects are converted as
+					// any other value, the rest are references
+					if nt := nval.Type(); nval.IsKnown() && (nt.IsObjectType() || nt.IsMapType() || nt.IsTupleType() || nt.IsListType() || nt.IsSetType()) {
+						if nv, ok := convertCtyValue(modulePrefix, nil, nval); ok {
+							values = append(values, nv)
+						}
+						continue
+					}
 					vars := make([]string, 0, 0)
 					for _, vr := range attrvars {
 						v := string(hclwrite.TokensForTraversal(vr).Bytes())
@@ -783,7 +967,7 @@ func convertCtyValue(modulePrefix string, attrvars []hcl.Traversal, val cty.Valu
 				}
 			}
 			return values, true
-		} else if val.Type().IsObjectType() {
+		} else if val.IsKnown() && (ty.IsObjectType() || ty.IsMapType()) {
 			cfg := make(map[string]interface{})
 			for k, v := range val.AsValueMap() {
 				nv, ok := convertCtyValue(modulePrefix, nil, v)
@@ -823,17 +1007,13 @@ func convertCtyValue(modulePrefix string, attrvars []hcl.Traversal, val cty.Valu
 
 // getHCLProviders extracts provider configurations from the module and initializes the providers using the
 // providerInitializers slice. The resulting map of aliases to instantiated providers is then returned.
-func getHCLProviders(mod *configs.Module, evalCtx *hcl.EvalContext, providerInitializers []ProviderInitializer) (map[string]Provider, error) {
-	piMap := make(map[string]ProviderInitializer)
-	for _, pi := range providerInitializers {
-		for _, name := range pi.MatchNames {
-			piMap[name] = pi
-		}
-	}
+func getHCLProviders(logger *slog.Logger, mod *configs.Module, evalCtx *hcl.EvalContext, providerInitializers []ProviderInitializer) (map[string]Provider, error) {
+	pm := newProviderMatcher(providerInitializers)
 
 	providers := make(map[string]Provider)
 	for pk, pv := range mod.ProviderConfigs {
-		pi, ok := piMap[pv.Name]
+		source := mod.ProviderForLocalConfig(addrs.LocalProviderConfig{LocalName: pv.Name})
+		pi, ok := pm.match(pv.Name, source.String())
 		if !ok {
 			continue
 		}
@@ -843,13 +1023,13 @@ func getHCLProviders(mod *configs.Module, evalCtx *hcl.EvalContext, providerInit
 			return nil, fmt.Errorf("bad body")
 		}
 
-		cfg := getBodyJSON("", body, evalCtx)
+		cfg := getBodyJSON(logger, "", body, evalCtx)
 		values := make(map[string]interface{})
 		for k, v := range cfg {
 			values[k] = v
 		}
 
-		prov, err := pi.Provider(values)
+		prov, err := pi.newProvider(logger, values)
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize provider: %w", err)
 		}
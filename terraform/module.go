@@ -0,0 +1,497 @@
+package terraform
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform/addrs"
+	"github.com/hashicorp/terraform/configs"
+	"github.com/spf13/afero"
+
+	"github.com/cycloidio/terracost/util"
+)
+
+const (
+	// modulesManifestPath is the path, relative to the root module, of the
+	// manifest 'terraform init' writes with the installed modules
+	modulesManifestPath = ".terraform/modules/modules.json"
+
+	// cacheManifestName is the name of the manifest of the modules
+	// on the module cache directory
+	cacheManifestName = "modules.json"
+)
+
+// cacheLocks are the mutexes of the module cache directories and of the modules on them, so the extractions
+// that share a module cache do not download the same module nor write its manifest at the same time
+var cacheLocks sync.Map
+
+// moduleRecord is a module installed on a directory. It has the same
+// format Terraform uses on the modules manifest
+type moduleRecord struct {
+	// Key is the path of the module call, like 'ec2.ebs', or
+	// the cache key when it's used on the module cache
+	Key     string `json:"Key"`
+	Source  string `json:"Source"`
+	Version string `json:"Version,omitempty"`
+
+	// Dir is the directory of the module, relative to the root
+	// module or to the module cache
+	Dir string `json:"Dir"`
+}
+
+// modulesManifest is the list of modules installed
+type modulesManifest struct {
+	Modules []moduleRecord `json:"Modules"`
+}
+
+// moduleInstaller resolves the directories of the remote modules. It first looks for the modules already
+// installed by 'terraform init' on the root module, then on the module cache and, if not offline,
+// it downloads them.
+type moduleInstaller struct {
+	fs      afero.Fs
+	opts    HCLOptions
+	fetcher ModuleFetcher
+
+	rootPath  string
+	installed map[string]moduleRecord
+
+	// dirs are the directories of the modules already
+	// installed by install with their key
+	dirs map[string]string
+}
+
+// newModuleInstaller returns a moduleInstaller for the root module on rootPath
+func newModuleInstaller(fs afero.Fs, rootPath string, opts HCLOptions) (*moduleInstaller, error) {
+	mi := &moduleInstaller{
+		fs:        fs,
+		opts:      opts,
+		fetcher:   opts.ModuleFetcher,
+		rootPath:  rootPath,
+		installed: make(map[string]moduleRecord),
+		dirs:      make(map[string]string),
+	}
+	if mi.fetcher == nil {
+		f, err := NewModuleFetcher(opts.Credentials)
+		if err != nil {
+			return nil, err
+		}
+		mi.fetcher = f
+	}
+
+	mp := path.Join(rootPath, modulesManifestPath)
+	b, err := afero.ReadFile(fs, mp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return mi, nil
+		}
+		return nil, fmt.Errorf("failed to read modules manifest %q: %w", mp, err)
+	}
+
+	var mm modulesManifest
+	if err := json.Unmarshal(b, &mm); err != nil {
+		return nil, fmt.Errorf("failed to parse modules manifest %q: %w", mp, err)
+	}
+	for _, r := range mm.Modules {
+		mi.installed[r.Key] = r
+	}
+	opts.logger().Debug("hcl: Found modules manifest", "path", mp, "count", len(mm.Modules))
+
+	return mi, nil
+}
+
+// InstallModule returns the directory on the fs of the remote module with the source, which can be any
+// remote address supported on the module calls, installing it with the opts like the remote modules called
+// from HCL. The version is the version constraint of the registry modules.
+func InstallModule(fs afero.Fs, source, ver string, opts HCLOptions) (string, error) {
+	sa, err := addrs.ParseModuleSource(source)
+	if err != nil {
+		return "", fmt.Errorf("invalid module source %q: %w", source, err)
+	}
+	if _, ok := sa.(addrs.ModuleSourceLocal); ok {
+		return "", fmt.Errorf("module source %q is not remote", source)
+	}
+
+	mc := &configs.ModuleCall{
+		SourceAddr:    sa,
+		SourceAddrRaw: source,
+	}
+	if ver != "" {
+		vc, err := version.NewConstraint(ver)
+		if err != nil {
+			return "", fmt.Errorf("invalid version constraint %q of module %q: %w", ver, source, err)
+		}
+		mc.Version.Required = vc
+	}
+
+	// There is no root module so it can only be
+	// on the module cache or downloaded
+	mi := &moduleInstaller{
+		fs:        fs,
+		opts:      opts,
+		fetcher:   opts.ModuleFetcher,
+		installed: make(map[string]moduleRecord),
+		dirs:      make(map[string]string),
+	}
+	if mi.fetcher == nil {
+		mi.fetcher, err = NewModuleFetcher(opts.Credentials)
+		if err != nil {
+			return "", err
+		}
+	}
+	return mi.install("", mc)
+}
+
+// install returns the directory on the fs of the remote module called with mc, the key
+// is the path of the module call from the root module, like 'ec2.ebs'
+func (mi *moduleInstaller) install(key string, mc *configs.ModuleCall) (string, error) {
+	if dir, ok := mi.dirs[key]; ok {
+		return dir, nil
+	}
+	dir, err := mi.installModule(key, mc)
+	if err != nil {
+		return "", err
+	}
+	mi.dirs[key] = dir
+	return dir, nil
+}
+
+// installModule installs the module called with mc with the key, see install
+func (mi *moduleInstaller) installModule(key string, mc *configs.ModuleCall) (string, error) {
+	src := mc.SourceAddr.String()
+
+	if r, ok := mi.installed[key]; ok && (r.Source == src || r.Source == mc.SourceAddrRaw) {
+		dir := path.Join(mi.rootPath, r.Dir)
+		if !versionMatches(r.Version, mc.Version.Required) {
+			mi.opts.logger().Warn("hcl: Installed module does not match the version constraint, 'terraform init' has to be run again", "key", key, "version", r.Version, "constraint", mc.Version.Required.String())
+		} else if ok, _ := afero.DirExists(mi.fs, dir); ok {
+			mi.opts.logger().Debug("hcl: Module already installed", "key", key, "path", dir)
+			return dir, nil
+		}
+	}
+
+	var cacheKey string
+	if mi.opts.ModuleCacheDir != "" {
+		cacheKey = moduleCacheKey(mc)
+		// The module is only looked up and downloaded by one
+		// extraction at a time so they do not overwrite it
+		defer mi.lockCache(cacheKey)()
+		dir, err := mi.fromCache(cacheKey, mc)
+		if err != nil {
+			return "", err
+		}
+		if dir != "" {
+			mi.opts.logger().Debug("hcl: Module found on cache", "key", key, "path", dir)
+			return mi.toFs(dir, "")
+		}
+	}
+
+	if mi.opts.Offline {
+		return "", fmt.Errorf("%w: %s", ErrModuleNotInstalled, src)
+	}
+
+	return mi.download(cacheKey, mc)
+}
+
+// fromCache returns the directory of the module with the cacheKey on the module cache,
+// if it's not on it an empty string is returned
+func (mi *moduleInstaller) fromCache(cacheKey string, mc *configs.ModuleCall) (string, error) {
+	mm, err := mi.readCacheManifest()
+	if err != nil {
+		return "", err
+	}
+	for _, r := range mm.Modules {
+		if r.Key != cacheKey {
+			continue
+		}
+		// The version is checked again as the cache could have
+		// been written with a different constraint
+		if !versionMatches(r.Version, mc.Version.Required) {
+			return "", nil
+		}
+		dir := filepath.Join(mi.opts.ModuleCacheDir, r.Dir)
+		if _, err := os.Stat(dir); err != nil {
+			return "", nil
+		}
+		return dir, nil
+	}
+	return "", nil
+}
+
+// download fetches the module into the module cache with the cacheKey or, if there
+// is no module cache, into a temporary directory
+func (mi *moduleInstaller) download(cacheKey string, mc *configs.ModuleCall) (string, error) {
+	ctx := context.Background()
+	var (
+		pkg    string
+		subdir string
+		ver    string
+	)
+	switch sa := mc.SourceAddr.(type) {
+	case addrs.ModuleSourceRegistry:
+		v, err := mi.selectModuleVersion(ctx, sa.Package, mc.Version.Required)
+		if err != nil {
+			return "", err
+		}
+		ml, err := mi.fetcher.ModuleLocation(ctx, sa.Package, v)
+		if err != nil {
+			return "", fmt.Errorf("failed to locate module %s: %w", sa, err)
+		}
+		ver = v
+		pkg, subdir, err = splitModulePackage(ml)
+		if err != nil {
+			return "", err
+		}
+		subdir = path.Join(subdir, sa.Subdir)
+	case addrs.ModuleSourceRemote:
+		pkg = sa.Package.String()
+		subdir = sa.Subdir
+	default:
+		return "", fmt.Errorf("module source %q cannot be downloaded", mc.SourceAddr)
+	}
+
+	var dir, fetchDir string
+	if cacheKey != "" {
+		dir = filepath.Join(mi.opts.ModuleCacheDir, cacheKey)
+		if err := os.MkdirAll(mi.opts.ModuleCacheDir, 0700); err != nil {
+			return "", fmt.Errorf("could not create path %q: %w", mi.opts.ModuleCacheDir, err)
+		}
+		// The module is fetched next to the one on the cache so
+		// it's only replaced once the download is complete
+		tmp, err := os.MkdirTemp(mi.opts.ModuleCacheDir, cacheKey+".*.tmp")
+		if err != nil {
+			return "", fmt.Errorf("failed to create a temp dir: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		fetchDir = filepath.Join(tmp, "module")
+	} else {
+		tmp, err := os.MkdirTemp("", "terracost")
+		if err != nil {
+			return "", fmt.Errorf("failed to create a temp dir: %w", err)
+		}
+		dir = tmp
+		// We remove it to create just the folder and then
+		// we pass the 'dir' path to the Fetcher and we know
+		// it's a valid unexistent dir. If we did not delete it
+		// the 'FetchPackage' would try to 'git pull' instead
+		// of 'git clone'
+		os.RemoveAll(dir)
+		fetchDir = dir
+	}
+
+	mi.opts.logger().Debug("hcl: Downloading module", "source", pkg, "version", ver, "path", dir)
+	authPkg, err := withGitCredentials(pkg, mi.opts.GitCredentials)
+	if err != nil {
+		return "", err
+	}
+	err = mi.fetcher.FetchPackage(ctx, fetchDir, authPkg)
+	if err != nil {
+		return "", fmt.Errorf("failed to download the module %q: %w", pkg, err)
+	}
+
+	if cacheKey != "" {
+		if err := os.RemoveAll(dir); err != nil {
+			return "", fmt.Errorf("failed to remove the cached module %q: %w", dir, err)
+		}
+		if err := os.Rename(fetchDir, dir); err != nil {
+			return "", fmt.Errorf("failed to move the module to the cache %q: %w", dir, err)
+		}
+
+		err = mi.addToCacheManifest(moduleRecord{
+			Key:     cacheKey,
+			Source:  mc.SourceAddr.String(),
+			Version: ver,
+			Dir:     filepath.Join(cacheKey, subdir),
+		})
+		if err != nil {
+			return "", err
+		}
+		return mi.toFs(filepath.Join(dir, subdir), "")
+	}
+
+	return mi.toFs(dir, subdir)
+}
+
+// toFs makes the module on the OS dir available on the fs on the same path, and returns
+// the path of the module on the subdir. If the fs already has the dir it's assumed it's
+// the OS and nothing is copied.
+func (mi *moduleInstaller) toFs(dir, subdir string) (string, error) {
+	mdir := filepath.Join(dir, subdir)
+
+	// If the dir already exists we assume it's the right Fs
+	// so no need to copy the content
+	if ok, _ := afero.DirExists(mi.fs, dir); ok {
+		return mdir, nil
+	}
+
+	// Once everything is copied we can remove it, unless it's the cache
+	if mi.opts.ModuleCacheDir == "" {
+		defer os.RemoveAll(dir)
+	}
+
+	err := mi.fs.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", fmt.Errorf("could not create path %q: %w", dir, err)
+	}
+	err = util.FromOSToAfero(mi.fs, dir, dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to copy the module to %s: %w", dir, err)
+	}
+	return mdir, nil
+}
+
+// readCacheManifest reads the manifest of the module cache, if it does not exist
+// an empty one is returned
+func (mi *moduleInstaller) readCacheManifest() (modulesManifest, error) {
+	var mm modulesManifest
+	mp := filepath.Join(mi.opts.ModuleCacheDir, cacheManifestName)
+	b, err := os.ReadFile(mp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return mm, nil
+		}
+		return mm, fmt.Errorf("failed to read module cache manifest %q: %w", mp, err)
+	}
+	if err := json.Unmarshal(b, &mm); err != nil {
+		return mm, fmt.Errorf("failed to parse module cache manifest %q: %w", mp, err)
+	}
+	return mm, nil
+}
+
+// addToCacheManifest adds the r to the manifest of the module cache, replacing
+// any record with the same key
+func (mi *moduleInstaller) addToCacheManifest(r moduleRecord) error {
+	defer mi.lockCache("")()
+
+	mm, err := mi.readCacheManifest()
+	if err != nil {
+		return err
+	}
+	mods := make([]moduleRecord, 0, len(mm.Modules)+1)
+	for _, m := range mm.Modules {
+		if m.Key != r.Key {
+			mods = append(mods, m)
+		}
+	}
+	mm.Modules = append(mods, r)
+
+	b, err := json.MarshalIndent(mm, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// We write it to a temporary file first so the
+	// manifest is never left half written
+	mp := filepath.Join(mi.opts.ModuleCacheDir, cacheManifestName)
+	f, err := os.CreateTemp(mi.opts.ModuleCacheDir, cacheManifestName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write module cache manifest %q: %w", mp, err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write module cache manifest %q: %w", f.Name(), err)
+	}
+	if err := os.Rename(f.Name(), mp); err != nil {
+		return fmt.Errorf("failed to write module cache manifest %q: %w", mp, err)
+	}
+	return nil
+}
+
+// lockCache locks the mutex of the module with the cacheKey on the module cache, or of the
+// whole module cache if it's empty, and returns the function to unlock it
+func (mi *moduleInstaller) lockCache(cacheKey string) func() {
+	dir, err := filepath.Abs(mi.opts.ModuleCacheDir)
+	if err != nil {
+		dir = filepath.Clean(mi.opts.ModuleCacheDir)
+	}
+	v, _ := cacheLocks.LoadOrStore(dir+"|"+cacheKey, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// versionMatches returns true if the version ver of a module matches the constraints,
+// or if any of them is not known
+func versionMatches(ver string, constraints version.Constraints) bool {
+	if ver == "" || len(constraints) == 0 {
+		return true
+	}
+	v, err := version.NewVersion(ver)
+	return err == nil && constraints.Check(v)
+}
+
+// moduleCacheKey returns the key of the module on the module cache, it only depends
+// on the module call so it can be known without downloading anything
+func moduleCacheKey(mc *configs.ModuleCall) string {
+	h := sha256.Sum256([]byte(mc.SourceAddr.String() + "|" + mc.Version.Required.String()))
+	return fmt.Sprintf("%x", h[:16])
+}
+
+// selectModuleVersion returns the latest version of the registry module rm that matches the
+// constraints. Like Terraform, prereleases are only selected when explicitly required.
+func (mi *moduleInstaller) selectModuleVersion(ctx context.Context, rm addrs.ModuleRegistryPackage, constraints version.Constraints) (string, error) {
+	mvs, err := mi.fetcher.ModuleVersions(ctx, rm)
+	if err != nil {
+		return "", fmt.Errorf("failed to list versions of module %s: %w", rm, err)
+	}
+	if len(mvs) == 0 {
+		return "", fmt.Errorf("no versions found for module %s", rm)
+	}
+
+	exact := make(map[string]struct{})
+	for _, c := range constraints {
+		exact[c.String()] = struct{}{}
+	}
+
+	versions := make(version.Collection, 0, len(mvs))
+	for _, mv := range mvs {
+		v, err := version.NewVersion(mv)
+		if err != nil {
+			mi.opts.logger().Debug("hcl: Invalid module version", "module", rm.String(), "version", mv)
+			continue
+		}
+		if v.Prerelease() != "" {
+			if _, ok := exact[v.String()]; !ok {
+				if _, ok := exact["= "+v.String()]; !ok {
+					continue
+				}
+			}
+		}
+		if len(constraints) != 0 && !constraints.Check(v) {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	if len(versions) == 0 {
+		return "", fmt.Errorf("no version of module %s matches the constraints %q", rm, constraints.String())
+	}
+	sort.Sort(versions)
+
+	return versions[len(versions)-1].Original(), nil
+}
+
+// splitModulePackage splits the location of a module, as returned by the registry, into the
+// address of the package to download and the subdirectory of the module on it
+func splitModulePackage(location string) (string, string, error) {
+	ms, err := addrs.ParseModuleSource(location)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid module location %q: %w", location, err)
+	}
+	rs, ok := ms.(addrs.ModuleSourceRemote)
+	if !ok {
+		return "", "", fmt.Errorf("invalid module location %q: must be a remote source", location)
+	}
+	return rs.Package.String(), rs.Subdir, nil
+}
@@ -0,0 +1,56 @@
+package terraform_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cycloidio/terracost/terraform"
+)
+
+func TestInstallModule_ConcurrentCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	opts := terraform.HCLOptions{
+		ModuleCacheDir: cacheDir,
+		ModuleFetcher:  terraform.NewLocalModuleFetcher("../testdata/aws/module-fetcher"),
+	}
+	versions := []string{"~> 1.0", "1.0.0", "2.0.0"}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 12)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = terraform.InstallModule(afero.NewOsFs(), "app.example.com/acme/web/aws", versions[i%len(versions)], opts)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(cacheDir, "modules.json"))
+	require.NoError(t, err)
+
+	var mm struct {
+		Modules []struct {
+			Version string
+			Dir     string
+		}
+	}
+	require.NoError(t, json.Unmarshal(b, &mm))
+
+	installed := make([]string, 0, len(mm.Modules))
+	for _, m := range mm.Modules {
+		installed = append(installed, m.Version)
+		assert.DirExists(t, filepath.Join(cacheDir, m.Dir))
+	}
+	assert.ElementsMatch(t, []string{"1.2.0", "1.0.0", "2.0.0"}, installed)
+}
@@ -0,0 +1,51 @@
+package terraform
+
+import (
+	"log/slog"
+
+	"github.com/cycloidio/terracost/log"
+)
+
+// HCLOptions are the options used to extract the queries from HCL
+type HCLOptions struct {
+	// VarFiles are the paths to variable definition files (.tfvars or .tfvars.json)
+	// to load on the root module, like the '-var-file' flag of Terraform. They are
+	// loaded in order after the ones found on the module directory.
+	VarFiles []string
+
+	// Vars are the values of the root module variables, like the '-var' flag of
+	// Terraform. They have the highest precedence over any other value.
+	Vars map[string]string
+
+	// ModuleCacheDir is a directory on the OS where the remote modules are kept
+	// once downloaded, so they are not downloaded again on the next estimations.
+	// It can be shared by the extractions running at the same time.
+	ModuleCacheDir string
+
+	// Offline does not allow to download any remote module, so they have to be
+	// installed on '.terraform/modules' (by 'terraform init') or on the ModuleCacheDir.
+	Offline bool
+
+	// Credentials are the tokens used to authenticate to the private registries by host,
+	// like the 'credentials' blocks of the Terraform CLI configuration. They can be
+	// loaded from it with LoadCredentials.
+	Credentials map[string]string
+
+	// GitCredentials are the credentials used to clone the private Git modules by host
+	GitCredentials map[string]GitCredentials
+
+	// ModuleFetcher fetches the remote modules, if not set the one returned by
+	// NewModuleFetcher with the Credentials is used
+	ModuleFetcher ModuleFetcher
+
+	// Logger is the logger of the extraction, log.Logger by default
+	Logger *slog.Logger
+}
+
+// logger returns the Logger or log.Logger if not set
+func (o HCLOptions) logger() *slog.Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return log.Logger
+}
@@ -4,19 +4,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"regexp"
 	"strconv"
 	"strings"
 
+	"github.com/cycloidio/terracost/log"
 	"github.com/cycloidio/terracost/query"
 	"github.com/cycloidio/terracost/usage"
 )
 
 // Plan is a representation of a Terraform plan file.
 type Plan struct {
-	providerInitializers map[string]ProviderInitializer
+	providerInitializers providerMatcher
 	usage                usage.Usage
+	logger               *slog.Logger
 
+	FormatVersion string              `json:"format_version"`
 	Configuration Configuration       `json:"configuration"`
 	PriorState    *State              `json:"prior_state"`
 	PlannedValues Values              `json:"planned_values"`
@@ -26,23 +30,26 @@ type Plan struct {
 // SetUsage will set the usage of the plan
 func (p *Plan) SetUsage(u usage.Usage) { p.usage = u }
 
+// SetLogger sets the logger of the plan, log.Logger by default
+func (p *Plan) SetLogger(l *slog.Logger) { p.logger = l }
+
 // NewPlan returns an empty Plan.
 func NewPlan(providerInitializers ...ProviderInitializer) *Plan {
-	piMap := make(map[string]ProviderInitializer)
-	for _, pi := range providerInitializers {
-		for _, name := range pi.MatchNames {
-			piMap[name] = pi
-		}
-	}
-	plan := &Plan{providerInitializers: piMap}
+	plan := &Plan{providerInitializers: newProviderMatcher(providerInitializers)}
 	return plan
 }
 
-// Read reads the Plan file from the provider io.Reader.
+// Read reads the Plan file from the provider io.Reader. The plans from
+// Terraform and OpenTofu are supported.
 func (p *Plan) Read(r io.Reader) error {
 	if err := json.NewDecoder(r).Decode(p); err != nil {
 		return err
 	}
+	// The minor versions are backwards compatible so
+	// only the major version is checked
+	if mv, _, _ := strings.Cut(p.FormatVersion, "."); p.FormatVersion != "" && mv != "0" && mv != "1" {
+		return fmt.Errorf("%w: %s", ErrUnsupportedPlanFormat, p.FormatVersion)
+	}
 	return nil
 }
 
@@ -81,12 +88,16 @@ func (p *Plan) ExtractPriorQueries() ([]query.Resource, error) {
 func (p *Plan) extractProviders() (map[string]Provider, error) {
 	providers := make(map[string]Provider)
 	for name, provConfig := range p.Configuration.ProviderConfig {
-		if pi, ok := p.providerInitializers[provConfig.Name]; ok {
+		if pi, ok := p.providerInitializers.match(provConfig.Name, provConfig.FullName); ok {
 			values, err := p.evaluateProviderConfigExpressions(provConfig)
 			if err != nil {
 				return nil, fmt.Errorf("failed to read config of provider %q: %w", name, err)
 			}
-			prov, err := pi.Provider(values)
+			logger := p.logger
+			if logger == nil {
+				logger = log.Logger
+			}
+			prov, err := pi.newProvider(logger, values)
 			if err != nil {
 				return nil, err
 			}
@@ -239,7 +250,7 @@ func (p *Plan) extractModuleQueries(module *Module, resourceProviders map[string
 			}
 		}
 		rss[tfres.Address] = tfres
-		tfres.Values[usage.Key] = p.usage.GetUsage(tfres.Type)
+		tfres.Values[usage.Key] = p.usage.GetResourceUsage(tfres.Address, tfres.Type)
 	}
 
 	for _, rs := range rss {
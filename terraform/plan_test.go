@@ -3,6 +3,7 @@ package terraform_test
 import (
 	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -12,8 +13,17 @@ import (
 	"github.com/cycloidio/terracost/mock"
 	"github.com/cycloidio/terracost/query"
 	"github.com/cycloidio/terracost/terraform"
+	"github.com/cycloidio/terracost/usage"
 )
 
+func TestPlan_Read(t *testing.T) {
+	t.Run("UnsupportedFormatVersion", func(t *testing.T) {
+		plan := terraform.NewPlan()
+		err := plan.Read(strings.NewReader(`{"format_version": "2.0"}`))
+		assert.ErrorIs(t, err, terraform.ErrUnsupportedPlanFormat)
+	})
+}
+
 func TestPlan_ExtractPlannedQueries(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -52,6 +62,89 @@ func TestPlan_ExtractPlannedQueries(t *testing.T) {
 		require.Len(t, queries, 2)
 	})
 
+	t.Run("ResourceUsage", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		provider := mock.NewTerraformProvider(ctrl)
+
+		plan := terraform.NewPlan(terraform.ProviderInitializer{
+			MatchNames: []string{"aws", "aws-test"},
+			Provider: func(_ map[string]interface{}) (terraform.Provider, error) {
+				return provider, nil
+			},
+		})
+		plan.SetUsage(usage.Usage{
+			ResourceDefaultTypeUsage: map[string]interface{}{
+				"aws_instance": map[string]interface{}{"monthly_cpu_credit_hrs": 350},
+				"aws_lb":       map[string]interface{}{"new_connections": 10},
+			},
+			ResourceUsage: map[string]interface{}{
+				"module.instance.*": map[string]interface{}{"monthly_cpu_credit_hrs": 10},
+			},
+		})
+
+		f, err := os.Open("../testdata/aws/terraform-plan.json")
+		require.NoError(t, err)
+		defer f.Close()
+
+		err = plan.Read(f)
+		require.NoError(t, err)
+
+		provider.EXPECT().Name().AnyTimes().Return("aws-test")
+		provider.EXPECT().ResourceComponents(gomock.Any(), gomock.Any()).DoAndReturn(func(rss map[string]terraform.Resource, res terraform.Resource) []query.Component {
+			if res.Type == "aws_instance" {
+				assert.Equal(t, map[string]interface{}{"monthly_cpu_credit_hrs": 10}, res.Values[usage.Key])
+			} else {
+				assert.Equal(t, map[string]interface{}{"new_connections": 10}, res.Values[usage.Key])
+			}
+			return []query.Component{}
+		}).Times(2)
+
+		_, err = plan.ExtractPlannedQueries()
+		require.NoError(t, err)
+	})
+
+	t.Run("OpenTofu", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		provider := mock.NewTerraformProvider(ctrl)
+
+		var providerValues map[string]interface{}
+		plan := terraform.NewPlan(terraform.ProviderInitializer{
+			MatchNames: []string{"registry.terraform.io/hashicorp/aws"},
+			Provider: func(values map[string]interface{}) (terraform.Provider, error) {
+				providerValues = values
+				return provider, nil
+			},
+		})
+
+		f, err := os.Open("../testdata/aws/opentofu-plan.json")
+		require.NoError(t, err)
+		defer f.Close()
+
+		err = plan.Read(f)
+		require.NoError(t, err)
+
+		provider.EXPECT().Name().AnyTimes().Return("aws")
+		provider.EXPECT().ResourceComponents(gomock.Any(), gomock.Any()).DoAndReturn(func(rss map[string]terraform.Resource, res terraform.Resource) []query.Component {
+			if res.Type == "aws_instance" {
+				assert.Equal(t, "aws_instance.web", res.Address)
+				assert.Equal(t, "t3.medium", res.Values["instance_type"])
+			} else {
+				assert.Equal(t, "module.db.aws_db_instance.db", res.Address)
+				assert.Equal(t, "db.r5.large", res.Values["instance_class"])
+			}
+			return []query.Component{}
+		}).Times(2)
+
+		queries, err := plan.ExtractPlannedQueries()
+		require.NoError(t, err)
+		require.Len(t, queries, 2)
+		assert.Equal(t, "eu-west-1", providerValues["region"])
+	})
+
 	t.Run("BadProvider", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -1,9 +1,18 @@
 package terraform
 
 import (
+	"log/slog"
+	"strings"
+
+	"github.com/hashicorp/terraform/addrs"
+
 	"github.com/cycloidio/terracost/query"
 )
 
+// openTofuRegistryHost is the host of the OpenTofu public registry, which
+// has the same providers than the Terraform one
+const openTofuRegistryHost = "registry.opentofu.org"
+
 //go:generate mockgen -destination=../mock/terraform_provider.go -mock_names=Provider=TerraformProvider -package mock github.com/cycloidio/terracost/terraform Provider
 
 // Provider represents a Terraform provider. It extracts price queries from Terraform resources.
@@ -23,11 +32,69 @@ type ProviderInitializer struct {
 	// MatchNames contains the names that this ProviderInitializer will match. Most providers will only
 	// have one name (such as `aws`) but some might use multiple names to refer to the same provider
 	// implementation (such as `google` and `google-beta`).
+	// The names can also be fully-qualified provider sources (such as `registry.terraform.io/hashicorp/aws`),
+	// which match the provider with that source independently of its local name. The Terraform and OpenTofu
+	// public registries are considered the same one.
 	MatchNames []string
 
 	// Provider initializes a Provider instance given the values defined in the config and returns it.
 	// If a provider must be ignored (related to version constraints, etc), please return nil to avoid using it.
 	Provider func(values map[string]interface{}) (Provider, error)
+
+	// ProviderWithLogger is like Provider but it also receives the logger of the extraction,
+	// it's used instead of Provider if set
+	ProviderWithLogger func(logger *slog.Logger, values map[string]interface{}) (Provider, error)
+}
+
+// newProvider initializes the Provider with the values and the logger
+func (pi ProviderInitializer) newProvider(logger *slog.Logger, values map[string]interface{}) (Provider, error) {
+	if pi.ProviderWithLogger != nil {
+		return pi.ProviderWithLogger(logger, values)
+	}
+	return pi.Provider(values)
+}
+
+// providerMatcher finds the ProviderInitializer of a provider
+// by its local name or its fully-qualified source
+type providerMatcher map[string]ProviderInitializer
+
+// newProviderMatcher returns a providerMatcher for the MatchNames of the providerInitializers
+func newProviderMatcher(providerInitializers []ProviderInitializer) providerMatcher {
+	pm := make(providerMatcher)
+	for _, pi := range providerInitializers {
+		for _, name := range pi.MatchNames {
+			if strings.Contains(name, "/") {
+				name = normalizeProviderSource(name)
+			}
+			pm[name] = pi
+		}
+	}
+	return pm
+}
+
+// match returns the ProviderInitializer of the provider with the local name and the source, which
+// can be empty if it's not known. The source has precedence over the name.
+func (pm providerMatcher) match(name, source string) (ProviderInitializer, bool) {
+	if source != "" {
+		if pi, ok := pm[normalizeProviderSource(source)]; ok {
+			return pi, true
+		}
+	}
+	pi, ok := pm[name]
+	return pi, ok
+}
+
+// normalizeProviderSource returns the source of the provider in its fully-qualified
+// form, with the OpenTofu public registry replaced by the Terraform one
+func normalizeProviderSource(source string) string {
+	p, diags := addrs.ParseProviderSourceString(source)
+	if diags.HasErrors() {
+		return source
+	}
+	if p.Hostname.String() == openTofuRegistryHost {
+		p.Hostname = addrs.DefaultProviderRegistryHost
+	}
+	return p.String()
 }
 
 // validateProviders will verify that at least one of the queries is from a known provider
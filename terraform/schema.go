@@ -13,7 +13,10 @@ type ProviderConfigExpression struct {
 
 // ProviderConfig is configuration of a provider with the given Name.
 type ProviderConfig struct {
-	Name        string                              `json:"name"`
+	Name string `json:"name"`
+	// FullName is the fully-qualified source of the provider, like
+	// 'registry.terraform.io/hashicorp/aws', it's not present on old plans
+	FullName    string                              `json:"full_name"`
 	Alias       string                              `json:"alias"`
 	Expressions map[string]ProviderConfigExpression `json:"expressions"`
 }
@@ -24,6 +27,7 @@ type ProviderConfig struct {
 func (cfg *ProviderConfig) UnmarshalJSON(b []byte) error {
 	var s struct {
 		Name        string                 `json:"name"`
+		FullName    string                 `json:"full_name"`
 		Alias       string                 `json:"alias"`
 		Expressions map[string]interface{} `json:"expressions"`
 	}
@@ -32,6 +36,7 @@ func (cfg *ProviderConfig) UnmarshalJSON(b []byte) error {
 	}
 
 	cfg.Name = s.Name
+	cfg.FullName = s.FullName
 	cfg.Alias = s.Alias
 	cfg.Expressions = make(map[string]ProviderConfigExpression)
 
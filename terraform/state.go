@@ -0,0 +1,69 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// stateFile is a state on the JSON format of 'terraform show -json'
+type stateFile struct {
+	FormatVersion string `json:"format_version"`
+	Values        Values `json:"values"`
+}
+
+// NewPlanFromState reads the state on the JSON format of 'terraform show -json' from r and returns a Plan
+// with its resources as the PlannedValues, so they can be estimated with ExtractPlannedQueries. As the state
+// has no configuration, the providers are the ones of the resources configured with the providerConfigs by
+// name (like 'aws'), for example with the 'region'.
+func NewPlanFromState(r io.Reader, providerConfigs map[string]map[string]string, providerInitializers ...ProviderInitializer) (*Plan, error) {
+	var sf stateFile
+	if err := json.NewDecoder(r).Decode(&sf); err != nil {
+		return nil, err
+	}
+	if mv, _, _ := strings.Cut(sf.FormatVersion, "."); sf.FormatVersion != "" && mv != "0" && mv != "1" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPlanFormat, sf.FormatVersion)
+	}
+
+	p := NewPlan(providerInitializers...)
+	p.FormatVersion = sf.FormatVersion
+	p.PlannedValues = sf.Values
+	p.Configuration.ProviderConfig = make(map[string]ProviderConfig)
+
+	// All the resources are set on the root module with their full address
+	// as it's the one used to match them with the providers
+	addStateModule(&p.Configuration, &sf.Values.RootModule, providerConfigs)
+
+	return p, nil
+}
+
+// addStateModule adds the managed resources of the module m and its children to the cfg
+// with the providers of them
+func addStateModule(cfg *Configuration, m *Module, providerConfigs map[string]map[string]string) {
+	for _, res := range m.Resources {
+		if res.Mode != "managed" || res.ProviderName == "" {
+			continue
+		}
+		name := path.Base(res.ProviderName)
+		if _, ok := cfg.ProviderConfig[name]; !ok {
+			exps := make(map[string]ProviderConfigExpression)
+			for k, v := range providerConfigs[name] {
+				exps[k] = ProviderConfigExpression{ConstantValue: v}
+			}
+			cfg.ProviderConfig[name] = ProviderConfig{
+				Name:        name,
+				FullName:    res.ProviderName,
+				Expressions: exps,
+			}
+		}
+		cfg.RootModule.Resources = append(cfg.RootModule.Resources, ConfigurationResource{
+			Address:           cleanResourceAddresss(res.Address),
+			ProviderConfigKey: name,
+		})
+	}
+	for _, c := range m.ChildModules {
+		addStateModule(cfg, c, providerConfigs)
+	}
+}
@@ -0,0 +1,169 @@
+package terraform
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/configs"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+const (
+	// varEnvPrefix is the prefix of the environment variables
+	// that set the value of a variable
+	varEnvPrefix = "TF_VAR_"
+
+	defaultVarsFilename     = "terraform.tfvars"
+	defaultVarsFilenameJSON = "terraform.tfvars.json"
+	autoVarsSuffix          = ".auto.tfvars"
+	autoVarsSuffixJSON      = ".auto.tfvars.json"
+)
+
+// getRootVariableValues returns the values of the variables of the root module on modPath. Each source of
+// values overrides the previous ones, following the Terraform precedence:
+//   - inputs, as Terragrunt passes them as environment variables
+//   - TF_VAR_name environment variables
+//   - terraform.tfvars
+//   - terraform.tfvars.json
+//   - *.auto.tfvars and *.auto.tfvars.json, in lexical order of their names
+//   - opts.VarFiles, in the order they are defined
+//   - opts.Vars
+//
+// https://developer.hashicorp.com/terraform/language/values/variables#variable-definition-precedence
+func getRootVariableValues(fs afero.Fs, parser *configs.Parser, mod *configs.Module, modPath string, inputs map[string]interface{}, opts HCLOptions) (map[string]cty.Value, error) {
+	raw := make(map[string]cty.Value)
+
+	for vk, vv := range mod.Variables {
+		iv, ok := inputs[vk]
+		if !ok {
+			continue
+		}
+		iv, it := convertGoTypesToExpectedCtyType(opts.logger(), iv, vv.Type)
+		ctyv, err := gocty.ToCtyValue(iv, it)
+		if err != nil {
+			opts.logger().Error("hcl: Error on abstracting value for 'input'", "key", vk, "reason", err.Error())
+			// NOTE: There are some types that we don't how to
+			// parse yet but we want to continue so we ignore
+			// the error
+			continue
+		}
+		raw[vk] = ctyv
+	}
+
+	for vk, vv := range mod.Variables {
+		ev, ok := os.LookupEnv(varEnvPrefix + vk)
+		if !ok {
+			continue
+		}
+		val, diags := vv.ParsingMode.Parse(vk, ev)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("invalid value for variable %q on environment variable %s%s: %w", vk, varEnvPrefix, vk, diags)
+		}
+		raw[vk] = val
+	}
+
+	files, err := findVarFiles(fs, modPath)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, opts.VarFiles...)
+	for _, f := range files {
+		opts.logger().Debug("hcl: Loading variable definitions file", "path", f)
+		vals, diags := parser.LoadValuesFile(f)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to load variable definitions file %q: %w", f, diags)
+		}
+		for k, v := range vals {
+			raw[k] = v
+		}
+	}
+
+	for vk, ev := range opts.Vars {
+		vv, ok := mod.Variables[vk]
+		if !ok {
+			return nil, fmt.Errorf("value for undeclared variable %q", vk)
+		}
+		val, diags := vv.ParsingMode.Parse(vk, ev)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("invalid value for variable %q: %w", vk, diags)
+		}
+		raw[vk] = val
+	}
+
+	vars := make(map[string]cty.Value)
+	for vk, val := range raw {
+		vv, ok := mod.Variables[vk]
+		if !ok {
+			// Like Terraform we ignore the values of
+			// variables that are not declared
+			opts.logger().Debug("hcl: Value for undeclared variable", "name", vk)
+			continue
+		}
+		vars[vk] = convertVariableValue(opts.logger(), vv, val)
+	}
+
+	return vars, nil
+}
+
+// findVarFiles returns the variable definition files on the modPath that Terraform loads
+// automatically, in the order they have to be loaded
+func findVarFiles(fs afero.Fs, modPath string) ([]string, error) {
+	dir := modPath
+	if dir == "" {
+		dir = "."
+	}
+	infos, err := afero.ReadDir(fs, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %q: %w", dir, err)
+	}
+
+	var (
+		files []string
+		auto  []string
+	)
+	for _, fi := range infos {
+		if fi.IsDir() {
+			continue
+		}
+		switch n := fi.Name(); {
+		case n == defaultVarsFilename, n == defaultVarsFilenameJSON:
+			files = append(files, n)
+		case strings.HasSuffix(n, autoVarsSuffix), strings.HasSuffix(n, autoVarsSuffixJSON):
+			auto = append(auto, n)
+		}
+	}
+	// terraform.tfvars has to be loaded before terraform.tfvars.json
+	// which is already the lexical order
+	sort.Strings(files)
+	sort.Strings(auto)
+	files = append(files, auto...)
+
+	for i, f := range files {
+		files[i] = path.Join(modPath, f)
+	}
+	return files, nil
+}
+
+// convertVariableValue converts the val to the type of the variable v,
+// if it cannot be converted the val is returned as it is
+func convertVariableValue(logger *slog.Logger, v *configs.Variable, val cty.Value) cty.Value {
+	if v.TypeDefaults != nil && !val.IsNull() {
+		val = v.TypeDefaults.Apply(val)
+	}
+	if v.ConstraintType == cty.NilType || v.ConstraintType == cty.DynamicPseudoType {
+		return val
+	}
+	cv, err := convert.Convert(val, v.ConstraintType)
+	if err != nil {
+		logger.Error("hcl: Invalid value for variable", "name", v.Name, "reason", err.Error())
+		return val
+	}
+	return cv
+}
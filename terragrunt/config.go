@@ -0,0 +1,337 @@
+package terragrunt
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+const (
+	// ConfigName is the name of the Terragrunt configuration of the units
+	ConfigName = "terragrunt.hcl"
+
+	// maxDepth is the maximum number of nested includes
+	// and read_terragrunt_config calls
+	maxDepth = 10
+)
+
+// config is a parsed configuration file
+type config struct {
+	source   string
+	skip     *bool
+	locals   map[string]cty.Value
+	inputs   map[string]cty.Value
+	deps     map[string]*Dependency
+	generate map[string]generateBlock
+
+	// includes are the paths of all the included files
+	includes []string
+}
+
+// generateBlock is a 'generate' block
+type generateBlock struct {
+	path     string
+	contents string
+	ifExists string
+}
+
+// value returns the config as it's exposed to the other configurations with
+// 'read_terragrunt_config' and 'include'
+func (c *config) value() cty.Value {
+	deps := make(map[string]cty.Value, len(c.deps))
+	for n, d := range c.deps {
+		deps[n] = d.value()
+	}
+	return cty.ObjectVal(map[string]cty.Value{
+		"locals":     cty.ObjectVal(c.locals),
+		"inputs":     cty.ObjectVal(c.inputs),
+		"dependency": cty.ObjectVal(deps),
+	})
+}
+
+// merge merges the included config p into c, the values of c have precedence
+func (c *config) merge(p *config) {
+	if c.source == "" {
+		c.source = p.source
+	}
+	if c.skip == nil {
+		c.skip = p.skip
+	}
+	for k, v := range p.inputs {
+		if _, ok := c.inputs[k]; !ok {
+			c.inputs[k] = v
+		}
+	}
+	for n, d := range p.deps {
+		if _, ok := c.deps[n]; !ok {
+			c.deps[n] = d
+		}
+	}
+	for n, g := range p.generate {
+		if _, ok := c.generate[n]; !ok {
+			c.generate[n] = g
+		}
+	}
+}
+
+// parseFile parses the configuration file of the scope s with its includes
+func parseFile(s *scope) (*config, error) {
+	if s.depth > maxDepth {
+		return nil, fmt.Errorf("too many nested includes on %s", s.file)
+	}
+
+	src, err := afero.ReadFile(s.fs, s.file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", s.file, err)
+	}
+	f, diags := hclsyntax.ParseConfig(src, s.file, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %q: %w", s.file, diags)
+	}
+	body := f.Body.(*hclsyntax.Body)
+
+	c := &config{
+		locals:   make(map[string]cty.Value),
+		inputs:   make(map[string]cty.Value),
+		deps:     make(map[string]*Dependency),
+		generate: make(map[string]generateBlock),
+	}
+
+	// The includes are evaluated only with the functions
+	includes := make(map[string]*config)
+	incOrder := make([]string, 0)
+	for _, b := range body.Blocks {
+		if b.Type != "include" {
+			continue
+		}
+		var name string
+		if len(b.Labels) > 0 {
+			name = b.Labels[0]
+		}
+		p, err := evalString(b.Body, "path", s.evalCtx(nil))
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate the include %q of %q: %w", name, s.file, err)
+		}
+		if !path.IsAbs(p) {
+			p = path.Join(path.Dir(s.file), p)
+		}
+
+		s.logger.Debug("terragrunt: Including configuration", "path", p, "file", s.file)
+		is := &scope{fs: s.fs, dir: s.dir, file: p, includeDir: path.Dir(p), depth: s.depth + 1, logger: s.logger}
+		ic, err := parseFile(is)
+		if err != nil {
+			return nil, err
+		}
+		includes[name] = ic
+		incOrder = append(incOrder, name)
+		c.includes = append(c.includes, p)
+		c.includes = append(c.includes, ic.includes...)
+	}
+
+	vars := make(map[string]cty.Value)
+	if len(includes) != 0 {
+		vars["include"] = includesValue(includes)
+	}
+
+	// The locals can reference each other so they are evaluated
+	// until all of them have a value or no more can be evaluated
+	var lattrs []*hclsyntax.Attribute
+	for _, b := range body.Blocks {
+		if b.Type == "locals" {
+			for _, a := range b.Body.Attributes {
+				lattrs = append(lattrs, a)
+			}
+		}
+	}
+	for len(lattrs) != 0 {
+		vars["local"] = cty.ObjectVal(c.locals)
+		pending := make([]*hclsyntax.Attribute, 0)
+		var lastDiags hcl.Diagnostics
+		for _, a := range lattrs {
+			v, diags := a.Expr.Value(s.evalCtx(vars))
+			if diags.HasErrors() {
+				pending = append(pending, a)
+				lastDiags = diags
+				continue
+			}
+			c.locals[a.Name] = v
+		}
+		if len(pending) == len(lattrs) {
+			return nil, fmt.Errorf("failed to evaluate the locals of %q: %w", s.file, lastDiags)
+		}
+		lattrs = pending
+	}
+	vars["local"] = cty.ObjectVal(c.locals)
+
+	for _, b := range body.Blocks {
+		if b.Type != "dependency" || len(b.Labels) == 0 {
+			continue
+		}
+		d, err := parseDependency(s, b, s.evalCtx(vars))
+		if err != nil {
+			return nil, err
+		}
+		c.deps[d.Name] = d
+	}
+
+	// The dependencies of the includes can be referenced too
+	for _, n := range incOrder {
+		c.merge(&config{deps: includes[n].deps})
+	}
+	deps := make(map[string]cty.Value, len(c.deps))
+	for n, d := range c.deps {
+		deps[n] = d.value()
+	}
+	vars["dependency"] = cty.ObjectVal(deps)
+	evalCtx := s.evalCtx(vars)
+
+	for _, b := range body.Blocks {
+		switch b.Type {
+		case "terraform":
+			if _, ok := b.Body.Attributes["source"]; ok {
+				c.source, err = evalString(b.Body, "source", evalCtx)
+				if err != nil {
+					return nil, fmt.Errorf("failed to evaluate the terraform source of %q: %w", s.file, err)
+				}
+			}
+		case "generate":
+			if len(b.Labels) == 0 {
+				continue
+			}
+			g, ok, err := parseGenerate(b, evalCtx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate the generate %q of %q: %w", b.Labels[0], s.file, err)
+			}
+			if ok {
+				c.generate[b.Labels[0]] = g
+			}
+		}
+	}
+
+	if a, ok := body.Attributes["inputs"]; ok {
+		v, diags := a.Expr.Value(evalCtx)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to evaluate the inputs of %q: %w", s.file, diags)
+		}
+		if !v.IsNull() && v.CanIterateElements() {
+			for it := v.ElementIterator(); it.Next(); {
+				k, ev := it.Element()
+				c.inputs[k.AsString()] = ev
+			}
+		}
+	}
+
+	if a, ok := body.Attributes["skip"]; ok {
+		v, diags := a.Expr.Value(evalCtx)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to evaluate skip of %q: %w", s.file, diags)
+		}
+		if v.Type() == cty.Bool && v.IsKnown() && !v.IsNull() {
+			skip := v.True()
+			c.skip = &skip
+		}
+	}
+
+	for _, n := range incOrder {
+		c.merge(includes[n])
+	}
+
+	return c, nil
+}
+
+// includesValue returns the value of 'include' with the included configs by name. If there
+// is only one include without a name, its values are directly on 'include'
+func includesValue(includes map[string]*config) cty.Value {
+	if c, ok := includes[""]; ok && len(includes) == 1 {
+		return c.value()
+	}
+	vals := make(map[string]cty.Value, len(includes))
+	for n, c := range includes {
+		vals[n] = c.value()
+	}
+	return cty.ObjectVal(vals)
+}
+
+// parseGenerate parses the 'generate' block b, it returns false if it's disabled
+func parseGenerate(b *hclsyntax.Block, evalCtx *hcl.EvalContext) (generateBlock, bool, error) {
+	var g generateBlock
+	if a, ok := b.Body.Attributes["disable"]; ok {
+		v, diags := a.Expr.Value(evalCtx)
+		if diags.HasErrors() {
+			return g, false, diags
+		}
+		if v.Type() == cty.Bool && v.IsKnown() && !v.IsNull() && v.True() {
+			return g, false, nil
+		}
+	}
+
+	var err error
+	g.path, err = evalString(b.Body, "path", evalCtx)
+	if err != nil {
+		return g, false, err
+	}
+	g.contents, err = evalString(b.Body, "contents", evalCtx)
+	if err != nil {
+		return g, false, err
+	}
+	if _, ok := b.Body.Attributes["if_exists"]; ok {
+		g.ifExists, err = evalString(b.Body, "if_exists", evalCtx)
+		if err != nil {
+			return g, false, err
+		}
+	}
+	return g, true, nil
+}
+
+// evalString evaluates the attribute name of the body as a known string
+func evalString(body *hclsyntax.Body, name string, evalCtx *hcl.EvalContext) (string, error) {
+	a, ok := body.Attributes[name]
+	if !ok {
+		return "", fmt.Errorf("missing %q", name)
+	}
+	v, diags := a.Expr.Value(evalCtx)
+	if diags.HasErrors() {
+		return "", diags
+	}
+	if v.IsNull() || !v.IsWhollyKnown() || v.Type() != cty.String {
+		return "", fmt.Errorf("%q must be a known string", name)
+	}
+	return v.AsString(), nil
+}
+
+// toGoValues converts the values to Go values like the ones decoded from JSON, the
+// values that are not known are ignored
+func toGoValues(logger *slog.Logger, vals map[string]cty.Value) (map[string]interface{}, error) {
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	gvals := make(map[string]interface{}, len(vals))
+	for _, k := range keys {
+		v := vals[k]
+		if !v.IsWhollyKnown() {
+			logger.Debug("terragrunt: Ignoring input with unknown value", "key", k)
+			continue
+		}
+		b, err := ctyjson.Marshal(v, v.Type())
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert input %q: %w", k, err)
+		}
+		var gv interface{}
+		if err := json.Unmarshal(b, &gv); err != nil {
+			return nil, fmt.Errorf("failed to convert input %q: %w", k, err)
+		}
+		gvals[k] = gv
+	}
+	return gvals, nil
+}
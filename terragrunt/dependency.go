@@ -0,0 +1,194 @@
+package terragrunt
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// StateName is the name of the local state file of the units
+const StateName = "terraform.tfstate"
+
+// The strategies of 'mock_outputs_merge_strategy_with_state'
+const (
+	mergeNone    = "no_merge"
+	mergeShallow = "shallow"
+	mergeDeep    = "deep_map_only"
+)
+
+// Dependency is a 'dependency' block of a unit
+type Dependency struct {
+	Name string
+
+	// ConfigPath is the directory of the unit of the dependency
+	ConfigPath string
+
+	// Outputs are the outputs of the dependency, read from its local state file if it
+	// has one or the 'mock_outputs' if not. If there is none of them they are unknown,
+	// so the inputs that use them are ignored.
+	Outputs cty.Value
+
+	// StatePath is the path of the state file the Outputs were read from, empty if
+	// they are the 'mock_outputs'
+	StatePath string
+}
+
+// value returns the Dependency as it's accessed with 'dependency.NAME'
+func (d *Dependency) value() cty.Value {
+	return cty.ObjectVal(map[string]cty.Value{
+		"outputs":     d.Outputs,
+		"config_path": cty.StringVal(d.ConfigPath),
+	})
+}
+
+// parseDependency parses the 'dependency' block b
+func parseDependency(s *scope, b *hclsyntax.Block, evalCtx *hcl.EvalContext) (*Dependency, error) {
+	d := &Dependency{
+		Name:    b.Labels[0],
+		Outputs: cty.DynamicVal,
+	}
+	cp, err := evalString(b.Body, "config_path", evalCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate the config_path of the dependency %q of %q: %w", d.Name, s.file, err)
+	}
+	if !path.IsAbs(cp) {
+		cp = path.Join(s.dir, cp)
+	}
+	d.ConfigPath = cp
+
+	mocks := cty.NilVal
+	if a, ok := b.Body.Attributes["mock_outputs"]; ok {
+		v, diags := a.Expr.Value(evalCtx)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to evaluate the mock_outputs of the dependency %q of %q: %w", d.Name, s.file, diags)
+		}
+		if !v.IsNull() {
+			mocks = v
+		}
+	}
+
+	strategy := mergeNone
+	if a, ok := b.Body.Attributes["mock_outputs_merge_with_state"]; ok {
+		// Deprecated on favor of 'mock_outputs_merge_strategy_with_state'
+		v, diags := a.Expr.Value(evalCtx)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to evaluate the mock_outputs_merge_with_state of the dependency %q of %q: %w", d.Name, s.file, diags)
+		}
+		if v.Type() == cty.Bool && v.IsKnown() && !v.IsNull() && v.True() {
+			strategy = mergeShallow
+		}
+	}
+	if _, ok := b.Body.Attributes["mock_outputs_merge_strategy_with_state"]; ok {
+		strategy, err = evalString(b.Body, "mock_outputs_merge_strategy_with_state", evalCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate the mock_outputs_merge_strategy_with_state of the dependency %q of %q: %w", d.Name, s.file, err)
+		}
+		if strategy != mergeNone && strategy != mergeShallow && strategy != mergeDeep {
+			return nil, fmt.Errorf("invalid mock_outputs_merge_strategy_with_state %q of the dependency %q of %q", strategy, d.Name, s.file)
+		}
+	}
+
+	sp := path.Join(cp, StateName)
+	outputs, ok, err := readStateOutputs(s.fs, sp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the outputs of the dependency %q of %q: %w", d.Name, s.file, err)
+	}
+
+	switch {
+	case ok && mocks != cty.NilVal:
+		d.Outputs = mergeOutputs(mocks, outputs, strategy)
+		d.StatePath = sp
+	case ok:
+		d.Outputs = outputs
+		d.StatePath = sp
+	case mocks != cty.NilVal:
+		d.Outputs = mocks
+	}
+	s.logger.Debug("terragrunt: Dependency outputs", "name", d.Name, "path", d.ConfigPath, "state", d.StatePath)
+
+	return d, nil
+}
+
+// state is the part of a Terraform state file with the outputs
+type state struct {
+	Outputs map[string]struct {
+		Value json.RawMessage `json:"value"`
+		Type  json.RawMessage `json:"type"`
+	} `json:"outputs"`
+}
+
+// readStateOutputs reads the outputs of the state file on p as an object,
+// it returns false if there is no file
+func readStateOutputs(fs afero.Fs, p string) (cty.Value, bool, error) {
+	if ok, _ := afero.Exists(fs, p); !ok {
+		return cty.NilVal, false, nil
+	}
+
+	b, err := afero.ReadFile(fs, p)
+	if err != nil {
+		return cty.NilVal, false, fmt.Errorf("failed to read %q: %w", p, err)
+	}
+	var st state
+	if err := json.Unmarshal(b, &st); err != nil {
+		return cty.NilVal, false, fmt.Errorf("failed to decode %q: %w", p, err)
+	}
+
+	outputs := make(map[string]cty.Value, len(st.Outputs))
+	for n, o := range st.Outputs {
+		ty, err := ctyjson.UnmarshalType(o.Type)
+		if err != nil {
+			return cty.NilVal, false, fmt.Errorf("invalid type of the output %q on %q: %w", n, p, err)
+		}
+		v, err := ctyjson.Unmarshal(o.Value, ty)
+		if err != nil {
+			return cty.NilVal, false, fmt.Errorf("invalid value of the output %q on %q: %w", n, p, err)
+		}
+		outputs[n] = v
+	}
+	return cty.ObjectVal(outputs), true, nil
+}
+
+// mergeOutputs merges the outputs of the state with the mocks with the strategy, the ones
+// of the state have precedence. With 'shallow' only the missing outputs are taken from the
+// mocks, and with 'deep_map_only' the maps and objects are merged recursively.
+func mergeOutputs(mocks, outputs cty.Value, strategy string) cty.Value {
+	switch strategy {
+	case mergeShallow:
+		return mergeValues(mocks, outputs, false)
+	case mergeDeep:
+		return mergeValues(mocks, outputs, true)
+	default:
+		return outputs
+	}
+}
+
+// mergeValues merges the object or map src over dst, recursively if deep
+func mergeValues(dst, src cty.Value, deep bool) cty.Value {
+	if !isMapping(dst) || !isMapping(src) {
+		return src
+	}
+
+	vals := dst.AsValueMap()
+	if vals == nil {
+		vals = make(map[string]cty.Value)
+	}
+	for k, v := range src.AsValueMap() {
+		if dv, ok := vals[k]; ok && deep {
+			v = mergeValues(dv, v, deep)
+		}
+		vals[k] = v
+	}
+	return cty.ObjectVal(vals)
+}
+
+// isMapping checks if v is a known object or map
+func isMapping(v cty.Value) bool {
+	ty := v.Type()
+	return (ty.IsObjectType() || ty.IsMapType()) && v.IsKnown() && !v.IsNull()
+}
@@ -0,0 +1,3 @@
+// Package terragrunt parses the Terragrunt configurations of the units of a stack, with their includes, locals,
+// dependencies, inputs and module source, without running Terragrunt, so they can be estimated from any afero.Fs.
+package terragrunt
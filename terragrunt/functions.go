@@ -0,0 +1,225 @@
+package terragrunt
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/terraform/lang"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// scope is the scope on which a configuration file is evaluated, as the functions
+// of the included files are evaluated relative to the unit that includes them
+type scope struct {
+	fs afero.Fs
+
+	// dir is the directory of the unit
+	dir string
+
+	// file is the configuration file being evaluated
+	file string
+
+	// includeDir is the directory of the included file being
+	// evaluated, empty when evaluating the unit one
+	includeDir string
+
+	// depth is the number of includes and read_terragrunt_config
+	// to the file, to avoid cycles
+	depth int
+
+	logger *slog.Logger
+}
+
+// evalCtx returns an hcl.EvalContext with the Terraform and Terragrunt functions and the vars
+func (s *scope) evalCtx(vars map[string]cty.Value) *hcl.EvalContext {
+	fns := (&lang.Scope{BaseDir: s.dir}).Functions()
+	for n, f := range s.functions() {
+		fns[n] = f
+	}
+	return &hcl.EvalContext{
+		Variables: vars,
+		Functions: fns,
+	}
+}
+
+// functions returns the Terragrunt functions. The ones that need to execute something or
+// call the cloud providers, like 'run_cmd' or 'get_aws_account_id', return unknown values.
+func (s *scope) functions() map[string]function.Function {
+	unknown := function.New(&function.Spec{
+		VarParam: &function.Parameter{Type: cty.DynamicPseudoType, AllowUnknown: true, AllowNull: true},
+		Type:     function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			return cty.UnknownVal(cty.String), nil
+		},
+	})
+	return map[string]function.Function{
+		"find_in_parent_folders":      s.findInParentFoldersFunc(),
+		"path_relative_to_include":    stringFunc(s.pathRelativeToInclude),
+		"path_relative_from_include":  stringFunc(s.pathRelativeFromInclude),
+		"get_terragrunt_dir":          stringFunc(func() (string, error) { return s.dir, nil }),
+		"get_original_terragrunt_dir": stringFunc(func() (string, error) { return s.dir, nil }),
+		"get_parent_terragrunt_dir":   stringFunc(s.parentDir),
+		"get_repo_root":               stringFunc(s.repoRoot),
+		"get_path_from_repo_root":     stringFunc(s.pathFromRepoRoot),
+		"get_path_to_repo_root":       stringFunc(s.pathToRepoRoot),
+		"get_env":                     getEnvFunc,
+		"read_terragrunt_config":      s.readTerragruntConfigFunc(),
+
+		"get_aws_account_id":              unknown,
+		"get_aws_caller_identity_arn":     unknown,
+		"get_aws_caller_identity_user_id": unknown,
+		"run_cmd":                         unknown,
+		"sops_decrypt_file":               unknown,
+	}
+}
+
+// stringFunc returns a function without parameters that returns the string of fn
+func stringFunc(fn func() (string, error)) function.Function {
+	return function.New(&function.Spec{
+		// The optional name of the include is ignored as
+		// only the one being evaluated is known
+		VarParam: &function.Parameter{Type: cty.String},
+		Type:     function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			v, err := fn()
+			if err != nil {
+				return cty.NilVal, err
+			}
+			return cty.StringVal(v), nil
+		},
+	})
+}
+
+// findInParentFoldersFunc returns the 'find_in_parent_folders' function, which looks
+// for the file (terragrunt.hcl by default) from the parent directory of the unit
+func (s *scope) findInParentFoldersFunc() function.Function {
+	return function.New(&function.Spec{
+		VarParam: &function.Parameter{Type: cty.String},
+		Type:     function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			name := ConfigName
+			if len(args) > 0 {
+				name = args[0].AsString()
+			}
+			for dir := path.Dir(s.dir); ; dir = path.Dir(dir) {
+				p := path.Join(dir, name)
+				if ok, _ := afero.Exists(s.fs, p); ok {
+					return cty.StringVal(p), nil
+				}
+				if dir == path.Dir(dir) {
+					break
+				}
+			}
+			if len(args) > 1 {
+				return args[1], nil
+			}
+			return cty.NilVal, fmt.Errorf("could not find a %s in any of the parent folders of %s", name, s.dir)
+		},
+	})
+}
+
+// parentDir is the directory of the included file being evaluated
+func (s *scope) parentDir() (string, error) {
+	if s.includeDir == "" {
+		return s.dir, nil
+	}
+	return s.includeDir, nil
+}
+
+// pathRelativeToInclude is the path of the unit relative to the included file
+func (s *scope) pathRelativeToInclude() (string, error) {
+	if s.includeDir == "" {
+		return ".", nil
+	}
+	return filepath.Rel(s.includeDir, s.dir)
+}
+
+// pathRelativeFromInclude is the path of the included file relative to the unit
+func (s *scope) pathRelativeFromInclude() (string, error) {
+	if s.includeDir == "" {
+		return ".", nil
+	}
+	return filepath.Rel(s.dir, s.includeDir)
+}
+
+// repoRoot returns the first parent directory of the unit with a '.git'
+func (s *scope) repoRoot() (string, error) {
+	for dir := s.dir; ; dir = path.Dir(dir) {
+		if ok, _ := afero.Exists(s.fs, path.Join(dir, ".git")); ok {
+			return dir, nil
+		}
+		if dir == path.Dir(dir) {
+			break
+		}
+	}
+	return "", fmt.Errorf("%s is not on a git repository", s.dir)
+}
+
+// pathFromRepoRoot returns the path of the unit relative to the repository root
+func (s *scope) pathFromRepoRoot() (string, error) {
+	root, err := s.repoRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(root, s.dir)
+}
+
+// pathToRepoRoot returns the path of the repository root relative to the unit
+func (s *scope) pathToRepoRoot() (string, error) {
+	root, err := s.repoRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(s.dir, root)
+}
+
+// getEnvFunc is the 'get_env' function which returns the value of an
+// environment variable or the default, if any
+var getEnvFunc = function.New(&function.Spec{
+	Params:   []function.Parameter{{Name: "name", Type: cty.String}},
+	VarParam: &function.Parameter{Name: "default", Type: cty.String},
+	Type:     function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		if v, ok := os.LookupEnv(args[0].AsString()); ok {
+			return cty.StringVal(v), nil
+		}
+		if len(args) > 1 {
+			return args[1], nil
+		}
+		return cty.NilVal, fmt.Errorf("environment variable %q is not set", args[0].AsString())
+	},
+})
+
+// readTerragruntConfigFunc returns the 'read_terragrunt_config' function, which returns
+// the locals, inputs and dependencies of another configuration file
+func (s *scope) readTerragruntConfigFunc() function.Function {
+	return function.New(&function.Spec{
+		Params:   []function.Parameter{{Name: "path", Type: cty.String}},
+		VarParam: &function.Parameter{Name: "default", Type: cty.DynamicPseudoType},
+		Type:     function.StaticReturnType(cty.DynamicPseudoType),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			p := args[0].AsString()
+			if !path.IsAbs(p) {
+				p = path.Join(s.dir, p)
+			}
+			if ok, _ := afero.Exists(s.fs, p); !ok && len(args) > 1 {
+				return args[1], nil
+			}
+
+			// The file is evaluated as if it was the one of the unit
+			// but the nested includes are relative to it
+			rs := &scope{fs: s.fs, dir: path.Dir(p), file: p, depth: s.depth + 1, logger: s.logger}
+			c, err := parseFile(rs)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			return c.value(), nil
+		},
+	})
+}
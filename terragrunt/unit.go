@@ -0,0 +1,271 @@
+package terragrunt
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	"github.com/cycloidio/terracost/log"
+	"github.com/cycloidio/terracost/terraform"
+)
+
+// skipDirs are the directories in which no units are looked for
+var skipDirs = map[string]struct{}{
+	".terragrunt-cache": {},
+	".terraform":        {},
+	".git":              {},
+}
+
+// Unit is a directory with a Terragrunt configuration and a Terraform module
+type Unit struct {
+	// Path is the directory of the unit
+	Path string
+
+	// Source is the 'terraform.source', empty if the
+	// module is on the directory of the unit
+	Source string
+
+	// Inputs are the values of the variables of the module
+	Inputs map[string]interface{}
+
+	// Skip is set when the unit has 'skip = true'
+	Skip bool
+
+	// Dependencies are the 'dependency' blocks by name
+	Dependencies map[string]*Dependency
+
+	// generate are the files to generate on the module
+	generate map[string]generateBlock
+
+	// includes are the paths of the included files
+	includes []string
+
+	logger *slog.Logger
+}
+
+// FindUnits returns the units on the dir and its subdirectories sorted by path. The configurations
+// included by the units and the ones without 'terraform.source' nor Terraform files are not units,
+// like the root configurations. The logger of the ctx (see log.NewContext) is used.
+func FindUnits(ctx context.Context, fs afero.Fs, dir string) ([]*Unit, error) {
+	logger := log.FromContext(ctx)
+	dir = absPath(fs, dir)
+	dirs := make([]string, 0)
+	err := afero.Walk(fs, dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if _, ok := skipDirs[info.Name()]; ok {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Name() == ConfigName {
+			dirs = append(dirs, filepath.ToSlash(filepath.Dir(p)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk path %q: %w", dir, err)
+	}
+	sort.Strings(dirs)
+
+	// The included configurations may not be valid on their own,
+	// so the errors are only returned once it's known they are units
+	units := make([]*Unit, 0, len(dirs))
+	errs := make(map[string]error)
+	included := make(map[string]struct{})
+	for _, d := range dirs {
+		u, err := ParseUnit(ctx, fs, d)
+		if err != nil {
+			errs[d] = err
+			continue
+		}
+		for _, i := range u.includes {
+			included[i] = struct{}{}
+		}
+		units = append(units, u)
+	}
+	for _, d := range dirs {
+		if _, ok := included[path.Join(d, ConfigName)]; ok {
+			continue
+		}
+		if err, ok := errs[d]; ok {
+			return nil, err
+		}
+	}
+
+	res := make([]*Unit, 0, len(units))
+	for _, u := range units {
+		if _, ok := included[path.Join(u.Path, ConfigName)]; ok {
+			logger.Debug("terragrunt: Ignoring included configuration", "path", u.Path)
+			continue
+		}
+		if u.Source == "" {
+			tfs, err := afero.Glob(fs, path.Join(u.Path, "*.tf"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to list files of %q: %w", u.Path, err)
+			}
+			if len(tfs) == 0 {
+				logger.Debug("terragrunt: Ignoring configuration without module", "path", u.Path)
+				continue
+			}
+		}
+		res = append(res, u)
+	}
+	return res, nil
+}
+
+// ParseUnit parses the Terragrunt configuration of the unit on the dir with the ones it includes. Nothing is
+// executed, so the functions that need it, like 'run_cmd' or 'get_aws_account_id', return unknown values and
+// the inputs with them are ignored. The logger of the ctx (see log.NewContext) is used.
+func ParseUnit(ctx context.Context, fs afero.Fs, dir string) (*Unit, error) {
+	logger := log.FromContext(ctx)
+	dir = absPath(fs, dir)
+	s := &scope{fs: fs, dir: dir, file: path.Join(dir, ConfigName), logger: logger}
+	c, err := parseFile(s)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, err := toGoValues(logger, c.inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the inputs of %q: %w", dir, err)
+	}
+
+	u := &Unit{
+		Path:         dir,
+		Source:       c.source,
+		Inputs:       inputs,
+		Skip:         c.skip != nil && *c.skip,
+		Dependencies: c.deps,
+		generate:     c.generate,
+		includes:     c.includes,
+		logger:       logger,
+	}
+	return u, nil
+}
+
+// Module returns a Fs with the Terraform module of the unit and its path on it, like the working directory
+// Terragrunt prepares: the module of the Source (installed with the opts if it's remote) with the files of
+// the unit and the ones of the 'generate' blocks. The files are only written on memory over the fs.
+func (u *Unit) Module(fs afero.Fs, opts terraform.HCLOptions) (afero.Fs, string, error) {
+	dir := u.Path
+	if u.Source != "" {
+		var err error
+		dir, err = u.sourceDir(fs, opts)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	mfs := afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(fs), afero.NewMemMapFs())
+	if dir != u.Path {
+		fis, err := afero.ReadDir(fs, u.Path)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read %q: %w", u.Path, err)
+		}
+		for _, fi := range fis {
+			if fi.IsDir() || fi.Name() == ConfigName || strings.HasPrefix(fi.Name(), ".") {
+				continue
+			}
+			b, err := afero.ReadFile(fs, path.Join(u.Path, fi.Name()))
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to read %q: %w", fi.Name(), err)
+			}
+			err = afero.WriteFile(mfs, path.Join(dir, fi.Name()), b, 0644)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to write %q: %w", fi.Name(), err)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(u.generate))
+	for n := range u.generate {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		g := u.generate[n]
+		p := path.Join(dir, g.path)
+		if ok, _ := afero.Exists(mfs, p); ok && g.ifExists == "skip" {
+			continue
+		}
+		err := afero.WriteFile(mfs, p, []byte(g.contents), 0644)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to generate %q: %w", g.path, err)
+		}
+	}
+
+	return mfs, dir, nil
+}
+
+// sourceDir returns the directory of the module of the Source on the fs
+func (u *Unit) sourceDir(fs afero.Fs, opts terraform.HCLOptions) (string, error) {
+	src := u.Source
+	if strings.HasPrefix(src, "/") || strings.HasPrefix(src, "./") || strings.HasPrefix(src, "../") {
+		// The '//' of the subdirectories is just
+		// part of the path for the local ones
+		if !path.IsAbs(src) {
+			src = path.Join(u.Path, src)
+		}
+		return path.Clean(src), nil
+	}
+
+	src, ver, err := registrySource(src)
+	if err != nil {
+		return "", err
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = u.logger
+	}
+	if logger == nil {
+		logger = log.Logger
+	}
+	logger.Debug("terragrunt: Installing module", "source", src, "version", ver, "path", u.Path)
+	return terraform.InstallModule(fs, src, ver, opts)
+}
+
+// registrySource converts the sources of the Terraform Registry modules of
+// Terragrunt ('tfr://HOST/NAMESPACE/NAME/SYSTEM?version=VERSION') to the
+// ones of the module calls with their version
+func registrySource(src string) (string, string, error) {
+	if !strings.HasPrefix(src, "tfr:") {
+		return src, "", nil
+	}
+	u, err := url.Parse(src)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid source %q: %w", src, err)
+	}
+	addr := strings.TrimPrefix(u.Path, "/")
+	if u.Host != "" {
+		addr = u.Host + "/" + addr
+	}
+	return addr, u.Query().Get("version"), nil
+}
+
+// absPath returns the absolute path of p if it's relative and it's on the fs (which
+// is the case of the OS), so the functions can look for the files on its parents
+func absPath(fs afero.Fs, p string) string {
+	p = filepath.ToSlash(filepath.Clean(p))
+	if path.IsAbs(p) {
+		return p
+	}
+	ap, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	if _, err := fs.Stat(ap); err != nil {
+		return p
+	}
+	return filepath.ToSlash(ap)
+}
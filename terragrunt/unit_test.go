@@ -0,0 +1,206 @@
+package terragrunt_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cycloidio/terracost/terraform"
+	"github.com/cycloidio/terracost/terragrunt"
+)
+
+func TestFindUnits(t *testing.T) {
+	stack, err := filepath.Abs("../testdata/aws/terragrunt-native")
+	require.NoError(t, err)
+
+	units, err := terragrunt.FindUnits(context.Background(), afero.NewReadOnlyFs(afero.NewOsFs()), "../testdata/aws/terragrunt-native")
+	require.NoError(t, err)
+
+	// The root configuration is only included
+	require.Len(t, units, 6)
+
+	db := units[0]
+	assert.Equal(t, filepath.Join(stack, "live/db"), db.Path)
+	assert.Equal(t, "../../modules/instance", db.Source)
+	assert.False(t, db.Skip)
+	assert.Equal(t, map[string]interface{}{
+		"environment":   "qa-db",
+		"name":          "db",
+		"instance_type": "m5.large",
+		"volume_size":   float64(100),
+	}, db.Inputs)
+	require.Contains(t, db.Dependencies, "web")
+	assert.Equal(t, filepath.Join(stack, "live/web"), db.Dependencies["web"].ConfigPath)
+	assert.Empty(t, db.Dependencies["web"].StatePath)
+
+	local := units[1]
+	assert.Equal(t, filepath.Join(stack, "live/local"), local.Path)
+	assert.Empty(t, local.Source)
+	assert.Equal(t, map[string]interface{}{
+		"environment": "qa",
+		"name":        "local",
+	}, local.Inputs)
+
+	skipped := units[3]
+	assert.Equal(t, filepath.Join(stack, "live/skipped"), skipped.Path)
+	assert.True(t, skipped.Skip)
+
+	web := units[4]
+	assert.Equal(t, filepath.Join(stack, "live/web"), web.Path)
+	assert.Equal(t, filepath.Join(stack, "modules//instance"), filepath.Clean(web.Source))
+	// The 'owner' is ignored as its value comes from AWS
+	assert.Equal(t, map[string]interface{}{
+		"environment":   "qa",
+		"name":          "web",
+		"instance_type": "t3.large",
+	}, web.Inputs)
+
+	// The outputs of the queue come from its state merged with the mocks
+	// and the 'owner' is ignored as the web has no outputs
+	worker := units[5]
+	assert.Equal(t, filepath.Join(stack, "live/worker"), worker.Path)
+	assert.Equal(t, map[string]interface{}{
+		"environment":   "qa",
+		"name":          "worker",
+		"instance_type": "c5.large",
+		"volume_size":   float64(58),
+	}, worker.Inputs)
+	assert.Equal(t, filepath.Join(stack, "live/queue/terraform.tfstate"), worker.Dependencies["queue"].StatePath)
+}
+
+func TestParseUnit_DependencyOutputs(t *testing.T) {
+	state := `{
+  "version": 4,
+  "outputs": {
+    "type": {"value": "c5.large", "type": "string"},
+    "sizes": {"value": {"root": 8}, "type": ["object", {"root": "number"}]}
+  }
+}`
+	tcs := []struct {
+		name     string
+		strategy string
+		state    bool
+		inputs   map[string]interface{}
+	}{
+		{
+			name:   "Mocks",
+			inputs: map[string]interface{}{"type": "t3.nano", "sizes": map[string]interface{}{"data": float64(50)}, "extra": "mock"},
+		},
+		{
+			name:   "State",
+			state:  true,
+			inputs: map[string]interface{}{"type": "c5.large", "sizes": map[string]interface{}{"root": float64(8)}},
+		},
+		{
+			name:     "Shallow",
+			strategy: `mock_outputs_merge_strategy_with_state = "shallow"`,
+			state:    true,
+			inputs:   map[string]interface{}{"type": "c5.large", "sizes": map[string]interface{}{"root": float64(8)}, "extra": "mock"},
+		},
+		{
+			name:     "DeprecatedMerge",
+			strategy: `mock_outputs_merge_with_state = true`,
+			state:    true,
+			inputs:   map[string]interface{}{"type": "c5.large", "sizes": map[string]interface{}{"root": float64(8)}, "extra": "mock"},
+		},
+		{
+			name:     "Deep",
+			strategy: `mock_outputs_merge_strategy_with_state = "deep_map_only"`,
+			state:    true,
+			inputs:   map[string]interface{}{"type": "c5.large", "sizes": map[string]interface{}{"root": float64(8), "data": float64(50)}, "extra": "mock"},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			require.NoError(t, afero.WriteFile(fs, "/stack/app/terragrunt.hcl", []byte(`
+dependency "dep" {
+  config_path = "../dep"
+
+  mock_outputs = {
+    type  = "t3.nano"
+    sizes = { data = 50 }
+    extra = "mock"
+  }
+  `+tc.strategy+`
+}
+
+inputs = dependency.dep.outputs
+`), 0644))
+			if tc.state {
+				require.NoError(t, afero.WriteFile(fs, "/stack/dep/terraform.tfstate", []byte(state), 0644))
+			}
+
+			u, err := terragrunt.ParseUnit(context.Background(), fs, "/stack/app")
+			require.NoError(t, err)
+			assert.Equal(t, tc.inputs, u.Inputs)
+		})
+	}
+
+	t.Run("InvalidStrategy", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, "/stack/app/terragrunt.hcl", []byte(`
+dependency "dep" {
+  config_path                            = "../dep"
+  mock_outputs_merge_strategy_with_state = "deep"
+}
+`), 0644))
+
+		_, err := terragrunt.ParseUnit(context.Background(), fs, "/stack/app")
+		assert.Error(t, err)
+	})
+}
+
+func TestUnit_Module(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/stack/terragrunt.hcl": `
+generate "provider" {
+  path      = "provider.tf"
+  if_exists = "overwrite_terragrunt"
+  contents  = "provider \"aws\" {\n  region = \"${get_env("TC_TEST_UNDEFINED", "eu-west-3")}\"\n}\n"
+}
+`,
+		"/stack/modules/vpc/main.tf": `resource "aws_vpc" "this" {}`,
+		"/stack/vpc/terragrunt.hcl": `
+include {
+  path = find_in_parent_folders()
+}
+
+terraform {
+  source = "../modules/vpc"
+}
+`,
+		"/stack/vpc/extra.tf": `resource "aws_eip" "this" {}`,
+	}
+	for p, c := range files {
+		require.NoError(t, afero.WriteFile(fs, p, []byte(c), 0644))
+	}
+
+	units, err := terragrunt.FindUnits(context.Background(), fs, "/stack")
+	require.NoError(t, err)
+	require.Len(t, units, 1)
+
+	mfs, dir, err := units[0].Module(fs, terraform.HCLOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "/stack/modules/vpc", dir)
+
+	b, err := afero.ReadFile(mfs, "/stack/modules/vpc/provider.tf")
+	require.NoError(t, err)
+	assert.Equal(t, "provider \"aws\" {\n  region = \"eu-west-3\"\n}\n", string(b))
+
+	ok, err := afero.Exists(mfs, "/stack/modules/vpc/extra.tf")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Nothing is written to the original fs
+	for _, p := range []string{"/stack/modules/vpc/provider.tf", "/stack/modules/vpc/extra.tf"} {
+		ok, err := afero.Exists(fs, p)
+		require.NoError(t, err)
+		assert.False(t, ok, p)
+	}
+}
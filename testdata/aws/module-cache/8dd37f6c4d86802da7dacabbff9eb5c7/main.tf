@@ -0,0 +1,10 @@
+variable "instance_class" {
+  type    = string
+  default = "db.t3.micro"
+}
+
+resource "aws_db_instance" "this" {
+  engine            = "mysql"
+  instance_class    = var.instance_class
+  allocated_storage = 20
+}
@@ -0,0 +1,4 @@
+resource "aws_instance" "web" {
+  ami           = "ami-123456"
+  instance_type = "t3.large"
+}
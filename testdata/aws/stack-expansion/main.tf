@@ -0,0 +1,92 @@
+provider "aws" {
+  region = "eu-west-1"
+}
+
+variable "volumes" {
+  default = {
+    logs = {
+      size = 50
+      type = "gp3"
+    }
+    data = {
+      size = 200
+      type = "io1"
+    }
+  }
+}
+
+variable "buckets" {
+  default = ["assets", "backups"]
+}
+
+variable "nodes" {
+  default = {
+    api    = "t3.large"
+    worker = "m5.xlarge"
+  }
+}
+
+locals {
+  disks = [
+    {
+      device_name = "/dev/sdb"
+      size        = 100
+    },
+    {
+      device_name = "/dev/sdc"
+      size        = 300
+    },
+  ]
+}
+
+data "aws_ec2_instance_type" "default" {
+  instance_type = "t3.medium"
+}
+
+resource "aws_ebs_volume" "volume" {
+  for_each = var.volumes
+
+  availability_zone = "eu-west-1a"
+  size              = each.value.size
+  type              = each.value.type
+
+  tags = {
+    Name = each.key
+  }
+}
+
+resource "aws_s3_bucket" "bucket" {
+  for_each = toset(var.buckets)
+
+  bucket = "terracost-${each.value}"
+}
+
+resource "aws_instance" "app" {
+  count = 2
+
+  ami           = "ami-123456"
+  instance_type = data.aws_ec2_instance_type.default.instance_type
+
+  tags = {
+    Name = "app-${count.index}"
+  }
+
+  dynamic "ebs_block_device" {
+    for_each = local.disks
+    iterator = disk
+
+    content {
+      device_name = disk.value.device_name
+      volume_size = disk.value.size
+      volume_type = "gp3"
+    }
+  }
+}
+
+module "node" {
+  source   = "./module-node"
+  for_each = var.nodes
+
+  name          = each.key
+  instance_type = each.value
+}
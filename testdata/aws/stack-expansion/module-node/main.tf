@@ -0,0 +1,25 @@
+variable "name" {}
+
+variable "instance_type" {}
+
+variable "disk_sizes" {
+  default = [20, 40]
+}
+
+resource "aws_instance" "node" {
+  ami           = "ami-123456"
+  instance_type = var.instance_type
+
+  dynamic "ebs_block_device" {
+    for_each = var.disk_sizes
+
+    content {
+      device_name = "/dev/sd${ebs_block_device.key}"
+      volume_size = ebs_block_device.value
+    }
+  }
+
+  tags = {
+    Name = var.name
+  }
+}
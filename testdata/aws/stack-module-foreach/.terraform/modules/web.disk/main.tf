@@ -0,0 +1,8 @@
+variable "size" {
+  type = number
+}
+
+resource "aws_ebs_volume" "this" {
+  availability_zone = "eu-west-1a"
+  size              = var.size
+}
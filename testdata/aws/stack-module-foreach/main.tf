@@ -0,0 +1,11 @@
+provider "aws" {
+  region = "eu-west-1"
+}
+
+module "web" {
+  for_each = toset(["a", "b"])
+  source   = "./modules/web"
+
+  instance_type = "t3.large"
+  disk_size     = each.key == "a" ? 50 : 100
+}
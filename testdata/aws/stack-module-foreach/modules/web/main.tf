@@ -0,0 +1,19 @@
+variable "instance_type" {
+  type = string
+}
+
+variable "disk_size" {
+  type = number
+}
+
+resource "aws_instance" "this" {
+  ami           = "ami-123456"
+  instance_type = var.instance_type
+}
+
+module "disk" {
+  source  = "terraform-aws-modules/ebs-volume/aws"
+  version = "1.2.0"
+
+  size = var.disk_size
+}
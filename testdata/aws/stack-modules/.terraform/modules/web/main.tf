@@ -0,0 +1,16 @@
+variable "instance_type" {
+  type    = string
+  default = "t3.micro"
+}
+
+resource "aws_instance" "this" {
+  ami           = "ami-123456"
+  instance_type = var.instance_type
+}
+
+module "disk" {
+  source  = "terraform-aws-modules/ebs-volume/aws"
+  version = "1.2.0"
+
+  size = 100
+}
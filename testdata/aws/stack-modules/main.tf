@@ -0,0 +1,17 @@
+provider "aws" {
+  region = "eu-west-1"
+}
+
+module "web" {
+  source  = "terraform-aws-modules/ec2-instance/aws"
+  version = "~> 5.0"
+
+  instance_type = "t3.large"
+}
+
+module "db" {
+  source  = "terraform-aws-modules/rds/aws"
+  version = ">= 6.0, < 7.0"
+
+  instance_class = "db.t3.medium"
+}
@@ -0,0 +1,44 @@
+terraform {
+  required_providers {
+    aws = {
+      source  = "registry.opentofu.org/hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+provider "aws" {
+  region = var.region
+}
+
+variable "region" {
+  type    = string
+  default = "eu-west-1"
+}
+
+variable "modules_path" {
+  type    = string
+  default = "./modules"
+}
+
+variable "registry" {
+  type    = string
+  default = "app.example.com/acme"
+}
+
+locals {
+  web_version = "1.0.0"
+}
+
+# OpenTofu evaluates the variables and locals on the
+# module source and version before loading the modules
+module "web" {
+  source  = "${var.registry}/web/aws"
+  version = local.web_version
+}
+
+module "db" {
+  source = "${var.modules_path}/db"
+
+  instance_class = "db.r5.large"
+}
@@ -0,0 +1,9 @@
+variable "instance_class" {
+  type = string
+}
+
+resource "aws_db_instance" "db" {
+  engine            = "mysql"
+  instance_class    = var.instance_class
+  allocated_storage = 100
+}
@@ -0,0 +1,14 @@
+provider "aws" {
+  region = "eu-west-1"
+}
+
+module "web" {
+  source  = "app.example.com/acme/web/aws"
+  version = "~> 1.0"
+}
+
+module "db" {
+  source = "git::https://git.example.com/acme/modules.git//db?ref=v1.0.0"
+
+  instance_class = "db.m5.large"
+}
@@ -0,0 +1,42 @@
+provider "aws" {
+  region = var.region
+}
+
+variable "region" {
+  default = "us-east-1"
+}
+
+variable "ami" {
+  default = "ami-123456"
+}
+
+variable "instance_type" {
+  type    = string
+  default = "t2.micro"
+}
+
+variable "instance_count" {
+  type    = number
+  default = 1
+}
+
+variable "volume_size" {
+  type    = number
+  default = 10
+}
+
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+
+resource "aws_instance" "web" {
+  count         = var.instance_count
+  ami           = var.ami
+  instance_type = var.instance_type
+  tags          = var.tags
+
+  root_block_device {
+    volume_size = var.volume_size
+  }
+}
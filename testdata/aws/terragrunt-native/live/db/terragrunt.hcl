@@ -0,0 +1,26 @@
+include "root" {
+  path   = find_in_parent_folders()
+  expose = true
+}
+
+locals {
+  size = 100
+}
+
+dependency "web" {
+  config_path = "../web"
+
+  mock_outputs = {
+    instance_type = "m5.large"
+  }
+}
+
+terraform {
+  source = "../../modules/instance"
+}
+
+inputs = {
+  instance_type = dependency.web.outputs.instance_type
+  volume_size   = local.size
+  environment   = "${include.root.inputs.environment}-db"
+}
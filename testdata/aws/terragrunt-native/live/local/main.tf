@@ -0,0 +1,12 @@
+variable "environment" {
+  type = string
+}
+
+resource "aws_instance" "local" {
+  ami           = "ami-0123456789"
+  instance_type = "t3.small"
+
+  tags = {
+    Environment = var.environment
+  }
+}
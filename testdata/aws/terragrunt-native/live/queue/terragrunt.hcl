@@ -0,0 +1,11 @@
+include "root" {
+  path = find_in_parent_folders()
+}
+
+terraform {
+  source = "../../modules/instance"
+}
+
+inputs = {
+  instance_type = "c5.large"
+}
@@ -0,0 +1,9 @@
+include "root" {
+  path = find_in_parent_folders()
+}
+
+terraform {
+  source = "../../modules/instance"
+}
+
+skip = true
@@ -0,0 +1,12 @@
+include "root" {
+  path = find_in_parent_folders()
+}
+
+terraform {
+  source = "${dirname(find_in_parent_folders())}/modules//instance"
+}
+
+inputs = {
+  instance_type = "t3.large"
+  owner         = get_aws_account_id()
+}
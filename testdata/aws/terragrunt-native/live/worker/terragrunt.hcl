@@ -0,0 +1,31 @@
+include "root" {
+  path = find_in_parent_folders()
+}
+
+# The outputs are read from the state of the queue, the
+# mocks are only used for the ones that are not on it
+dependency "queue" {
+  config_path = "../queue"
+
+  mock_outputs = {
+    instance_type = "t3.nano"
+    volume_sizes = {
+      data = 50
+    }
+  }
+  mock_outputs_merge_strategy_with_state = "deep_map_only"
+}
+
+dependency "web" {
+  config_path = "../web"
+}
+
+terraform {
+  source = "../../modules/instance"
+}
+
+inputs = {
+  instance_type = dependency.queue.outputs.instance_type
+  volume_size   = dependency.queue.outputs.volume_sizes.root + dependency.queue.outputs.volume_sizes.data
+  owner         = dependency.web.outputs.owner
+}
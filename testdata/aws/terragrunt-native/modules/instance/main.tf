@@ -0,0 +1,31 @@
+variable "name" {
+  type = string
+}
+
+variable "environment" {
+  type = string
+}
+
+variable "instance_type" {
+  type    = string
+  default = "t3.micro"
+}
+
+variable "volume_size" {
+  type    = number
+  default = 8
+}
+
+resource "aws_instance" "this" {
+  ami           = "ami-0123456789"
+  instance_type = var.instance_type
+
+  root_block_device {
+    volume_size = var.volume_size
+  }
+
+  tags = {
+    Name        = var.name
+    Environment = var.environment
+  }
+}
@@ -0,0 +1,28 @@
+locals {
+  region_vars = read_terragrunt_config(find_in_parent_folders("region.hcl"))
+  aws_region  = local.region_vars.locals.aws_region
+}
+
+generate "provider" {
+  path      = "provider.tf"
+  if_exists = "overwrite_terragrunt"
+  contents  = <<EOF
+provider "aws" {
+  region = "${local.aws_region}"
+}
+EOF
+}
+
+remote_state {
+  backend = "s3"
+  config = {
+    bucket = "terraform-state-${get_aws_account_id()}"
+    key    = "${path_relative_to_include()}/terraform.tfstate"
+    region = local.aws_region
+  }
+}
+
+inputs = {
+  environment = "qa"
+  name        = basename(path_relative_to_include())
+}
@@ -0,0 +1,227 @@
+package usage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Errors that might be returned when loading the usage
+var (
+	ErrUnknownKey          = errors.New("unknown key")
+	ErrUnknownResourceType = errors.New("unknown resource type")
+	ErrTypeMismatch        = errors.New("type mismatch")
+)
+
+// FieldError is an error on a field of a usage file
+type FieldError struct {
+	Line   int
+	Column int
+
+	// Path is the path of the field, like 'resource_default_type_usage.aws_s3_bucket.storage_gb'
+	Path string
+
+	Err error
+}
+
+// Error returns the error with its position
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s: %s", e.Line, e.Column, e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// ValidationError is the list of all the errors found on a usage file
+type ValidationError []*FieldError
+
+// Error returns all the errors, one per line
+func (e ValidationError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors of each field
+func (e ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, fe := range e {
+		errs = append(errs, fe)
+	}
+	return errs
+}
+
+// Load reads the Usage from r, which can be YAML or JSON. The usage of each resource is validated against the
+// Schema registered for its type, so the providers have to be imported before. All the unknown keys and values
+// with the wrong type are returned as a ValidationError with the position of each one of them.
+func Load(r io.Reader) (Usage, error) {
+	var u Usage
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return u, fmt.Errorf("failed to read usage: %w", err)
+	}
+
+	// As JSON is a subset of YAML the YAML
+	// parser is used for both
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return u, fmt.Errorf("failed to parse usage: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return u, nil
+	}
+	root := doc.Content[0]
+
+	var v validator
+	v.validateUsage(root)
+	if len(v.errs) != 0 {
+		return u, v.errs
+	}
+
+	if err := root.Decode(&u); err != nil {
+		return u, fmt.Errorf("failed to decode usage: %w", err)
+	}
+	return u, nil
+}
+
+// validator validates the usage nodes
+// keeping all the errors found
+type validator struct {
+	errs ValidationError
+}
+
+// addError adds the err found on the node n with the path
+func (v *validator) addError(n *yaml.Node, path string, err error) {
+	v.errs = append(v.errs, &FieldError{
+		Line:   n.Line,
+		Column: n.Column,
+		Path:   path,
+		Err:    err,
+	})
+}
+
+// validateUsage validates the root node n of the usage
+func (v *validator) validateUsage(n *yaml.Node) {
+	if !v.expectObject(n, "") {
+		return
+	}
+	for i := 0; i < len(n.Content); i += 2 {
+		k, val := n.Content[i], n.Content[i+1]
+		switch k.Value {
+		case "resource_default_type_usage":
+			v.validateResources(val, k.Value, func(rt string) string { return rt })
+		case "resource_usage":
+			v.validateResources(val, k.Value, resourceTypeFromAddress)
+		default:
+			v.addError(k, k.Value, fmt.Errorf("%w %q", ErrUnknownKey, k.Value))
+		}
+	}
+}
+
+// validateResources validates the node n which has the usage of the resources by
+// key, the resourceType returns the type of the resource of the key if known
+func (v *validator) validateResources(n *yaml.Node, path string, resourceType func(key string) string) {
+	if !v.expectObject(n, path) {
+		return
+	}
+	for i := 0; i < len(n.Content); i += 2 {
+		k, val := n.Content[i], n.Content[i+1]
+		p := fmt.Sprintf("%s.%s", path, k.Value)
+
+		rt := resourceType(k.Value)
+		if rt == "" {
+			// If the type is not known, like with the addresses with
+			// wildcards, only the format can be validated
+			v.expectObject(val, p)
+			continue
+		}
+
+		s, ok := GetSchema(rt)
+		if !ok {
+			v.addError(k, p, fmt.Errorf("%w %q", ErrUnknownResourceType, rt))
+			continue
+		}
+		v.validateAttributes(val, p, s)
+	}
+}
+
+// validateAttributes validates the attributes of the node n with the Schema s
+func (v *validator) validateAttributes(n *yaml.Node, path string, s Schema) {
+	if !v.expectObject(n, path) {
+		return
+	}
+	for i := 0; i < len(n.Content); i += 2 {
+		k, val := n.Content[i], n.Content[i+1]
+		p := fmt.Sprintf("%s.%s", path, k.Value)
+
+		a, ok := s.Get(k.Value)
+		if !ok {
+			v.addError(k, p, fmt.Errorf("%w %q", ErrUnknownKey, k.Value))
+			continue
+		}
+
+		if a.Type == Object {
+			v.validateAttributes(val, p, a.Attributes)
+			continue
+		}
+		if t := nodeType(val); t != a.Type {
+			v.addError(val, p, fmt.Errorf("%w: expected %s, got %s", ErrTypeMismatch, a.Type, t))
+		}
+	}
+}
+
+// expectObject checks that the node n is an Object
+func (v *validator) expectObject(n *yaml.Node, path string) bool {
+	if t := nodeType(n); t != Object {
+		v.addError(n, path, fmt.Errorf("%w: expected %s, got %s", ErrTypeMismatch, Object, t))
+		return false
+	}
+	return true
+}
+
+// nodeType returns the Type of the value of the node n
+func nodeType(n *yaml.Node) Type {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return Object
+	case yaml.SequenceNode:
+		return "list"
+	case yaml.AliasNode:
+		return nodeType(n.Alias)
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!int", "!!float":
+			return Number
+		case "!!bool":
+			return Bool
+		case "!!str":
+			return String
+		case "!!null":
+			return "null"
+		}
+	}
+	return Type(n.ShortTag())
+}
+
+// resourceTypeFromAddress returns the type of the resource from the address,
+// if it cannot be known, because of the wildcards, it returns an empty string
+func resourceTypeFromAddress(address string) string {
+	parts := strings.Split(reInstanceKey.ReplaceAllString(address, ""), ".")
+	if len(parts) < 2 {
+		return ""
+	}
+
+	// The type is always followed by the name of the
+	// resource and it cannot be the name of a module
+	rt := parts[len(parts)-2]
+	if strings.Contains(rt, "*") || (len(parts) > 2 && parts[len(parts)-3] == "module") {
+		return ""
+	}
+	return rt
+}
@@ -0,0 +1,151 @@
+package usage_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	// The providers register the Schema of their resource types
+	_ "github.com/cycloidio/terracost/aws/terraform"
+	_ "github.com/cycloidio/terracost/azurerm/terraform"
+	"github.com/cycloidio/terracost/usage"
+)
+
+func init() {
+	usage.RegisterSchema("test_bucket", usage.Schema{
+		{Key: "storage_gb", Type: usage.Number, Unit: "GB"},
+		{Key: "storage_class", Type: usage.String},
+		{Key: "disk", Type: usage.Object, Attributes: usage.Schema{
+			{Key: "operations", Type: usage.Number},
+		}},
+	})
+}
+
+func TestLoad(t *testing.T) {
+	expected := usage.Usage{
+		ResourceDefaultTypeUsage: map[string]interface{}{
+			"test_bucket": map[string]interface{}{
+				"storage_gb":    200,
+				"storage_class": "standard",
+				"disk": map[string]interface{}{
+					"operations": 1000,
+				},
+			},
+		},
+		ResourceUsage: map[string]interface{}{
+			"module.logs.test_bucket.*": map[string]interface{}{
+				"storage_gb": 50000.5,
+			},
+			"module.logs.*": map[string]interface{}{
+				"anything": 1,
+			},
+		},
+	}
+
+	t.Run("YAML", func(t *testing.T) {
+		u, err := usage.Load(strings.NewReader(`
+resource_default_type_usage:
+  test_bucket:
+    storage_gb: 200
+    storage_class: standard
+    disk:
+      operations: 1000
+resource_usage:
+  module.logs.test_bucket.*:
+    storage_gb: 50000.5
+  module.logs.*:
+    anything: 1
+`))
+		require.NoError(t, err)
+		assert.Equal(t, expected, u)
+	})
+	t.Run("JSON", func(t *testing.T) {
+		u, err := usage.Load(strings.NewReader(`{
+	"resource_default_type_usage": {
+		"test_bucket": {
+			"storage_gb": 200,
+			"storage_class": "standard",
+			"disk": {"operations": 1000}
+		}
+	},
+	"resource_usage": {
+		"module.logs.test_bucket.*": {"storage_gb": 50000.5},
+		"module.logs.*": {"anything": 1}
+	}
+}`))
+		require.NoError(t, err)
+		assert.Equal(t, expected, u)
+	})
+	t.Run("Empty", func(t *testing.T) {
+		u, err := usage.Load(strings.NewReader(""))
+		require.NoError(t, err)
+		assert.Equal(t, usage.Usage{}, u)
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := usage.Load(strings.NewReader(`
+resource_default_type_usage:
+  test_bucket:
+    storage_gbs: 200
+    storage_class: 1
+    disk:
+      operations: many
+  potato_bucket:
+    storage_gb: 1
+resource_usage:
+  test_bucket.logs:
+    storage_gb: [1]
+resource_usages: {}
+`))
+		require.Error(t, err)
+
+		var verr usage.ValidationError
+		require.True(t, errors.As(err, &verr))
+		require.Len(t, verr, 6)
+		assert.True(t, errors.Is(err, usage.ErrUnknownKey))
+		assert.True(t, errors.Is(err, usage.ErrUnknownResourceType))
+		assert.True(t, errors.Is(err, usage.ErrTypeMismatch))
+		assert.Equal(t, strings.Join([]string{
+			`line 4, column 5: resource_default_type_usage.test_bucket.storage_gbs: unknown key "storage_gbs"`,
+			`line 5, column 20: resource_default_type_usage.test_bucket.storage_class: type mismatch: expected string, got number`,
+			`line 7, column 19: resource_default_type_usage.test_bucket.disk.operations: type mismatch: expected number, got string`,
+			`line 8, column 3: resource_default_type_usage.potato_bucket: unknown resource type "potato_bucket"`,
+			`line 12, column 17: resource_usage.test_bucket.logs.storage_gb: type mismatch: expected number, got list`,
+			`line 13, column 1: resource_usages: unknown key "resource_usages"`,
+		}, "\n"), err.Error())
+	})
+	t.Run("InvalidSyntax", func(t *testing.T) {
+		_, err := usage.Load(strings.NewReader(`{"resource_default_type_usage": `))
+		assert.Error(t, err)
+	})
+}
+
+func TestLoad_Default(t *testing.T) {
+	for rt := range usage.Default.ResourceDefaultTypeUsage {
+		_, ok := usage.GetSchema(rt)
+		assert.True(t, ok, "no schema for %s", rt)
+	}
+
+	t.Run("Marshalled", func(t *testing.T) {
+		b, err := yaml.Marshal(usage.Default)
+		require.NoError(t, err)
+
+		_, err = usage.Load(bytes.NewReader(b))
+		require.NoError(t, err)
+	})
+	t.Run("Template", func(t *testing.T) {
+		resources := make([]usage.TemplateResource, 0, len(usage.Default.ResourceDefaultTypeUsage))
+		for rt := range usage.Default.ResourceDefaultTypeUsage {
+			resources = append(resources, usage.TemplateResource{Address: rt + ".this", Type: rt})
+		}
+		var buf bytes.Buffer
+		require.NoError(t, usage.WriteTemplate(&buf, resources, usage.Default))
+
+		_, err := usage.Load(&buf)
+		require.NoError(t, err)
+	})
+}
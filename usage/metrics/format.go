@@ -0,0 +1,305 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrMissingColumn is returned when a required column is not on a CSV export
+var ErrMissingColumn = errors.New("missing column")
+
+// CSV reads the exports in CSV with a header. The columns are the ones on the fields
+// and all the other columns are the Dimensions of the metrics.
+type CSV struct {
+	// Resource, Namespace, Metric, Timestamp, Value and Unit are the names
+	// of the columns of each field, only Metric, Timestamp and Value are required
+	Resource  string
+	Namespace string
+	Metric    string
+	Timestamp string
+	Value     string
+	Unit      string
+
+	// TimeLayout is the layout of the Timestamp, time.RFC3339 if empty
+	TimeLayout string
+}
+
+// DefaultCSV is the CSV with the columns 'resource', 'namespace', 'metric', 'timestamp', 'value' and 'unit'
+var DefaultCSV = CSV{
+	Resource:  "resource",
+	Namespace: "namespace",
+	Metric:    "metric",
+	Timestamp: "timestamp",
+	Value:     "value",
+	Unit:      "unit",
+}
+
+// Read reads the Datapoints from the CSV export r
+func (c CSV) Read(r io.Reader) ([]Datapoint, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header: %w", err)
+	}
+
+	cols := make(map[string]int, len(header))
+	for i, h := range header {
+		cols[strings.TrimSpace(h)] = i
+	}
+	for _, rc := range []string{c.Metric, c.Timestamp, c.Value} {
+		if _, ok := cols[rc]; !ok {
+			return nil, fmt.Errorf("%w %q", ErrMissingColumn, rc)
+		}
+	}
+	fields := map[string]bool{c.Resource: true, c.Namespace: true, c.Metric: true, c.Timestamp: true, c.Value: true, c.Unit: true}
+
+	layout := c.TimeLayout
+	if layout == "" {
+		layout = time.RFC3339
+	}
+
+	dps := make([]Datapoint, 0)
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read record: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+
+		get := func(col string) string {
+			if i, ok := cols[col]; ok && col != "" {
+				return rec[i]
+			}
+			return ""
+		}
+
+		// The empty values are the periods without datapoints
+		vs := get(c.Value)
+		if vs == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(vs, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value on line %d: %w", line, err)
+		}
+		ts, err := time.Parse(layout, get(c.Timestamp))
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp on line %d: %w", line, err)
+		}
+
+		dims := make(map[string]string)
+		for i, h := range header {
+			if !fields[h] && rec[i] != "" {
+				dims[h] = rec[i]
+			}
+		}
+
+		dps = append(dps, Datapoint{
+			Resource:   get(c.Resource),
+			Namespace:  get(c.Namespace),
+			Metric:     get(c.Metric),
+			Dimensions: dims,
+			Timestamp:  ts,
+			Value:      v,
+			Unit:       get(c.Unit),
+		})
+	}
+
+	return dps, nil
+}
+
+// cloudWatchMetric is the output of 'aws cloudwatch get-metric-statistics'
+// with the Namespace, MetricName and Dimensions of the request
+type cloudWatchMetric struct {
+	Namespace  string `json:"Namespace"`
+	MetricName string `json:"MetricName"`
+	Label      string `json:"Label"`
+	Dimensions []struct {
+		Name  string `json:"Name"`
+		Value string `json:"Value"`
+	} `json:"Dimensions"`
+	Datapoints []map[string]interface{} `json:"Datapoints"`
+}
+
+// cloudWatchStatistics are the statistics of the CloudWatch datapoints
+// in the order they are used if there are more than one
+var cloudWatchStatistics = []string{"Sum", "Average", "Maximum", "Minimum", "SampleCount"}
+
+// CloudWatchJSON reads the exports in JSON of the output of 'aws cloudwatch get-metric-statistics' with
+// the Namespace, MetricName and Dimensions of the request added, as a single object or a list of them.
+// If the datapoints have more than one statistic the first of Sum, Average, Maximum, Minimum and
+// SampleCount is used.
+var CloudWatchJSON = FormatFunc(func(r io.Reader) ([]Datapoint, error) {
+	var ms []cloudWatchMetric
+	if err := decodeJSONList(r, &ms); err != nil {
+		return nil, err
+	}
+
+	dps := make([]Datapoint, 0)
+	for _, m := range ms {
+		name := m.MetricName
+		if name == "" {
+			name = m.Label
+		}
+		dims := make(map[string]string, len(m.Dimensions))
+		for _, d := range m.Dimensions {
+			dims[d.Name] = d.Value
+		}
+
+		for _, cdp := range m.Datapoints {
+			ts, err := time.Parse(time.RFC3339, fmt.Sprint(cdp["Timestamp"]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp of %q: %w", name, err)
+			}
+
+			var (
+				v  float64
+				ok bool
+			)
+			for _, s := range cloudWatchStatistics {
+				if v, ok = cdp[s].(float64); ok {
+					break
+				}
+			}
+			if !ok {
+				return nil, fmt.Errorf("no statistic on the datapoint of %q at %s", name, ts)
+			}
+
+			unit, _ := cdp["Unit"].(string)
+			dps = append(dps, Datapoint{
+				Namespace:  m.Namespace,
+				Metric:     name,
+				Dimensions: dims,
+				Timestamp:  ts,
+				Value:      v,
+				Unit:       unit,
+			})
+		}
+	}
+
+	return dps, nil
+})
+
+// azureMonitorResponse is the response of the Azure Monitor Metrics API
+type azureMonitorResponse struct {
+	Namespace string `json:"namespace"`
+	Value     []struct {
+		ID   string `json:"id"`
+		Name struct {
+			Value string `json:"value"`
+		} `json:"name"`
+		Unit       string `json:"unit"`
+		Timeseries []struct {
+			Metadatavalues []struct {
+				Name struct {
+					Value string `json:"value"`
+				} `json:"name"`
+				Value string `json:"value"`
+			} `json:"metadatavalues"`
+			Data []map[string]interface{} `json:"data"`
+		} `json:"timeseries"`
+	} `json:"value"`
+}
+
+// azureMonitorAggregations are the aggregations of the Azure Monitor data
+// in the order they are used if there are more than one
+var azureMonitorAggregations = []string{"total", "average", "maximum", "minimum", "count"}
+
+// azureMonitorMetricsPath is the path that the Azure Monitor Metrics API
+// adds to the resource ID on the ID of the metrics
+const azureMonitorMetricsPath = "/providers/Microsoft.Insights/metrics/"
+
+// AzureMonitorJSON reads the exports in JSON of the response of the Azure Monitor Metrics API
+// ('az monitor metrics list'), as a single object or a list of them. The Resource of the
+// Datapoints is the Azure resource ID. If the data has more than one aggregation the first of
+// total, average, maximum, minimum and count is used.
+var AzureMonitorJSON = FormatFunc(func(r io.Reader) ([]Datapoint, error) {
+	var rs []azureMonitorResponse
+	if err := decodeJSONList(r, &rs); err != nil {
+		return nil, err
+	}
+
+	dps := make([]Datapoint, 0)
+	for _, res := range rs {
+		for _, m := range res.Value {
+			resource := m.ID
+			if i := strings.Index(strings.ToLower(resource), strings.ToLower(azureMonitorMetricsPath)); i != -1 {
+				resource = resource[:i]
+			}
+
+			for _, ts := range m.Timeseries {
+				dims := make(map[string]string, len(ts.Metadatavalues))
+				for _, md := range ts.Metadatavalues {
+					dims[md.Name.Value] = md.Value
+				}
+
+				for _, d := range ts.Data {
+					t, err := time.Parse(time.RFC3339, fmt.Sprint(d["timeStamp"]))
+					if err != nil {
+						return nil, fmt.Errorf("invalid timestamp of %q: %w", m.Name.Value, err)
+					}
+
+					var (
+						v  float64
+						ok bool
+					)
+					for _, a := range azureMonitorAggregations {
+						if v, ok = d[a].(float64); ok {
+							break
+						}
+					}
+					// The periods without data
+					// have no aggregations
+					if !ok {
+						continue
+					}
+
+					dps = append(dps, Datapoint{
+						Resource:   resource,
+						Namespace:  res.Namespace,
+						Metric:     m.Name.Value,
+						Dimensions: dims,
+						Timestamp:  t,
+						Value:      v,
+						Unit:       m.Unit,
+					})
+				}
+			}
+		}
+	}
+
+	return dps, nil
+})
+
+// decodeJSONList decodes the JSON of r, which can be a single object
+// or a list of them, on the list v
+func decodeJSONList[T any](r io.Reader, v *[]T) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read: %w", err)
+	}
+	if t := strings.TrimSpace(string(b)); strings.HasPrefix(t, "[") {
+		if err := json.Unmarshal(b, v); err != nil {
+			return fmt.Errorf("failed to decode: %w", err)
+		}
+		return nil
+	}
+
+	var o T
+	if err := json.Unmarshal(b, &o); err != nil {
+		return fmt.Errorf("failed to decode: %w", err)
+	}
+	*v = []T{o}
+	return nil
+}
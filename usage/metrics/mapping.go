@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"time"
+)
+
+// Units conversion factors
+const (
+	// BytesToGB converts bytes to GB
+	BytesToGB = 1.0 / (1 << 30)
+
+	// hoursPerMonth is the same used by the cost package
+	hoursPerMonth = 730
+)
+
+// Aggregation computes the usage value from the datapoints
+// of a resource, which are sorted by timestamp
+type Aggregation func(dps []Datapoint) float64
+
+// Average is the average of the values, for the metrics of
+// a size like the storage
+func Average(dps []Datapoint) float64 {
+	if len(dps) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, dp := range dps {
+		sum += dp.Value
+	}
+	return sum / float64(len(dps))
+}
+
+// Maximum is the maximum of the values
+func Maximum(dps []Datapoint) float64 {
+	var max float64
+	for i, dp := range dps {
+		if i == 0 || dp.Value > max {
+			max = dp.Value
+		}
+	}
+	return max
+}
+
+// Latest is the value of the last datapoint
+func Latest(dps []Datapoint) float64 {
+	if len(dps) == 0 {
+		return 0
+	}
+	return dps[len(dps)-1].Value
+}
+
+// MonthlySum is the sum of the values extrapolated to a month, for the metrics of
+// a count like the requests or the data transferred. The period of each datapoint
+// is the smallest interval between them, and with only one datapoint its value
+// is considered to be of the whole month.
+func MonthlySum(dps []Datapoint) float64 {
+	if len(dps) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, dp := range dps {
+		sum += dp.Value
+	}
+	if len(dps) == 1 {
+		return sum
+	}
+
+	var period time.Duration
+	for i := 1; i < len(dps); i++ {
+		if d := dps[i].Timestamp.Sub(dps[i-1].Timestamp); d > 0 && (period == 0 || d < period) {
+			period = d
+		}
+	}
+	if period == 0 {
+		return sum
+	}
+
+	covered := dps[len(dps)-1].Timestamp.Sub(dps[0].Timestamp) + period
+	return sum * (hoursPerMonth * float64(time.Hour)) / float64(covered)
+}
+
+// Mapping maps a metric to the usage key of a resource type
+type Mapping struct {
+	// Namespace and Metric of the metric to map, like 'AWS/S3' and 'BucketSizeBytes'
+	Namespace string
+	Metric    string
+
+	// Dimensions that the metric must have with the same values, like
+	// the 'StorageType' of the S3 metrics
+	Dimensions map[string]string
+
+	// Dimension is the one that identifies the resource, like 'BucketName',
+	// if empty the Resource of the Datapoint is used
+	Dimension string
+
+	// ResourceType and Key are the ones the usage is set to, like
+	// 'aws_s3_bucket' and 'storage_gb'
+	ResourceType string
+	Key          string
+
+	// Attribute is the attribute of the resource that has the value that identifies
+	// it on the metrics, used by IDsFromPlan
+	Attribute string
+
+	// Aggregation computes the usage from the datapoints, Average if nil
+	Aggregation Aggregation
+
+	// Factor multiplies the aggregated value to convert it to the unit of the Key, 1 if 0
+	Factor float64
+}
+
+// Match checks if the Datapoint dp is of the metric of the Mapping
+func (m Mapping) Match(dp Datapoint) bool {
+	if dp.Namespace != m.Namespace || dp.Metric != m.Metric {
+		return false
+	}
+	for k, v := range m.Dimensions {
+		if dp.Dimensions[k] != v {
+			return false
+		}
+	}
+	return m.ResourceID(dp) != ""
+}
+
+// ResourceID returns the identifier of the resource of the Datapoint dp
+func (m Mapping) ResourceID(dp Datapoint) string {
+	if m.Dimension != "" {
+		return dp.Dimensions[m.Dimension]
+	}
+	return dp.Resource
+}
+
+// DefaultMappings are the mappings of the CloudWatch and Azure Monitor
+// metrics to the usage keys read by the providers
+var DefaultMappings = []Mapping{
+	{
+		Namespace:    "AWS/S3",
+		Metric:       "BucketSizeBytes",
+		Dimensions:   map[string]string{"StorageType": "StandardStorage"},
+		Dimension:    "BucketName",
+		ResourceType: "aws_s3_bucket",
+		Key:          "storage_gb",
+		Attribute:    "bucket",
+		Aggregation:  Average,
+		Factor:       BytesToGB,
+	},
+	{
+		Namespace:    "AWS/S3",
+		Metric:       "BytesDownloaded",
+		Dimension:    "BucketName",
+		ResourceType: "aws_s3_bucket",
+		Key:          "monthly_outbound_data_gb",
+		Attribute:    "bucket",
+		Aggregation:  MonthlySum,
+		Factor:       BytesToGB,
+	},
+	{
+		Namespace:    "AWS/NATGateway",
+		Metric:       "BytesOutToDestination",
+		Dimension:    "NatGatewayId",
+		ResourceType: "aws_nat_gateway",
+		Key:          "monthly_data_processed_gb",
+		Attribute:    "id",
+		Aggregation:  MonthlySum,
+		Factor:       BytesToGB,
+	},
+	{
+		Namespace:    "AWS/NATGateway",
+		Metric:       "BytesOutToSource",
+		Dimension:    "NatGatewayId",
+		ResourceType: "aws_nat_gateway",
+		Key:          "monthly_data_processed_gb",
+		Attribute:    "id",
+		Aggregation:  MonthlySum,
+		Factor:       BytesToGB,
+	},
+	{
+		Namespace:    "AWS/SQS",
+		Metric:       "NumberOfMessagesSent",
+		Dimension:    "QueueName",
+		ResourceType: "aws_sqs_queue",
+		Key:          "monthly_requests",
+		Attribute:    "name",
+		Aggregation:  MonthlySum,
+	},
+	{
+		Namespace:    "AWS/Logs",
+		Metric:       "IncomingBytes",
+		Dimension:    "LogGroupName",
+		ResourceType: "aws_cloudwatch_log_group",
+		Key:          "monthly_data_ingested_gb",
+		Attribute:    "name",
+		Aggregation:  MonthlySum,
+		Factor:       BytesToGB,
+	},
+	{
+		Namespace:    "Microsoft.Network/natGateways",
+		Metric:       "ByteCount",
+		ResourceType: "azurerm_nat_gateway",
+		Key:          "monthly_data_processed_gb",
+		Attribute:    "id",
+		Aggregation:  MonthlySum,
+		Factor:       BytesToGB,
+	},
+}
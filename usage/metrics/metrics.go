@@ -0,0 +1,202 @@
+// Package metrics imports the usage of the resources from the exports of
+// their historical metrics, like the ones of CloudWatch or Azure Monitor.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cycloidio/terracost/log"
+	"github.com/cycloidio/terracost/usage"
+)
+
+// Datapoint is the value of a metric of a resource at a time
+type Datapoint struct {
+	// Resource is the identifier of the resource of the metric when it's
+	// not on the Dimensions, like the Azure resource ID
+	Resource string
+
+	// Namespace of the metric, like 'AWS/S3' or 'Microsoft.Network/natGateways'
+	Namespace string
+
+	// Metric is the name of the metric, like 'BucketSizeBytes'
+	Metric string
+
+	// Dimensions of the metric, like 'BucketName'
+	Dimensions map[string]string
+
+	Timestamp time.Time
+	Value     float64
+	Unit      string
+}
+
+// Format reads the Datapoints of a metrics export
+type Format interface {
+	Read(r io.Reader) ([]Datapoint, error)
+}
+
+// FormatFunc is a function that implements Format
+type FormatFunc func(r io.Reader) ([]Datapoint, error)
+
+// Read reads the Datapoints from r
+func (f FormatFunc) Read(r io.Reader) ([]Datapoint, error) { return f(r) }
+
+// Importer imports the usage of the resources from their metrics
+type Importer struct {
+	// Logger is the logger of the import, log.Logger by default
+	Logger *slog.Logger
+
+	mappings []Mapping
+
+	// addresses are the addresses of the
+	// resources by their normalized identifier
+	addresses map[string][]string
+}
+
+// NewImporter returns an Importer that converts the metrics to usage with the mappings. The ids are the
+// identifiers of the resources on the metrics (like the bucket name or the Azure resource ID) by their
+// Terraform address, which can be taken from a plan with IDsFromPlan.
+func NewImporter(mappings []Mapping, ids map[string]string) *Importer {
+	ads := make(map[string][]string, len(ids))
+	for a, id := range ids {
+		nid := normalizeID(id)
+		ads[nid] = append(ads[nid], a)
+	}
+	for _, as := range ads {
+		sort.Strings(as)
+	}
+	return &Importer{
+		mappings:  mappings,
+		addresses: ads,
+	}
+}
+
+// logger returns the Logger or log.Logger if not set
+func (i *Importer) logger() *slog.Logger {
+	if i.Logger != nil {
+		return i.Logger
+	}
+	return log.Logger
+}
+
+// Read reads the metrics export from r with the Format f and returns its usage, see Import
+func (i *Importer) Read(r io.Reader, f Format) (usage.Usage, error) {
+	dps, err := f.Read(r)
+	if err != nil {
+		return usage.Usage{}, fmt.Errorf("failed to read metrics: %w", err)
+	}
+	return i.Import(dps)
+}
+
+// Import returns the usage of the resources of the datapoints, on the ResourceUsage by address. The datapoints of
+// each resource and Mapping are aggregated, and the ones of the different Mappings with the same key are added.
+// The datapoints that do not match any Mapping or resource address are ignored.
+func (i *Importer) Import(dps []Datapoint) (usage.Usage, error) {
+	type group struct {
+		mapping Mapping
+		address string
+		dps     []Datapoint
+	}
+	groups := make(map[string]*group)
+	for _, dp := range dps {
+		for mi, m := range i.mappings {
+			if !m.Match(dp) {
+				continue
+			}
+
+			id := m.ResourceID(dp)
+			addr, ok := i.address(id, m.ResourceType)
+			if !ok {
+				i.logger().Debug("usage: No address for the resource of the metric", "resource", id, "namespace", dp.Namespace, "metric", dp.Metric)
+				continue
+			}
+
+			gk := fmt.Sprintf("%d/%s", mi, addr)
+			g, ok := groups[gk]
+			if !ok {
+				g = &group{mapping: m, address: addr}
+				groups[gk] = g
+			}
+			g.dps = append(g.dps, dp)
+		}
+	}
+
+	if len(groups) == 0 {
+		return usage.Usage{}, nil
+	}
+
+	// The keys are sorted so the sums
+	// are always done on the same order
+	gks := make([]string, 0, len(groups))
+	for gk := range groups {
+		gks = append(gks, gk)
+	}
+	sort.Strings(gks)
+
+	ru := make(map[string]interface{})
+	for _, gk := range gks {
+		g := groups[gk]
+		sort.Slice(g.dps, func(i, j int) bool {
+			return g.dps[i].Timestamp.Before(g.dps[j].Timestamp)
+		})
+
+		aggregate := g.mapping.Aggregation
+		if aggregate == nil {
+			aggregate = Average
+		}
+		factor := g.mapping.Factor
+		if factor == 0 {
+			factor = 1
+		}
+		v := aggregate(g.dps) * factor
+
+		au, ok := ru[g.address].(map[string]interface{})
+		if !ok {
+			au = make(map[string]interface{})
+			ru[g.address] = au
+		}
+		if pv, ok := au[g.mapping.Key].(float64); ok {
+			v += pv
+		}
+		au[g.mapping.Key] = v
+	}
+
+	return usage.Usage{ResourceUsage: ru}, nil
+}
+
+// address returns the address of the resource with the identifier id and type rt, as the
+// identifiers are only unique by type (a bucket and a queue can have the same name)
+func (i *Importer) address(id, rt string) (string, bool) {
+	for _, a := range i.addresses[normalizeID(id)] {
+		if addressType(a) == rt {
+			return a, true
+		}
+	}
+	return "", false
+}
+
+// addressType returns the resource type of the address
+func addressType(address string) string {
+	parts := strings.Split(reInstanceKey.ReplaceAllString(address, ""), ".")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
+
+// reInstanceKey matches the instance keys of the addresses, like '[0]' or '["a.b"]'
+var reInstanceKey = regexp.MustCompile(`\[[^\]]*\]`)
+
+// normalizeID normalizes the identifiers of the resources, as the
+// Azure resource IDs are not case sensitive
+func normalizeID(id string) string {
+	if strings.HasPrefix(id, "/subscriptions/") {
+		return strings.ToLower(id)
+	}
+	return id
+}
@@ -0,0 +1,148 @@
+package metrics_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cycloidio/terracost/terraform"
+	"github.com/cycloidio/terracost/usage"
+	"github.com/cycloidio/terracost/usage/metrics"
+)
+
+func TestImporter_Read(t *testing.T) {
+	ids := map[string]string{
+		"aws_s3_bucket.logs":    "logs",
+		"aws_sqs_queue.logs":    "logs",
+		"aws_nat_gateway.nat":   "nat-0123456789",
+		"azurerm_nat_gateway.n": "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/rg/providers/Microsoft.Network/natGateways/nat",
+	}
+	imp := metrics.NewImporter(metrics.DefaultMappings, ids)
+
+	t.Run("CloudWatchJSON", func(t *testing.T) {
+		f, err := os.Open("../../testdata/usage/metrics/cloudwatch.json")
+		require.NoError(t, err)
+		defer f.Close()
+
+		u, err := imp.Read(f, metrics.CloudWatchJSON)
+		require.NoError(t, err)
+
+		require.Len(t, u.ResourceUsage, 2)
+		assert.InDelta(t, 20, u.GetResourceUsage("aws_s3_bucket.logs", "aws_s3_bucket")["storage_gb"], 0.0001)
+		// 2GB + 1GB on 2 days
+		assert.InDelta(t, 45.625, u.GetResourceUsage("aws_nat_gateway.nat", "aws_nat_gateway")["monthly_data_processed_gb"], 0.0001)
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		f, err := os.Open("../../testdata/usage/metrics/sqs.csv")
+		require.NoError(t, err)
+		defer f.Close()
+
+		u, err := imp.Read(f, metrics.DefaultCSV)
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]interface{}{
+			"aws_sqs_queue.logs": map[string]interface{}{
+				"monthly_requests": float64(730000),
+			},
+		}, u.ResourceUsage)
+	})
+
+	t.Run("AzureMonitorJSON", func(t *testing.T) {
+		f, err := os.Open("../../testdata/usage/metrics/azure-monitor.json")
+		require.NoError(t, err)
+		defer f.Close()
+
+		u, err := imp.Read(f, metrics.AzureMonitorJSON)
+		require.NoError(t, err)
+
+		require.Len(t, u.ResourceUsage, 1)
+		assert.InDelta(t, 45.625, u.GetResourceUsage("azurerm_nat_gateway.n", "azurerm_nat_gateway")["monthly_data_processed_gb"], 0.0001)
+	})
+
+	t.Run("CustomMapping", func(t *testing.T) {
+		imp := metrics.NewImporter([]metrics.Mapping{
+			{
+				Namespace:    "custom",
+				Metric:       "requests",
+				ResourceType: "aws_secretsmanager_secret",
+				Key:          "monthly_requests",
+				Aggregation:  metrics.Maximum,
+				Factor:       2,
+			},
+		}, map[string]string{"module.a.aws_secretsmanager_secret.s[0]": "secret"})
+
+		u, err := imp.Import([]metrics.Datapoint{
+			{Resource: "secret", Namespace: "custom", Metric: "requests", Value: 5},
+			{Resource: "secret", Namespace: "custom", Metric: "requests", Value: 7},
+			{Resource: "secret", Namespace: "custom", Metric: "other", Value: 100},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]interface{}{
+			"module.a.aws_secretsmanager_secret.s[0]": map[string]interface{}{
+				"monthly_requests": float64(14),
+			},
+		}, u.ResourceUsage)
+	})
+
+	t.Run("NoMatch", func(t *testing.T) {
+		u, err := imp.Import([]metrics.Datapoint{
+			{Resource: "other", Namespace: "custom", Metric: "requests", Value: 5},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, usage.Usage{}, u)
+	})
+
+	t.Run("MissingColumn", func(t *testing.T) {
+		_, err := imp.Read(strings.NewReader("metric,value\nBucketSizeBytes,10\n"), metrics.DefaultCSV)
+		assert.ErrorIs(t, err, metrics.ErrMissingColumn)
+	})
+}
+
+func TestMonthlySum(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, float64(0), metrics.MonthlySum(nil))
+	assert.Equal(t, float64(10), metrics.MonthlySum([]metrics.Datapoint{{Timestamp: t0, Value: 10}}))
+	assert.Equal(t, float64(730*30), metrics.MonthlySum([]metrics.Datapoint{
+		{Timestamp: t0, Value: 10},
+		{Timestamp: t0.Add(time.Hour), Value: 20},
+		{Timestamp: t0.Add(2 * time.Hour), Value: 60},
+	}))
+}
+
+func TestIDsFromPlan(t *testing.T) {
+	plan := terraform.NewPlan()
+	plan.PlannedValues.RootModule = terraform.Module{
+		Resources: []terraform.Resource{
+			{Address: "aws_s3_bucket.logs", Mode: "managed", Type: "aws_s3_bucket", Values: map[string]interface{}{"bucket": "logs"}},
+			{Address: "aws_nat_gateway.nat", Mode: "managed", Type: "aws_nat_gateway", Values: map[string]interface{}{}},
+			{Address: "aws_instance.web", Mode: "managed", Type: "aws_instance", Values: map[string]interface{}{"id": "i-0123"}},
+		},
+		ChildModules: []*terraform.Module{
+			{
+				Address: "module.queue",
+				Resources: []terraform.Resource{
+					{Address: "module.queue.aws_sqs_queue.this", Mode: "managed", Type: "aws_sqs_queue", Values: map[string]interface{}{"name": "jobs"}},
+				},
+			},
+		},
+	}
+	plan.PriorState = &terraform.State{Values: terraform.Values{RootModule: terraform.Module{
+		Resources: []terraform.Resource{
+			{Address: "aws_nat_gateway.nat", Mode: "managed", Type: "aws_nat_gateway", Values: map[string]interface{}{"id": "nat-0123456789"}},
+			{Address: "data.aws_s3_bucket.other", Mode: "data", Type: "aws_s3_bucket", Values: map[string]interface{}{"bucket": "other"}},
+		},
+	}}}
+
+	assert.Equal(t, map[string]string{
+		"aws_s3_bucket.logs":              "logs",
+		"aws_nat_gateway.nat":             "nat-0123456789",
+		"module.queue.aws_sqs_queue.this": "jobs",
+	}, metrics.IDsFromPlan(plan, metrics.DefaultMappings))
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/cycloidio/terracost/terraform"
+)
+
+// IDsFromPlan returns the identifiers on the metrics of the resources of the plan by their address, to be
+// used with NewImporter. The identifiers are the values of the Attribute of the mappings of each resource
+// type, which are taken from the prior state as most of them are only known once the resource exists.
+func IDsFromPlan(plan *terraform.Plan, mappings []Mapping) map[string]string {
+	attrs := make(map[string][]string)
+	for _, m := range mappings {
+		if m.Attribute != "" {
+			attrs[m.ResourceType] = append(attrs[m.ResourceType], m.Attribute)
+		}
+	}
+
+	ids := make(map[string]string)
+	addIDs(ids, &plan.PlannedValues.RootModule, attrs)
+	if plan.PriorState != nil {
+		addIDs(ids, &plan.PriorState.Values.RootModule, attrs)
+	}
+	return ids
+}
+
+// addIDs adds to ids the identifiers of the resources of the module m and its children
+// using the attrs by resource type
+func addIDs(ids map[string]string, m *terraform.Module, attrs map[string][]string) {
+	for _, r := range m.Resources {
+		if r.Mode != "" && r.Mode != "managed" {
+			continue
+		}
+		for _, a := range attrs[r.Type] {
+			if id, ok := r.Values[a]; ok && id != nil {
+				ids[r.Address] = fmt.Sprint(id)
+				break
+			}
+		}
+	}
+	for _, cm := range m.ChildModules {
+		addIDs(ids, cm, attrs)
+	}
+}
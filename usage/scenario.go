@@ -0,0 +1,8 @@
+package usage
+
+// Scenario is a named Usage, like 'low', 'expected' or 'high', to
+// estimate the same resources with different usages in one run
+type Scenario struct {
+	Name  string
+	Usage Usage
+}
@@ -0,0 +1,81 @@
+package usage
+
+import (
+	"sort"
+	"sync"
+)
+
+// Type is the type of the value of a usage attribute
+type Type string
+
+// List of the types of the usage attributes
+const (
+	Number Type = "number"
+	String Type = "string"
+	Bool   Type = "bool"
+	Object Type = "object"
+)
+
+// Attribute is a usage attribute accepted by a resource
+type Attribute struct {
+	Key  string
+	Type Type
+
+	// Unit is the unit of the value, like 'GB' or 'requests'
+	Unit string
+
+	// Description explains what the value is used for
+	Description string
+
+	// Attributes are the nested attributes when the Type is Object
+	Attributes Schema
+}
+
+// Schema is the list of usage attributes accepted by a resource type
+type Schema []Attribute
+
+// Get returns the attribute with the key
+func (s Schema) Get(key string) (Attribute, bool) {
+	for _, a := range s {
+		if a.Key == key {
+			return a, true
+		}
+	}
+	return Attribute{}, false
+}
+
+var (
+	schemasMu sync.RWMutex
+	schemas   = make(map[string]Schema)
+)
+
+// RegisterSchema registers the usage Schema accepted by the resource type rt (ex: aws_s3_bucket). The providers
+// register the schemas of their resources when initialized, so the usage can be validated when loaded.
+func RegisterSchema(rt string, s Schema) {
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+
+	schemas[rt] = s
+}
+
+// GetSchema returns the usage Schema of the resource type rt, if it has one registered
+func GetSchema(rt string) (Schema, bool) {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+
+	s, ok := schemas[rt]
+	return s, ok
+}
+
+// ResourceTypes returns all the resource types with a Schema registered sorted by name
+func ResourceTypes() []string {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+
+	rts := make([]string, 0, len(schemas))
+	for rt := range schemas {
+		rts = append(rts, rt)
+	}
+	sort.Strings(rts)
+	return rts
+}
@@ -0,0 +1,151 @@
+package usage
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateResource is a resource to add to the usage template
+type TemplateResource struct {
+	Address string
+	Type    string
+}
+
+// templateHeader is the comment on the top of the templates
+const templateHeader = `Usage of the resources, to be loaded with usage.Load.
+The values are the ones used by default for each resource type, change
+them to the expected usage and remove the ones that do not need to change.`
+
+// WriteTemplate writes to w a commented YAML usage template with the usage keys of each one of the resources
+// by address, so it can be filled and loaded with Load. The keys are the ones of the Schema of the resource type
+// and the values the ones of u (usually Default) for the resource type.
+func WriteTemplate(w io.Writer, resources []TemplateResource, u Usage) error {
+	resources = append([]TemplateResource(nil), resources...)
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Address < resources[j].Address
+	})
+
+	rus := &yaml.Node{Kind: yaml.MappingNode}
+	for _, r := range resources {
+		attrs, err := templateAttributes(templateSchema(r.Type, u), u.GetUsage(r.Type))
+		if err != nil {
+			return fmt.Errorf("failed to generate the template of %q: %w", r.Address, err)
+		}
+		rus.Content = append(rus.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: r.Address, HeadComment: r.Type},
+			attrs,
+		)
+	}
+
+	doc := &yaml.Node{
+		Kind: yaml.DocumentNode,
+		Content: []*yaml.Node{{
+			Kind:        yaml.MappingNode,
+			HeadComment: templateHeader,
+			Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Value: "resource_usage"},
+				rus,
+			},
+		}},
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write the template: %w", err)
+	}
+	return enc.Close()
+}
+
+// templateSchema returns the Schema of the resource type rt or, if it has none, one
+// with the keys of the usage of the resource type on u
+func templateSchema(rt string, u Usage) Schema {
+	if s, ok := GetSchema(rt); ok {
+		return s
+	}
+	return schemaFromValues(u.GetUsage(rt))
+}
+
+// schemaFromValues returns a Schema with the types of the values
+func schemaFromValues(values map[string]interface{}) Schema {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	s := make(Schema, 0, len(keys))
+	for _, k := range keys {
+		a := Attribute{Key: k}
+		switch v := values[k].(type) {
+		case map[string]interface{}:
+			a.Type = Object
+			a.Attributes = schemaFromValues(v)
+		case string:
+			a.Type = String
+		case bool:
+			a.Type = Bool
+		default:
+			a.Type = Number
+		}
+		s = append(s, a)
+	}
+	return s
+}
+
+// templateAttributes returns the node with the attributes of the Schema s, with
+// the values set to the ones on values or to the zero value of the type
+func templateAttributes(s Schema, values map[string]interface{}) (*yaml.Node, error) {
+	n := &yaml.Node{Kind: yaml.MappingNode}
+	for _, a := range s {
+		k := &yaml.Node{Kind: yaml.ScalarNode, Value: a.Key, HeadComment: attributeComment(a)}
+
+		if a.Type == Object {
+			nv, _ := values[a.Key].(map[string]interface{})
+			v, err := templateAttributes(a.Attributes, nv)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, k, v)
+			continue
+		}
+
+		val, ok := values[a.Key]
+		if !ok {
+			val = zeroValue(a.Type)
+		}
+		v := &yaml.Node{}
+		if err := v.Encode(val); err != nil {
+			return nil, err
+		}
+		n.Content = append(n.Content, k, v)
+	}
+	return n, nil
+}
+
+// attributeComment returns the comment of the Attribute a
+func attributeComment(a Attribute) string {
+	switch {
+	case a.Description != "" && a.Unit != "":
+		return fmt.Sprintf("%s (%s)", a.Description, a.Unit)
+	case a.Description != "":
+		return a.Description
+	case a.Unit != "":
+		return fmt.Sprintf("(%s)", a.Unit)
+	}
+	return ""
+}
+
+// zeroValue returns the zero value of the Type t
+func zeroValue(t Type) interface{} {
+	switch t {
+	case String:
+		return ""
+	case Bool:
+		return false
+	}
+	return 0
+}
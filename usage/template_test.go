@@ -0,0 +1,68 @@
+package usage_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cycloidio/terracost/usage"
+)
+
+func TestWriteTemplate(t *testing.T) {
+	u := usage.Usage{
+		ResourceDefaultTypeUsage: map[string]interface{}{
+			"test_bucket": map[string]interface{}{
+				"storage_gb": 200,
+				"disk": map[string]interface{}{
+					"operations": 1000,
+				},
+			},
+			"test_queue": map[string]interface{}{
+				"monthly_requests": 15000000,
+			},
+		},
+	}
+
+	var buff bytes.Buffer
+	err := usage.WriteTemplate(&buff, []usage.TemplateResource{
+		{Address: "test_queue.jobs", Type: "test_queue"},
+		{Address: `module.logs.test_bucket.logs["eu"]`, Type: "test_bucket"},
+	}, u)
+	require.NoError(t, err)
+
+	assert.Equal(t, `# Usage of the resources, to be loaded with usage.Load.
+# The values are the ones used by default for each resource type, change
+# them to the expected usage and remove the ones that do not need to change.
+resource_usage:
+  # test_bucket
+  module.logs.test_bucket.logs["eu"]:
+    # (GB)
+    storage_gb: 200
+    storage_class: ""
+    disk:
+      operations: 1000
+  # test_queue
+  test_queue.jobs:
+    monthly_requests: 15000000
+`, buff.String())
+
+	t.Run("Load", func(t *testing.T) {
+		var buff bytes.Buffer
+		err := usage.WriteTemplate(&buff, []usage.TemplateResource{
+			{Address: `module.logs.test_bucket.logs["eu"]`, Type: "test_bucket"},
+		}, u)
+		require.NoError(t, err)
+
+		lu, err := usage.Load(&buff)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"storage_gb":    200,
+			"storage_class": "",
+			"disk": map[string]interface{}{
+				"operations": 1000,
+			},
+		}, lu.GetResourceUsage(`module.logs.test_bucket.logs["eu"]`, "test_bucket"))
+	})
+}
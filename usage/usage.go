@@ -1,5 +1,11 @@
 package usage
 
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
 const (
 	// Key is the key used to set the usage
 	// on the values passed to the resources
@@ -16,13 +22,10 @@ var Default = Usage{
 			"monthly_data_scanned_insights_gb": 20,
 		},
 		"aws_eks_node_group": map[string]interface{}{
-			"instances":                        15,
-			"operating_system":                 "linux",
-			"reserved_instance_type":           "standard",
-			"reserved_instance_term":           "1_year",
-			"reserved_instance_payment_option": "partial_upfront",
-			"monthly_cpu_credit_hrs":           350,
-			"vcpu_count":                       2,
+			"instances":              15,
+			"operating_system":       "linux",
+			"monthly_cpu_credit_hrs": 350,
+			"vcpu_count":             2,
 		},
 		"aws_efs_file_system": map[string]interface{}{
 			"storage_gb":                         180,
@@ -133,6 +136,13 @@ var Default = Usage{
 // Usage is the struct defining all the configure usages
 type Usage struct {
 	ResourceDefaultTypeUsage map[string]interface{} `json:"resource_default_type_usage" yaml:"resource_default_type_usage"`
+
+	// ResourceUsage is the usage of specific resources by address, like 'module.logs.aws_s3_bucket.this'.
+	// The addresses can have wildcards ('*') to match any part of it, like 'module.logs.aws_s3_bucket.*',
+	// and without the instance keys ('[0]' or '["key"]') they match all the instances of the resource.
+	// It's merged over the ResourceDefaultTypeUsage of the resource type, the most specific
+	// address (with more characters that are not wildcards) having the precedence.
+	ResourceUsage map[string]interface{} `json:"resource_usage,omitempty" yaml:"resource_usage,omitempty"`
 }
 
 // GetUsage will return the usage from the resource rt (ex: aws_instance)
@@ -144,3 +154,104 @@ func (u Usage) GetUsage(rt string) map[string]interface{} {
 
 	return nil
 }
+
+// GetResourceUsage will return the usage of the resource with the address and the type rt, which
+// is the usage of the type with the ResourceUsage of the addresses matching it merged over
+func (u Usage) GetResourceUsage(address, rt string) map[string]interface{} {
+	us := u.GetUsage(rt)
+
+	patterns := make([]string, 0)
+	for p := range u.ResourceUsage {
+		if matchAddress(p, address) {
+			patterns = append(patterns, p)
+		}
+	}
+	if len(patterns) == 0 {
+		return us
+	}
+
+	// The less specific ones are merged first so
+	// the most specific ones override them
+	sort.Slice(patterns, func(i, j int) bool {
+		si, sj := specificity(patterns[i]), specificity(patterns[j])
+		if si != sj {
+			return si < sj
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	res := merge(nil, us)
+	for _, p := range patterns {
+		ru, ok := u.ResourceUsage[p].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		res = merge(res, ru)
+	}
+
+	return res
+}
+
+var reInstanceKey = regexp.MustCompile(`\[[^\]]*\]`)
+
+// matchAddress checks if the address matches the pattern, directly
+// or without the instance keys
+func matchAddress(pattern, address string) bool {
+	if wildcardMatch(pattern, address) {
+		return true
+	}
+	na := reInstanceKey.ReplaceAllString(address, "")
+	return na != address && wildcardMatch(pattern, na)
+}
+
+// wildcardMatch checks if the s matches the pattern, in which
+// a '*' matches any sequence of characters
+func wildcardMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, p)
+		if i == -1 {
+			return false
+		}
+		s = s[i+len(p):]
+	}
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
+}
+
+// specificity returns how specific the pattern is
+func specificity(pattern string) int {
+	return len(pattern) - strings.Count(pattern, "*")
+}
+
+// merge returns a copy of dst with the values of src set over it, the
+// maps present on both are merged instead of replaced
+func merge(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil && src == nil {
+		return nil
+	}
+
+	res := make(map[string]interface{}, len(dst)+len(src))
+	for k, v := range dst {
+		res[k] = v
+	}
+	for k, v := range src {
+		sm, ok := v.(map[string]interface{})
+		if !ok {
+			res[k] = v
+			continue
+		}
+		dm, _ := res[k].(map[string]interface{})
+		res[k] = merge(dm, sm)
+	}
+	return res
+}
@@ -21,3 +21,96 @@ func TestGetUsage(t *testing.T) {
 	assert.Equal(t, eu, ru)
 
 }
+
+func TestGetResourceUsage(t *testing.T) {
+	us := usage.Usage{
+		ResourceDefaultTypeUsage: map[string]interface{}{
+			"aws_s3_bucket": map[string]interface{}{
+				"storage_gb":               200,
+				"monthly_outbound_data_gb": 10,
+			},
+			"azurerm_linux_virtual_machine": map[string]interface{}{
+				"os_disk": map[string]interface{}{
+					"monthly_disk_operations": 100,
+				},
+			},
+		},
+		ResourceUsage: map[string]interface{}{
+			"module.logs.aws_s3_bucket.*": map[string]interface{}{
+				"storage_gb": 50000,
+			},
+			"module.logs.aws_s3_bucket.archive": map[string]interface{}{
+				"storage_gb": 900000,
+			},
+			"aws_s3_bucket.config": map[string]interface{}{
+				"storage_gb": 1,
+			},
+			"*.aws_s3_bucket.*": map[string]interface{}{
+				"monthly_outbound_data_gb": 5,
+			},
+			"azurerm_linux_virtual_machine.vm": map[string]interface{}{
+				"os_disk": map[string]interface{}{
+					"monthly_disk_operations": 1000,
+				},
+			},
+		},
+	}
+
+	tcs := []struct {
+		Name     string
+		Address  string
+		Type     string
+		Expected map[string]interface{}
+	}{
+		{
+			Name:     "TypeDefault",
+			Address:  "aws_s3_bucket.assets",
+			Type:     "aws_s3_bucket",
+			Expected: map[string]interface{}{"storage_gb": 200, "monthly_outbound_data_gb": 10},
+		},
+		{
+			Name:     "Address",
+			Address:  "aws_s3_bucket.config",
+			Type:     "aws_s3_bucket",
+			Expected: map[string]interface{}{"storage_gb": 1, "monthly_outbound_data_gb": 10},
+		},
+		{
+			Name:     "AddressAllInstances",
+			Address:  `aws_s3_bucket.config["eu"]`,
+			Type:     "aws_s3_bucket",
+			Expected: map[string]interface{}{"storage_gb": 1, "monthly_outbound_data_gb": 10},
+		},
+		{
+			Name:     "Wildcard",
+			Address:  "module.logs.aws_s3_bucket.analytics",
+			Type:     "aws_s3_bucket",
+			Expected: map[string]interface{}{"storage_gb": 50000, "monthly_outbound_data_gb": 5},
+		},
+		{
+			Name:     "MostSpecific",
+			Address:  "module.logs.aws_s3_bucket.archive[0]",
+			Type:     "aws_s3_bucket",
+			Expected: map[string]interface{}{"storage_gb": 900000, "monthly_outbound_data_gb": 5},
+		},
+		{
+			Name:     "NestedMerge",
+			Address:  "azurerm_linux_virtual_machine.vm",
+			Type:     "azurerm_linux_virtual_machine",
+			Expected: map[string]interface{}{"os_disk": map[string]interface{}{"monthly_disk_operations": 1000}},
+		},
+		{
+			Name:     "NoUsage",
+			Address:  "aws_instance.web",
+			Type:     "aws_instance",
+			Expected: nil,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, us.GetResourceUsage(tc.Address, tc.Type))
+		})
+	}
+
+	// The type defaults are not modified
+	assert.Equal(t, map[string]interface{}{"storage_gb": 200, "monthly_outbound_data_gb": 10}, us.GetUsage("aws_s3_bucket"))
+}
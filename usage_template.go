@@ -0,0 +1,71 @@
+package terracost
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/afero"
+
+	"github.com/cycloidio/terracost/query"
+	"github.com/cycloidio/terracost/terraform"
+	"github.com/cycloidio/terracost/usage"
+)
+
+// UsageTemplateFromPlan writes to w a commented YAML usage template (see usage.WriteTemplate) with all the resources
+// of the Terraform plan that have usage based components, set to the usage.Default values, to be filled and loaded
+// with usage.Load.
+func UsageTemplateFromPlan(w io.Writer, plan io.Reader, providerInitializers ...terraform.ProviderInitializer) error {
+	if len(providerInitializers) == 0 {
+		providerInitializers = getDefaultProviders()
+	}
+
+	tfplan := terraform.NewPlan(providerInitializers...)
+	if err := tfplan.Read(plan); err != nil {
+		return err
+	}
+	tfplan.SetUsage(usage.Default)
+
+	queries, err := tfplan.ExtractPlannedQueries()
+	if err != nil {
+		return err
+	}
+
+	return usage.WriteTemplate(w, usageResources(queries), usage.Default)
+}
+
+// UsageTemplateFromHCL writes to w a commented YAML usage template (see usage.WriteTemplate) with all the resources
+// of the HCL module on the modulePath that have usage based components, set to the usage.Default values, to be
+// filled and loaded with usage.Load.
+func UsageTemplateFromHCL(w io.Writer, afs afero.Fs, modulePath string, hclOpts terraform.HCLOptions, providerInitializers ...terraform.ProviderInitializer) error {
+	if len(providerInitializers) == 0 {
+		providerInitializers = getDefaultProviders()
+	}
+	if afs == nil {
+		afs = afero.NewOsFs()
+	}
+
+	queries, modAddr, err := terraform.ExtractQueriesFromHCLWithOptions(afs, providerInitializers, modulePath, usage.Default, nil, hclOpts)
+	if err != nil {
+		return fmt.Errorf("failed to ExtractQueriesFromHCL on module %q with 'modulePath' %q: %w", modAddr, modulePath, err)
+	}
+
+	return usage.WriteTemplate(w, usageResources(queries), usage.Default)
+}
+
+// usageResources returns the resources of the queries with
+// at least one component that is usage based
+func usageResources(queries []query.Resource) []usage.TemplateResource {
+	trs := make([]usage.TemplateResource, 0)
+	for _, q := range queries {
+		for _, c := range q.Components {
+			if c.Usage {
+				trs = append(trs, usage.TemplateResource{
+					Address: q.Address,
+					Type:    q.Type,
+				})
+				break
+			}
+		}
+	}
+	return trs
+}
@@ -0,0 +1,31 @@
+package terracost_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cycloidio/terracost"
+	"github.com/cycloidio/terracost/terraform"
+	"github.com/cycloidio/terracost/usage"
+)
+
+func TestUsageTemplateFromHCL(t *testing.T) {
+	var buff bytes.Buffer
+	err := terracost.UsageTemplateFromHCL(&buff, nil, "testdata/aws/stack-expansion", terraform.HCLOptions{})
+	require.NoError(t, err)
+
+	u, err := usage.Load(&buff)
+	require.NoError(t, err)
+
+	// Only the buckets have usage based components
+	require.Len(t, u.ResourceUsage, 2)
+	for _, addr := range []string{`aws_s3_bucket.bucket["assets"]`, `aws_s3_bucket.bucket["backups"]`} {
+		assert.Equal(t, map[string]interface{}{
+			"storage_gb":               200,
+			"monthly_outbound_data_gb": 10,
+		}, u.ResourceUsage[addr])
+	}
+}